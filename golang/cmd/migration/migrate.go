@@ -19,6 +19,15 @@ const (
 	MIGRATE_BOTH         = "both"
 	COUNT_TRANSACTIONS   = "count_t"
 	CREATE_TABLES        = "create_tables"
+	FRACTIONAL_QUANTITY  = "fractional_quantity"
+	TRANSACTION_FEE      = "transaction_fee"
+	TRANSFER_ID          = "transfer_id"
+	CORPORATE_ACTIONS    = "corporate_actions"
+	MULTI_CURRENCY       = "multi_currency"
+	FX_RATES             = "fx_rates"
+	SYMBOL_METADATA      = "symbol_metadata"
+	TARGET_ALLOCATIONS   = "target_allocations"
+	LOT_SELECTIONS       = "lot_selections"
 )
 
 // Set this to control which migration runs
@@ -44,6 +53,24 @@ func main() {
 		countTransactions(db)
 	case CREATE_TABLES:
 		createTables(db)
+	case FRACTIONAL_QUANTITY:
+		migrateFractionalQuantity(db)
+	case TRANSACTION_FEE:
+		migrateTransactionFee(db)
+	case TRANSFER_ID:
+		migrateTransferId(db)
+	case CORPORATE_ACTIONS:
+		createCorporateActionsTable(db)
+	case MULTI_CURRENCY:
+		migrateCurrency(db)
+	case FX_RATES:
+		migrateFxRates(db)
+	case SYMBOL_METADATA:
+		createSymbolsTable(db)
+	case TARGET_ALLOCATIONS:
+		createTargetAllocationsTable(db)
+	case LOT_SELECTIONS:
+		createLotSelectionsTable(db)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown migration type: %s\n", MIGRATION_TYPE)
 		os.Exit(1)
@@ -71,8 +98,11 @@ func createTables(db *sql.DB) {
 		symbol TEXT NOT NULL,
 		date TEXT NOT NULL,
 		transaction_type TEXT NOT NULL,
-		quantity INTEGER NOT NULL,
-		pps INTEGER NOT NULL
+		quantity TEXT NOT NULL,
+		pps INTEGER NOT NULL,
+		fee INTEGER NOT NULL DEFAULT 0,
+		transfer_id TEXT NOT NULL DEFAULT '',
+		currency TEXT NOT NULL DEFAULT 'USD'
 	)`)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create transactions table: %v\n", err)
@@ -87,7 +117,7 @@ func createTables(db *sql.DB) {
 		symbol TEXT NOT NULL,
 		date TEXT NOT NULL,
 		transaction_type TEXT NOT NULL,
-		quantity INTEGER NOT NULL,
+		quantity TEXT NOT NULL,
 		pps INTEGER NOT NULL
 	)`)
 	if err != nil {
@@ -105,6 +135,7 @@ func createTables(db *sql.DB) {
 		institution_id TEXT NOT NULL,
 		description TEXT,
 		tags TEXT,
+		base_currency TEXT NOT NULL DEFAULT 'USD',
 		created_at TEXT,
 		updated_at TEXT
 		)`)
@@ -126,6 +157,20 @@ func createTables(db *sql.DB) {
 	}
 	fmt.Println("Prices table created or already exists")
 
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS price_history (
+		symbol TEXT NOT NULL,
+		date TEXT NOT NULL,
+		adj_close INTEGER NOT NULL,
+		created_at DATETIME NULL,
+		PRIMARY KEY (symbol, date)
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create price_history table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("price_history table created or already exists")
+
 	_, err = db.Exec(`
 	CREATE TABLE IF NOT EXISTS rates (
 		symbol TEXT PRIMARY KEY,
@@ -145,6 +190,172 @@ func createTables(db *sql.DB) {
 	}
 	fmt.Println("Rates+history table created or already exists")
 
+	createFxRatesTable(db)
+
+	createCorporateActionsTable(db)
+	createSymbolsTable(db)
+	createTargetAllocationsTable(db)
+	createLotSelectionsTable(db)
+}
+
+// createCorporateActionsTable creates the table of renames, mergers and spin-offs, shares_per_share is a decimal
+// like quantity and basis_allocation the fraction of the cost basis that moves
+func createCorporateActionsTable(db *sql.DB) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS corporate_actions (
+		id TEXT PRIMARY KEY,
+		date TEXT NOT NULL,
+		action_type TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		new_symbol TEXT NOT NULL DEFAULT '',
+		shares_per_share TEXT NOT NULL DEFAULT '0',
+		cash_per_share INTEGER NOT NULL DEFAULT 0,
+		basis_allocation REAL NOT NULL DEFAULT 0
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create corporate_actions table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("corporate_actions table created or already exists")
+}
+
+// migrateFractionalQuantity rebuilds the transactions and dividends_splits tables with a TEXT quantity column so
+// fractional quantities are stored as exact decimals. Existing integer quantities are whole shares and are copied
+// as is, "10" is read back as 10 shares. Every other column is copied with its type, constraints and default.
+func migrateFractionalQuantity(db *sql.DB) {
+	fmt.Println("=== Migrating quantities to decimal text ===")
+
+	tx, err := db.Begin()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start transaction: %v\n", err)
+		os.Exit(1)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"transactions", "dividends_splits"} {
+		definitions, names, err := quantityTextColumns(tx, table)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read the columns of %s: %v\n", table, err)
+			os.Exit(1)
+		}
+
+		selected := make([]string, len(names))
+		for i, name := range names {
+			selected[i] = name
+			if name == "quantity" {
+				selected[i] = "CAST(quantity AS TEXT)"
+			}
+		}
+
+		statements := []string{
+			fmt.Sprintf("CREATE TABLE %s_new (%s)", table, strings.Join(definitions, ", ")),
+			fmt.Sprintf("INSERT INTO %s_new (%s) SELECT %s FROM %s",
+				table, strings.Join(names, ", "), strings.Join(selected, ", "), table),
+			fmt.Sprintf("DROP TABLE %s", table),
+			fmt.Sprintf("ALTER TABLE %s_new RENAME TO %s", table, table),
+		}
+
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to migrate %s: %v\n", table, err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("%s migrated\n", table)
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to commit transaction: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// quantityTextColumns reads the columns of a table and returns their definitions with quantity as TEXT, along with
+// their names in table order
+func quantityTextColumns(tx *sql.Tx, table string) ([]string, []string, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var definitions, names []string
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, kind       string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			return nil, nil, err
+		}
+
+		if name == "quantity" {
+			kind = "TEXT"
+		}
+		definition := name + " " + kind
+		if pk > 0 {
+			definition += " PRIMARY KEY"
+		}
+		if notNull == 1 {
+			definition += " NOT NULL"
+		}
+		if defaultValue.Valid {
+			definition += " DEFAULT " + defaultValue.String
+		}
+		definitions = append(definitions, definition)
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("table %s doesn't exist", table)
+	}
+
+	return definitions, names, nil
+}
+
+// migrateTransactionFee adds the fee column to the transactions table, existing trades get no fee
+func migrateTransactionFee(db *sql.DB) {
+	fmt.Println("=== Adding transaction fee column ===")
+
+	_, err := db.Exec("ALTER TABLE transactions ADD COLUMN fee INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to add fee column: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("transactions migrated")
+}
+
+// migrateTransferId adds the transfer_id column linking the two rows of a transfer between accounts
+func migrateTransferId(db *sql.DB) {
+	fmt.Println("=== Adding transfer id column ===")
+
+	_, err := db.Exec("ALTER TABLE transactions ADD COLUMN transfer_id TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to add transfer_id column: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("transactions migrated")
+}
+
+// migrateCurrency adds the currency of every transaction and the base currency of every account, existing rows
+// are in USD
+func migrateCurrency(db *sql.DB) {
+	fmt.Println("=== Adding currency columns ===")
+
+	statements := []string{
+		"ALTER TABLE transactions ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD'",
+		"ALTER TABLE accounts ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'USD'",
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to add currency column: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Println("transactions and accounts migrated")
 }
 
 func migrateTransactions(db *sql.DB) {
@@ -193,8 +404,8 @@ func migrateTransactions(db *sql.DB) {
 	// }
 
 	insertSQL := `
-	INSERT OR REPLACE INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps)
-	VALUES (?, ?, ?, ?, ?, ?, ?)`
+	INSERT OR REPLACE INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps, fee, transfer_id, currency)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	stmt, err := tx.Prepare(insertSQL)
 	if err != nil {
@@ -215,13 +426,16 @@ func migrateTransactions(db *sql.DB) {
 
 		// Define a temporary struct to handle date parsing
 		type jsonTransaction struct {
-			Id        string                `json:"id"`
-			AccountId string                `json:"account_id"`
-			Symbol    string                `json:"symbol"`
-			Date      string                `json:"date"`
-			Type      types.TransactionType `json:"transaction_type"`
-			Quantity  int32                 `json:"quantity"`
-			Pps       int32                 `json:"pps"`
+			Id         string                `json:"id"`
+			AccountId  string                `json:"account_id"`
+			Symbol     string                `json:"symbol"`
+			Date       string                `json:"date"`
+			Type       types.TransactionType `json:"transaction_type"`
+			Quantity   types.Quantity        `json:"quantity"`
+			Pps        types.Money           `json:"pps"`
+			Fee        types.Money           `json:"fee"`
+			TransferId string                `json:"transfer_id"`
+			Currency   string                `json:"currency"`
 		}
 
 		var jt jsonTransaction
@@ -241,13 +455,16 @@ func migrateTransactions(db *sql.DB) {
 		}
 
 		transaction := types.Transaction{
-			Id:        jt.Id,
-			AccountId: jt.AccountId,
-			Symbol:    jt.Symbol,
-			Date:      parsedDate,
-			Type:      jt.Type,
-			Quantity:  jt.Quantity,
-			Pps:       jt.Pps,
+			Id:         jt.Id,
+			AccountId:  jt.AccountId,
+			Symbol:     jt.Symbol,
+			Date:       parsedDate,
+			Type:       jt.Type,
+			Quantity:   jt.Quantity,
+			Pps:        jt.Pps,
+			Fee:        jt.Fee,
+			TransferId: jt.TransferId,
+			Currency:   types.NormalizeCurrency(jt.Currency),
 		}
 
 		_, err = stmt.Exec(
@@ -258,6 +475,9 @@ func migrateTransactions(db *sql.DB) {
 			transaction.Type,
 			transaction.Quantity,
 			transaction.Pps,
+			transaction.Fee,
+			transaction.TransferId,
+			transaction.Currency,
 		)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to insert transaction %s: %v\n", transaction.Id, err)
@@ -305,6 +525,7 @@ func migrateAccounts(db *sql.DB) {
 		institution_id TEXT NOT NULL,
 		description TEXT,
 		tags TEXT,
+		base_currency TEXT NOT NULL DEFAULT 'USD',
 		created_at TEXT,
 		updated_at TEXT
 	)`
@@ -326,8 +547,8 @@ func migrateAccounts(db *sql.DB) {
 
 	// Prepare insert statement
 	insertSQL := `
-	INSERT OR REPLACE INTO accounts (id, name, owner, institution, institution_id, description, tags, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	INSERT OR REPLACE INTO accounts (id, name, owner, institution, institution_id, description, tags, base_currency, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	stmt, err := db.Prepare(insertSQL)
 	if err != nil {
@@ -377,6 +598,7 @@ func migrateAccounts(db *sql.DB) {
 			account.InstitutionId,
 			description,
 			tagsStr,
+			types.NormalizeCurrency(account.BaseCurrency),
 			createdAt,
 			updatedAt,
 		)
@@ -404,3 +626,104 @@ func migrateAccounts(db *sql.DB) {
 	}
 	fmt.Printf("Total accounts in database: %d\n", count)
 }
+
+// createFxRatesTable creates the table of daily exchange rates, one USD based rate per currency and date
+func createFxRatesTable(db *sql.DB) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS fx_rates (
+		currency TEXT NOT NULL,
+		date TEXT NOT NULL,
+		value FLOAT NOT NULL,
+		created_at DATETIME NULL,
+		PRIMARY KEY (currency, date)
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create fx_rates table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("fx_rates table created or already exists")
+}
+
+// migrateFxRates creates fx_rates and copies the rates recorded so far into it, the last rate of a day wins.
+// Older days can then be filled with `tracker backfill`.
+func migrateFxRates(db *sql.DB) {
+	fmt.Println("=== Migrating exchange rates to daily rates ===")
+	createFxRatesTable(db)
+
+	for _, query := range []string{
+		`INSERT OR REPLACE INTO fx_rates (currency, date, value, created_at)
+		SELECT symbol, date(created_at), value, created_at FROM rates_history WHERE created_at IS NOT NULL ORDER BY created_at`,
+		`INSERT OR REPLACE INTO fx_rates (currency, date, value, created_at)
+		SELECT symbol, date(created_at), value, created_at FROM rates WHERE created_at IS NOT NULL`,
+	} {
+		result, err := db.Exec(query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to copy exchange rates: %v\n", err)
+			os.Exit(1)
+		}
+		copied, _ := result.RowsAffected()
+		fmt.Printf("Copied %d rates\n", copied)
+	}
+
+	fmt.Println("=== Exchange rates migration completed ===")
+}
+
+// createSymbolsTable creates the table of symbol metadata, manual marks rows edited by hand that fetched metadata
+// doesn't replace
+func createSymbolsTable(db *sql.DB) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS symbols (
+		symbol TEXT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		exchange TEXT NOT NULL DEFAULT '',
+		currency TEXT NOT NULL DEFAULT '',
+		asset_class TEXT NOT NULL DEFAULT '',
+		sector TEXT NOT NULL DEFAULT '',
+		country TEXT NOT NULL DEFAULT '',
+		isin TEXT NOT NULL DEFAULT '',
+		manual INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME NULL
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create symbols table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("symbols table created or already exists")
+}
+
+// createTargetAllocationsTable creates the table of target weights per tag, kind is symbol or asset_class and weight
+// and tolerance are fractions of the portfolio
+func createTargetAllocationsTable(db *sql.DB) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS target_allocations (
+		tag TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		weight FLOAT NOT NULL,
+		tolerance FLOAT NOT NULL DEFAULT 0,
+		PRIMARY KEY (tag, kind, key)
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create target_allocations table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("target_allocations table created or already exists")
+}
+
+// createLotSelectionsTable creates the table of lots picked for a sell, lot_id is the id of the buy that opened the
+// lot, position the order the lots are closed in and quantity a decimal like the transactions quantity
+func createLotSelectionsTable(db *sql.DB) {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS lot_selections (
+		sell_id TEXT NOT NULL,
+		lot_id TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		quantity TEXT NOT NULL,
+		PRIMARY KEY (sell_id, lot_id)
+		)`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create lot_selections table: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("lot_selections table created or already exists")
+}
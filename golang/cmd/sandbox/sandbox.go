@@ -82,7 +82,7 @@ func main() {
 
 	// Test exchange rates
 	fmt.Println("\n💱 Fetching exchange rates...")
-	if rates, err := fetcher.FetchExchangeRates(); err != nil {
+	if rates, err := fetcher.FetchExchangeRates([]string{"ILS", "EUR"}); err != nil {
 		log.Printf("❌ Exchange rate fetch failed: %v", err)
 	} else {
 		fmt.Printf("✅ Fetched %d exchange rates:\n", len(rates))
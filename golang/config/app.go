@@ -5,32 +5,69 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"tracker/types"
 )
 
 const DefaultDividendTaxRate = 0.25
+const DefaultCapitalGainTaxRate = 0.25
+const DefaultRiskFreeRate = 0.0
+const DefaultRebalanceTolerance = 0.05
 
 type AppConfig struct {
-	DividendTaxRate float64
+	DividendTaxes      DividendTaxConfig
+	CapitalGainTaxRate float64
+	RiskFreeRate       float64
+	RebalanceTolerance float64
+	LotMethod          types.LotMethod
+	Benchmarks         BenchmarkConfig
+	Currencies         CurrencyConfig
 }
 
 func Load() AppConfig {
-	rate := DefaultDividendTaxRate
-	raw := strings.TrimSpace(os.Getenv("TRACKER_DIVIDEND_TAX_RATE"))
-	if raw != "" {
-		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
-			if parsed > 1 {
-				parsed = parsed / 100
-			}
-			if parsed >= 0 && parsed <= 1 {
-				rate = parsed
-			}
-		}
+	return AppConfig{
+		DividendTaxes:      loadDividendTaxes(),
+		CapitalGainTaxRate: loadRate("TRACKER_CAPITAL_GAIN_TAX_RATE", DefaultCapitalGainTaxRate),
+		RiskFreeRate:       loadRate("TRACKER_RISK_FREE_RATE", DefaultRiskFreeRate),
+		RebalanceTolerance: loadRate("TRACKER_REBALANCE_TOLERANCE", DefaultRebalanceTolerance),
+		LotMethod:          loadLotMethod(),
+		Benchmarks:         loadBenchmarks(),
+		Currencies:         loadCurrencies(),
+	}
+}
+
+// loadLotMethod reads TRACKER_LOT_METHOD, FIFO, LIFO or HighestCost, the order sells close lots in when they have no
+// lots selected. FIFO when it isn't set or isn't one of them.
+func loadLotMethod() types.LotMethod {
+	if method, ok := types.ParseLotMethod(os.Getenv("TRACKER_LOT_METHOD")); ok {
+		return method
+	}
+
+	return types.LotMethodFIFO
+}
+
+// loadRate reads a rate between 0 and 1 from the env, values above 1 are treated as percentages
+func loadRate(env string, fallback float64) float64 {
+	if rate, ok := parseRate(os.Getenv(env)); ok {
+		return rate
+	}
+
+	return fallback
+}
+
+// parseRate reads a rate between 0 and 1, values above 1 are treated as percentages
+func parseRate(raw string) (float64, bool) {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, false
+	}
+	if parsed > 1 {
+		parsed = parsed / 100
 	}
 
-	return AppConfig{DividendTaxRate: rate}
+	return parsed, parsed >= 0 && parsed <= 1
 }
 
-func DividendsAfterTax(totalDividends int64, taxRate float64) int64 {
+func DividendsAfterTax(totalDividends types.Money, taxRate float64) types.Money {
 	netRatio := 1 - taxRate
 	if netRatio < 0 {
 		netRatio = 0
@@ -39,5 +76,5 @@ func DividendsAfterTax(totalDividends int64, taxRate float64) int64 {
 		netRatio = 1
 	}
 
-	return int64(math.Round(float64(totalDividends) * netRatio))
+	return types.Money(math.Round(float64(totalDividends) * netRatio))
 }
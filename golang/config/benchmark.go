@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"tracker/types"
+)
+
+// BenchmarkConfig maps accounts and tags to the symbol they are compared with, Default is used when neither matches
+type BenchmarkConfig struct {
+	Default  string
+	Accounts map[string]string
+	Tags     map[string]string
+}
+
+// loadBenchmarks reads TRACKER_BENCHMARK as the default symbol and TRACKER_BENCHMARKS as a comma separated list of
+// account:<id>=<symbol> and tag:<tag>=<symbol> entries
+func loadBenchmarks() BenchmarkConfig {
+	cfg := BenchmarkConfig{
+		Default:  strings.ToUpper(strings.TrimSpace(os.Getenv("TRACKER_BENCHMARK"))),
+		Accounts: make(map[string]string),
+		Tags:     make(map[string]string),
+	}
+
+	for _, entry := range strings.Split(os.Getenv("TRACKER_BENCHMARKS"), ",") {
+		key, symbol, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		kind, name, ok := strings.Cut(key, ":")
+		if !ok || symbol == "" {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "account":
+			cfg.Accounts[strings.TrimSpace(name)] = symbol
+		case "tag":
+			cfg.Tags[strings.TrimSpace(name)] = symbol
+		}
+	}
+
+	return cfg
+}
+
+// ForAccount returns the benchmark of the account, then of the first of its tags that has one, then the default
+func (b BenchmarkConfig) ForAccount(account types.Account) string {
+	if symbol, ok := b.Accounts[account.Id]; ok {
+		return symbol
+	}
+
+	for _, tag := range account.Tags {
+		if symbol, ok := b.Tags[tag]; ok {
+			return symbol
+		}
+	}
+
+	return b.Default
+}
+
+// ForTag returns the benchmark of a tag group, "All" and tags without a benchmark use the default
+func (b BenchmarkConfig) ForTag(tag string) string {
+	if symbol, ok := b.Tags[tag]; ok {
+		return symbol
+	}
+	return b.Default
+}
+
+// Symbols returns every configured benchmark symbol, sorted and without duplicates
+func (b BenchmarkConfig) Symbols() []string {
+	symbols := make([]string, 0, len(b.Accounts)+len(b.Tags)+1)
+	if b.Default != "" {
+		symbols = append(symbols, b.Default)
+	}
+	for _, symbol := range b.Accounts {
+		symbols = append(symbols, symbol)
+	}
+	for _, symbol := range b.Tags {
+		symbols = append(symbols, symbol)
+	}
+
+	slices.Sort(symbols)
+	return slices.Compact(symbols)
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"tracker/types"
+	"tracker/utils"
+)
+
+// DefaultCurrencies are the display currencies when TRACKER_CURRENCIES is not set
+const DefaultCurrencies = "USD,ILS,EUR"
+
+// knownFormats are the formats of common currencies, other currencies are written with their code
+var knownFormats = map[string]utils.CurrencyFormat{
+	"USD": {Symbol: "$", Decimals: 2},
+	"ILS": {Symbol: "₪", Decimals: 2},
+	"EUR": {Symbol: "€", Decimals: 2},
+	"GBP": {Symbol: "£", Decimals: 2},
+	"JPY": {Symbol: "¥", Decimals: 0},
+	"CHF": {Symbol: "CHF", Decimals: 2, SymbolAfter: true},
+	"SEK": {Symbol: "kr", Decimals: 2, SymbolAfter: true},
+	"NOK": {Symbol: "kr", Decimals: 2, SymbolAfter: true},
+	"DKK": {Symbol: "kr", Decimals: 2, SymbolAfter: true},
+}
+
+// DisplayCurrency is a currency amounts can be displayed in
+type DisplayCurrency struct {
+	Code string
+	utils.CurrencyFormat
+}
+
+// BaseCurrency is USD as it is displayed by default
+var BaseCurrency = displayCurrency(types.CurrencyUSD)
+
+// CurrencyConfig is the ordered set of display currencies, USD always comes first
+type CurrencyConfig []DisplayCurrency
+
+// loadCurrencies reads TRACKER_CURRENCIES as a comma separated list of currency codes. A code can be followed by
+// =<symbol> and ;-separated rules, "after" to write the symbol after the amount and a number of decimals, e.g.
+// "USD,ILS,EUR,SEK=kr;after,JPY=¥;0".
+func loadCurrencies() CurrencyConfig {
+	raw := strings.TrimSpace(os.Getenv("TRACKER_CURRENCIES"))
+	if raw == "" {
+		raw = DefaultCurrencies
+	}
+
+	currencies := CurrencyConfig{BaseCurrency}
+	for _, entry := range strings.Split(raw, ",") {
+		code, rules, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 3 {
+			continue
+		}
+
+		c := displayCurrency(code)
+		for i, rule := range strings.Split(rules, ";") {
+			rule = strings.TrimSpace(rule)
+			if decimals, err := strconv.Atoi(rule); err == nil && decimals >= 0 {
+				c.Decimals = decimals
+			} else if strings.EqualFold(rule, "after") {
+				c.SymbolAfter = true
+			} else if i == 0 && rule != "" {
+				c.Symbol = rule
+			}
+		}
+
+		if i := slices.IndexFunc(currencies, func(d DisplayCurrency) bool { return d.Code == code }); i >= 0 {
+			currencies[i] = c
+		} else {
+			currencies = append(currencies, c)
+		}
+	}
+
+	return currencies
+}
+
+func displayCurrency(code string) DisplayCurrency {
+	format, ok := knownFormats[code]
+	if !ok {
+		format = utils.CurrencyFormat{Symbol: code, Decimals: 2, SymbolAfter: true}
+	}
+	return DisplayCurrency{Code: code, CurrencyFormat: format}
+}
+
+// Get returns the display currency of code, unknown codes get USD
+func (c CurrencyConfig) Get(code string) DisplayCurrency {
+	code = types.NormalizeCurrency(code)
+	for _, d := range c {
+		if d.Code == code {
+			return d
+		}
+	}
+	return c.Base()
+}
+
+// Base returns USD, the currency the exchange rates are relative to
+func (c CurrencyConfig) Base() DisplayCurrency {
+	if len(c) == 0 {
+		return BaseCurrency
+	}
+	return c[0]
+}
+
+// Next returns the display currency after code, wrapping around to USD
+func (c CurrencyConfig) Next(code string) DisplayCurrency {
+	code = types.NormalizeCurrency(code)
+	i := slices.IndexFunc(c, func(d DisplayCurrency) bool { return d.Code == code })
+	if i < 0 || len(c) == 0 {
+		return c.Base()
+	}
+	return c[(i+1)%len(c)]
+}
+
+// Codes returns the codes of every display currency but USD, the ones whose exchange rates are fetched
+func (c CurrencyConfig) Codes() []string {
+	codes := make([]string, 0, len(c))
+	for _, d := range c {
+		if d.Code != types.CurrencyUSD {
+			codes = append(codes, d.Code)
+		}
+	}
+	return codes
+}
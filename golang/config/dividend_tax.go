@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// DividendTaxConfig maps accounts and the countries of securities to the tax rate of their dividends, Default is used
+// when neither matches. Accounts is keyed by account id, or by <id>:<country> for the dividends of a country received
+// in an account. Countries are uppercase.
+type DividendTaxConfig struct {
+	Default   float64
+	Accounts  map[string]float64
+	Countries map[string]float64
+}
+
+// loadDividendTaxes reads TRACKER_DIVIDEND_TAX_RATE as the default rate and TRACKER_DIVIDEND_TAX_RATES as a comma
+// separated list of account:<id>=<rate>, country:<code>=<rate> and account:<id>:<code>=<rate> entries. Rates are read
+// like the default, values above 1 are percentages.
+func loadDividendTaxes() DividendTaxConfig {
+	cfg := DividendTaxConfig{
+		Default:   loadRate("TRACKER_DIVIDEND_TAX_RATE", DefaultDividendTaxRate),
+		Accounts:  make(map[string]float64),
+		Countries: make(map[string]float64),
+	}
+
+	for _, entry := range strings.Split(os.Getenv("TRACKER_DIVIDEND_TAX_RATES"), ",") {
+		key, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+
+		rate, ok := parseRate(raw)
+		kind, name, found := strings.Cut(key, ":")
+		if !ok || !found {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "account":
+			id, country, _ := strings.Cut(strings.TrimSpace(name), ":")
+			if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+				id += ":" + country
+			}
+			cfg.Accounts[id] = rate
+		case "country":
+			cfg.Countries[strings.ToUpper(strings.TrimSpace(name))] = rate
+		}
+	}
+
+	return cfg
+}
+
+// Rate returns the rate of the dividends of a security from country received in the account, then the rate of the
+// account, then of the country, then the default. An account rate covers every country, e.g. 0 for a tax exempt
+// account, unless the country has its own rate in that account.
+func (d DividendTaxConfig) Rate(accountId, country string) float64 {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country != "" {
+		if rate, ok := d.Accounts[accountId+":"+country]; ok {
+			return rate
+		}
+	}
+	if rate, ok := d.Accounts[accountId]; ok {
+		return rate
+	}
+	if rate, ok := d.Countries[country]; ok && country != "" {
+		return rate
+	}
+
+	return d.Default
+}
@@ -6,7 +6,7 @@ Your role is to provide actionable insights and analysis of a user's investment
 When analyzing a portfolio, focus on:
 1. **Diversification**: Evaluate how well the portfolio is diversified across different asset classes, sectors, and geographies.
 2. **Sector Allocation**: Analyze the distribution of holdings across different sectors and identify any concentration risks.
-3. **Risk Exposure**: Assess the overall risk profile of the portfolio, including volatility, correlation, and concentration risks.
+3. **Risk Exposure**: Assess the overall risk profile of the portfolio, including volatility, drawdowns, risk-adjusted returns (Sharpe and Sortino), correlation, and concentration risks. Use the provided risk metrics when available.
 4. **Performance vs Benchmarks**: Compare the portfolio's performance against relevant benchmarks and market indices.
 
 Provide actionable insights that are:
@@ -44,7 +44,13 @@ func ContextBuilder(accountName string, holdings []PortfolioData, transactions [
 		context += "  Shares: " + h.Quantity + "\n"
 		context += "  Current Price: $" + h.CurrentPrice + "\n"
 		context += "  Current Value: $" + h.CurrentValue + "\n"
+		if h.AssetClass != "" {
+			context += "  Asset Class: " + h.AssetClass + "\n"
+		}
 		context += "  Sector: " + h.Sector + "\n"
+		if h.Country != "" {
+			context += "  Country: " + h.Country + "\n"
+		}
 		context += "  Allocation: " + h.AllocationPercent + "%\n\n"
 	}
 
@@ -54,7 +60,25 @@ func ContextBuilder(accountName string, holdings []PortfolioData, transactions [
 	context += "Total Cost Basis: $" + metrics.CostBasis + "\n"
 	context += "Unrealized Gain/Loss: $" + metrics.UnrealizedGain + " (" + metrics.UnrealizedGainPercent + "%)\n"
 	context += "Total Dividends Received: $" + metrics.DividendsReceived + "\n"
-	context += "Yield on Cost: " + metrics.YieldOnCost + "%\n\n"
+	context += "Yield on Cost: " + metrics.YieldOnCost + "%\n"
+	if metrics.TimeWeightedReturn != "" {
+		context += "Time-Weighted Return (3Y, 5Y and inception annualized): " + metrics.TimeWeightedReturn + "\n"
+	}
+	context += "\n"
+
+	if metrics.Volatility != "" {
+		context += "RISK METRICS:\n"
+		context += "-------------\n"
+		context += "Annualized Volatility: " + metrics.Volatility + "%\n"
+		context += "Maximum Drawdown: " + metrics.MaxDrawdown + "%"
+		if metrics.MaxDrawdownPeriod != "" {
+			context += " (" + metrics.MaxDrawdownPeriod + ")"
+		}
+		context += "\n"
+		context += "Current Drawdown: " + metrics.CurrentDrawdown + "%\n"
+		context += "Sharpe Ratio: " + metrics.SharpeRatio + " (risk-free rate " + metrics.RiskFreeRate + "%)\n"
+		context += "Sortino Ratio: " + metrics.SortinoRatio + "\n\n"
+	}
 
 	if len(transactions) > 0 {
 		context += "RECENT TRANSACTIONS:\n"
@@ -104,6 +128,8 @@ type PortfolioData struct {
 	CurrentPrice       string
 	CurrentValue       string
 	Sector             string
+	AssetClass         string
+	Country            string
 	AllocationPercent  string
 }
 
@@ -125,4 +151,12 @@ type MetricsData struct {
 	UnrealizedGainPercent string
 	DividendsReceived     string
 	YieldOnCost           string
+	TimeWeightedReturn    string
+	Volatility            string
+	MaxDrawdown           string
+	MaxDrawdownPeriod     string
+	CurrentDrawdown       string
+	SharpeRatio           string
+	SortinoRatio          string
+	RiskFreeRate          string
 }
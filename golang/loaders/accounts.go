@@ -10,7 +10,7 @@ import (
 
 func UserAccounts(db *sql.DB) (*[]types.Account, error) {
 	log := logging.Get()
-	rows, err := db.Query("SELECT id,name,institution,tags from accounts order by CAST(id as decimal)")
+	rows, err := db.Query("SELECT id,name,institution,tags,base_currency from accounts order by CAST(id as decimal)")
 	if err != nil {
 		log.Error("failed to all accounts for user", slog.Any("error", err))
 		return nil, err
@@ -21,7 +21,8 @@ func UserAccounts(db *sql.DB) (*[]types.Account, error) {
 	for rows.Next() {
 		var account types.Account
 		var tagsStr sql.NullString
-		_ = rows.Scan(&account.Id, &account.Name, &account.Institution, &tagsStr)
+		_ = rows.Scan(&account.Id, &account.Name, &account.Institution, &tagsStr, &account.BaseCurrency)
+		account.BaseCurrency = types.NormalizeCurrency(account.BaseCurrency)
 		if tagsStr.Valid && tagsStr.String != "" {
 			account.Tags = strings.Split(tagsStr.String, ",")
 		} else {
@@ -0,0 +1,55 @@
+package loaders
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"tracker/logging"
+	"tracker/types"
+	"tracker/utils"
+)
+
+// CorporateActions returns the corporate actions of symbols and of every symbol they became, sorted by date
+func CorporateActions(db *sql.DB, symbols []string) ([]types.CorporateAction, error) {
+	log := logging.Get()
+	rows, err := db.Query("SELECT id,date,action_type,symbol,new_symbol,shares_per_share,cash_per_share,basis_allocation from corporate_actions ORDER BY date")
+	if err != nil {
+		log.Error("failed to load corporate actions", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	lineage := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		lineage[strings.ToLower(s)] = true
+	}
+
+	actions := make([]types.CorporateAction, 0)
+	for rows.Next() {
+		var a types.CorporateAction
+		if err := rows.Scan(&a.Id, &a.Date, &a.Type, &a.Symbol, &a.NewSymbol, &a.SharesPerShare, &a.CashPerShare, &a.BasisAllocation); err != nil {
+			log.Error("failed to load next corporate action", slog.Any("error", err))
+			return nil, err
+		}
+
+		if !lineage[strings.ToLower(a.Symbol)] {
+			continue
+		}
+		if a.NewSymbol != "" {
+			lineage[strings.ToLower(a.NewSymbol)] = true
+		}
+		actions = append(actions, a)
+	}
+
+	return actions, rows.Err()
+}
+
+func AddCorporateAction(db *sql.DB, a types.CorporateAction) error {
+	if a.Id == "" {
+		a.Id = utils.GenerateUUID()
+	}
+	_, err := db.Exec("insert into corporate_actions (id,date,action_type,symbol,new_symbol,shares_per_share,cash_per_share,basis_allocation) values (?,?,?,?,?,?,?,?)",
+		a.Id, a.Date, a.Type, a.Symbol, a.NewSymbol, a.SharesPerShare, a.CashPerShare, a.BasisAllocation)
+
+	return err
+}
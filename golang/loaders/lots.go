@@ -0,0 +1,56 @@
+package loaders
+
+import (
+	"database/sql"
+	"log/slog"
+	"tracker/logging"
+	"tracker/types"
+)
+
+// LotSelections returns the lots picked for every sell keyed by the id of the sell, lots are keyed by the id of the
+// buy that opened them
+func LotSelections(db *sql.DB) (map[string][]types.LotSelection, error) {
+	log := logging.Get()
+	rows, err := db.Query("SELECT sell_id,lot_id,quantity FROM lot_selections ORDER BY sell_id, position")
+	if err != nil {
+		log.Error("failed to load lot selections", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	selections := make(map[string][]types.LotSelection)
+	for rows.Next() {
+		var sellId string
+		var s types.LotSelection
+		if err := rows.Scan(&sellId, &s.LotId, &s.Quantity); err != nil {
+			log.Error("failed to load next lot selection", slog.Any("error", err))
+			return nil, err
+		}
+		selections[sellId] = append(selections[sellId], s)
+	}
+
+	return selections, rows.Err()
+}
+
+// SetLotSelections replaces the lots picked for the sell sellId, in the order they are closed. No selections return
+// the sell to the lot method.
+func SetLotSelections(db *sql.DB, sellId string, selections []types.LotSelection) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("delete from lot_selections where sell_id = ?", sellId); err != nil {
+		return err
+	}
+	for i, s := range selections {
+		_, err := tx.Exec("insert into lot_selections (sell_id,lot_id,position,quantity) values (?,?,?,?)",
+			sellId, s.LotId, i, s.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
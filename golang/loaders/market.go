@@ -2,15 +2,18 @@ package loaders
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
 	"tracker/logging"
 	"tracker/types"
 )
 
 func AllPrices(db *sql.DB) map[string]types.SymbolPrice {
 	log := logging.Get()
-	rows, err := db.Query("SELECT symbol, adj_close from prices")
+	rows, err := db.Query("SELECT symbol, adj_close, created_at from prices")
 	if err != nil {
 		log.Error("failed to all transactions for user", slog.Any("error", err))
 		return map[string]types.SymbolPrice{}
@@ -21,13 +24,61 @@ func AllPrices(db *sql.DB) map[string]types.SymbolPrice {
 
 	for rows.Next() {
 		var p types.SymbolPrice
-		_ = rows.Scan(&p.Symbol, &p.AdjPrice)
+		var createdAt sql.NullTime
+		_ = rows.Scan(&p.Symbol, &p.AdjPrice, &createdAt)
+		if createdAt.Valid {
+			p.CreatedAt = createdAt.Time
+		}
 		prices[strings.ToLower(p.Symbol)] = p
 	}
 
+	// prices are in the currency of their symbol, without metadata they are taken to be in USD
+	if symbols, err := SymbolsInfo(db); err == nil {
+		for key, p := range prices {
+			p.Currency = symbols[key].Currency
+			prices[key] = p
+		}
+	}
+
 	return prices
 }
 
+// PriceHistory returns the daily prices of symbols since from, keyed by lowercase symbol and sorted by date
+func PriceHistory(db *sql.DB, symbols []string, from time.Time) (map[string][]types.SymbolPrice, error) {
+	log := logging.Get()
+	history := make(map[string][]types.SymbolPrice, len(symbols))
+	if len(symbols) == 0 {
+		return history, nil
+	}
+
+	ph := make([]string, len(symbols))
+	args := make([]any, len(symbols)+1)
+	args[0] = from.Format("2006-01-02")
+	for i := range ph {
+		ph[i] = "?"
+		args[i+1] = symbols[i]
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT symbol, date, adj_close FROM price_history WHERE date >= ? AND symbol IN (%s) ORDER BY date", strings.Join(ph, ",")), args...)
+	if err != nil {
+		log.Error("failed to load price history", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p types.SymbolPrice
+		if err := rows.Scan(&p.Symbol, &p.Date, &p.AdjPrice); err != nil {
+			log.Error("failed to scan price history row", slog.Any("error", err))
+			return nil, err
+		}
+		key := strings.ToLower(p.Symbol)
+		history[key] = append(history[key], p)
+	}
+
+	return history, nil
+}
+
 func SymbolPrice(db *sql.DB, symbol string) types.SymbolPrice {
 	log := logging.Get()
 	var p types.SymbolPrice
@@ -44,9 +95,76 @@ func CurrencyExchangeRate(db *sql.DB, symbol string) float64 {
 	var v float64
 	err := db.QueryRow("SELECT value FROM rates WHERE symbol = ?", symbol).Scan(&v)
 	if err != nil {
-		panic(err)
 		log.Error("failed to get exchange rate for symbol", slog.Any("error", err), slog.String("symbol", symbol))
 		return 1
 	}
 	return v
 }
+
+// FxRates returns the stored daily exchange rates of every currency followed by its current rate, keyed by
+// uppercase currency code and sorted by date
+func FxRates(db *sql.DB) (types.FxRates, error) {
+	log := logging.Get()
+	rates := make(types.FxRates)
+
+	for _, query := range []string{
+		"SELECT currency, value, date FROM fx_rates ORDER BY date",
+		"SELECT symbol, value, created_at FROM rates",
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			log.Error("failed to load exchange rates", slog.Any("error", err))
+			return nil, err
+		}
+
+		for rows.Next() {
+			var r types.FxRate
+			var date sql.NullTime
+			if err := rows.Scan(&r.Currency, &r.Value, &date); err != nil {
+				rows.Close()
+				log.Error("failed to scan exchange rate", slog.Any("error", err))
+				return nil, err
+			}
+			if !date.Valid {
+				continue
+			}
+
+			r.Currency = types.NormalizeCurrency(r.Currency)
+			r.Date = date.Time
+			history := rates[r.Currency]
+			if len(history) > 0 && !r.Date.After(history[len(history)-1].Date) {
+				continue
+			}
+			rates[r.Currency] = append(history, r)
+		}
+		rows.Close()
+	}
+
+	return rates, nil
+}
+
+// TransactionCurrencies returns the distinct uppercase currencies the transactions are in
+func TransactionCurrencies(db *sql.DB) ([]string, error) {
+	log := logging.Get()
+	rows, err := db.Query("SELECT DISTINCT(currency) FROM transactions")
+	if err != nil {
+		log.Error("failed to load transaction currencies", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	currencies := make([]string, 0)
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			log.Error("failed to scan transaction currency", slog.Any("error", err))
+			return nil, err
+		}
+
+		currency = types.NormalizeCurrency(currency)
+		if !slices.Contains(currencies, currency) {
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies, nil
+}
@@ -3,12 +3,19 @@ package loaders
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
+	"tracker/logging"
 	"tracker/types"
 )
 
+// LoadAllSymbols returns the symbols to fetch market data for, the traded and transferred symbols followed through
+// their corporate actions so renamed and merged symbols are replaced by what they became
 func LoadAllSymbols(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT DISTINCT(symbol) FROM transactions")
+	rows, err := db.Query("SELECT DISTINCT(symbol) FROM transactions WHERE transaction_type IN (?, ?, ?)",
+		types.TransactionTypeBuy, types.TransactionTypeSell, types.TransactionTypeTransfer)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to all symbols: %s\n", err)
 		return nil, err
@@ -27,14 +34,28 @@ func LoadAllSymbols(db *sql.DB) ([]string, error) {
 		symbols = append(symbols, symbol)
 	}
 
-	return symbols, nil
+	actions, err := CorporateActions(db, symbols)
+	if err != nil {
+		// without the corporate actions table the traded symbols are still fetched
+		fmt.Fprintf(os.Stderr, "failed to load corporate actions: %s\n", err)
+		return symbols, nil
+	}
+
+	return types.SymbolLineage(symbols, actions), nil
 }
 
 func SymbolsFromTransactions(transactions *[]types.Transaction) []string {
 
 	symbols := make(map[string]struct{}, 0)
 	for _, tr := range *transactions {
+		// cash transactions may name a symbol for reference but don't need its prices
+		if tr.Type.IsCash() {
+			continue
+		}
 		symbols[tr.Symbol] = struct{}{}
+		if tr.Action != nil && tr.Action.NewSymbol != "" {
+			symbols[tr.Action.NewSymbol] = struct{}{}
+		}
 	}
 
 	ret := make([]string, 0, len(symbols))
@@ -44,3 +65,39 @@ func SymbolsFromTransactions(transactions *[]types.Transaction) []string {
 
 	return ret
 }
+
+// SymbolsInfo returns the metadata of every symbol that has any, keyed by lowercase symbol
+func SymbolsInfo(db *sql.DB) (map[string]types.SymbolInfo, error) {
+	log := logging.Get()
+	rows, err := db.Query("SELECT symbol,name,exchange,currency,asset_class,sector,country,isin,manual,updated_at from symbols")
+	if err != nil {
+		log.Error("failed to load symbols metadata", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	symbols := make(map[string]types.SymbolInfo)
+	for rows.Next() {
+		var s types.SymbolInfo
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&s.Symbol, &s.Name, &s.Exchange, &s.Currency, &s.AssetClass, &s.Sector, &s.Country, &s.ISIN, &s.Manual, &updatedAt); err != nil {
+			log.Error("failed to load next symbol metadata", slog.Any("error", err))
+			return nil, err
+		}
+		if updatedAt.Valid {
+			s.UpdatedAt = updatedAt.Time
+		}
+		symbols[strings.ToLower(s.Symbol)] = s
+	}
+
+	return symbols, rows.Err()
+}
+
+// SaveSymbolInfo stores metadata edited by hand, fetched metadata no longer replaces it
+func SaveSymbolInfo(db *sql.DB, s types.SymbolInfo) error {
+	_, err := db.Exec(`insert or replace into symbols (symbol,name,exchange,currency,asset_class,sector,country,isin,manual,updated_at)
+		values (?,?,?,?,?,?,?,?,1,?)`,
+		strings.ToUpper(s.Symbol), s.Name, s.Exchange, s.Currency, s.AssetClass, s.Sector, s.Country, s.ISIN, time.Now())
+
+	return err
+}
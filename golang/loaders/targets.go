@@ -0,0 +1,76 @@
+package loaders
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"tracker/logging"
+	"tracker/types"
+)
+
+// TargetAllocations returns the target allocations of tag, largest weight first
+func TargetAllocations(db *sql.DB, tag string) ([]types.TargetAllocation, error) {
+	log := logging.Get()
+	rows, err := db.Query("SELECT tag,kind,key,weight,tolerance FROM target_allocations WHERE tag = ? ORDER BY weight DESC, key", tag)
+	if err != nil {
+		log.Error("failed to load target allocations", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make([]types.TargetAllocation, 0)
+	for rows.Next() {
+		var t types.TargetAllocation
+		if err := rows.Scan(&t.Tag, &t.Kind, &t.Key, &t.Weight, &t.Tolerance); err != nil {
+			log.Error("failed to load next target allocation", slog.Any("error", err))
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// SaveTargetAllocation adds t, or replaces the weight and tolerance of the same target
+func SaveTargetAllocation(db *sql.DB, t types.TargetAllocation) error {
+	_, err := db.Exec("insert or replace into target_allocations (tag,kind,key,weight,tolerance) values (?,?,?,?,?)",
+		t.Tag, t.Kind, targetKey(t.Kind, t.Key), t.Weight, t.Tolerance)
+
+	return err
+}
+
+// SetTargetAllocations replaces all the target allocations of tag with targets
+func SetTargetAllocations(db *sql.DB, tag string, targets []types.TargetAllocation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("delete from target_allocations where tag = ?", tag); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		_, err := tx.Exec("insert or replace into target_allocations (tag,kind,key,weight,tolerance) values (?,?,?,?,?)",
+			tag, t.Kind, targetKey(t.Kind, t.Key), t.Weight, t.Tolerance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func DeleteTargetAllocation(db *sql.DB, tag string, kind types.TargetKind, key string) error {
+	_, err := db.Exec("delete from target_allocations where tag = ? and kind = ? and key = ?", tag, kind, targetKey(kind, key))
+	return err
+}
+
+// targetKey stores symbols uppercase, asset classes keep their name
+func targetKey(kind types.TargetKind, key string) string {
+	key = strings.TrimSpace(key)
+	if kind == types.TargetSymbol {
+		return strings.ToUpper(key)
+	}
+	return key
+}
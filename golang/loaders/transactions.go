@@ -13,7 +13,7 @@ import (
 
 func AllTransactions(db *sql.DB) (*[]types.Transaction, error) {
 	log := logging.Get()
-	rows, err := db.Query("SELECT id,account_id,symbol,date,transaction_type,quantity,pps from transactions")
+	rows, err := db.Query("SELECT id,account_id,symbol,date,transaction_type,quantity,pps,fee,transfer_id,currency from transactions")
 	if err != nil {
 		log.Error("failed to all transactions for user", slog.Any("error", err))
 		return nil, err
@@ -23,7 +23,7 @@ func AllTransactions(db *sql.DB) (*[]types.Transaction, error) {
 	transactions := make([]types.Transaction, 0)
 	for rows.Next() {
 		var tr types.Transaction
-		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps)
+		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps, &tr.Fee, &tr.TransferId, &tr.Currency)
 		transactions = append(transactions, tr)
 	}
 
@@ -33,7 +33,7 @@ func AllTransactions(db *sql.DB) (*[]types.Transaction, error) {
 
 func AccountTransactions(db *sql.DB, accountId string) (*[]types.Transaction, error) {
 	log := logging.Get()
-	rows, err := db.Query("SELECT id,account_id,symbol,date,transaction_type,quantity,pps from transactions WHERE account_id=?", accountId)
+	rows, err := db.Query("SELECT id,account_id,symbol,date,transaction_type,quantity,pps,fee,transfer_id,currency from transactions WHERE account_id=?", accountId)
 	if err != nil {
 		log.Error("failed to all transactions for user account", slog.String("account", accountId), slog.Any("error", err))
 		return nil, err
@@ -43,7 +43,7 @@ func AccountTransactions(db *sql.DB, accountId string) (*[]types.Transaction, er
 	transactions := make([]types.Transaction, 0)
 	for rows.Next() {
 		var tr types.Transaction
-		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps)
+		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps, &tr.Fee, &tr.TransferId, &tr.Currency)
 		transactions = append(transactions, tr)
 	}
 
@@ -64,7 +64,7 @@ func AccountsTransactions(db *sql.DB, accountIds []string) (*[]types.Transaction
 		args[i] = id
 	}
 
-	query := fmt.Sprintf("SELECT id,account_id,symbol,date,transaction_type,quantity,pps from transactions WHERE account_id IN (%s)", strings.Join(ph, ","))
+	query := fmt.Sprintf("SELECT id,account_id,symbol,date,transaction_type,quantity,pps,fee,transfer_id,currency from transactions WHERE account_id IN (%s)", strings.Join(ph, ","))
 	rows, err := db.Query(query, args...)
 	if err != nil {
 		log.Error("failed to get transactions for accounts", slog.Any("error", err))
@@ -75,7 +75,7 @@ func AccountsTransactions(db *sql.DB, accountIds []string) (*[]types.Transaction
 	transactions := make([]types.Transaction, 0)
 	for rows.Next() {
 		var tr types.Transaction
-		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps)
+		_ = rows.Scan(&tr.Id, &tr.AccountId, &tr.Symbol, &tr.Date, &tr.Type, &tr.Quantity, &tr.Pps, &tr.Fee, &tr.TransferId, &tr.Currency)
 		transactions = append(transactions, tr)
 	}
 
@@ -110,20 +110,47 @@ func DividendsAndSplits(db *sql.DB, symbols []string, after time.Time) (*[]types
 
 }
 
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 func AddTransaction(db *sql.DB, tr types.Transaction) error {
+	return insertTransaction(db, tr)
+}
+
+func insertTransaction(db execer, tr types.Transaction) error {
 	if tr.Id == "" {
 		tr.Id = utils.GenerateUUID()
 	}
-	_, err := db.Exec("insert into transactions (id,account_id,symbol,date,transaction_type,quantity,pps) values (?,?,?,?,?,?,?)", tr.Id, tr.AccountId, tr.Symbol, tr.Date, tr.Type, tr.Quantity, tr.Pps)
+	_, err := db.Exec("insert into transactions (id,account_id,symbol,date,transaction_type,quantity,pps,fee,transfer_id,currency) values (?,?,?,?,?,?,?,?,?,?)", tr.Id, tr.AccountId, tr.Symbol, tr.Date, tr.Type, tr.Quantity, tr.Pps, tr.Fee, tr.TransferId, types.NormalizeCurrency(tr.Currency))
 
 	return err
 }
 
+// AddTransfer stores both rows of a transfer, either both are written or neither is
+func AddTransfer(db *sql.DB, out types.Transaction, in types.Transaction) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertTransaction(tx, out); err != nil {
+		return err
+	}
+	if err := insertTransaction(tx, in); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTransaction deletes a transaction, deleting one side of a transfer deletes the other side as well
 func DeleteTransaction(db *sql.DB, id string) error {
 	if id == "" {
 		return nil
 	}
 
-	_, err := db.Exec("delete from transactions where id=?", id)
+	_, err := db.Exec("delete from transactions where id=? or (transfer_id != '' and transfer_id = (select transfer_id from transactions where id=?))", id, id)
 	return err
 }
@@ -0,0 +1,157 @@
+package market
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"tracker/loaders"
+	"tracker/logging"
+	"tracker/types"
+)
+
+func BackfillPriceHistory(db *sql.DB, extraSymbols ...string) error {
+	return BackfillPriceHistoryWithFetcher(db, NewMarketStackDataFetcher(), extraSymbols...)
+}
+
+// BackfillPriceHistoryWithFetcher loads the end of day prices of every traded symbol and extraSymbols since the
+// first transaction into price_history, existing days are overwritten
+func BackfillPriceHistoryWithFetcher(db *sql.DB, fetcher DateFetcher, extraSymbols ...string) error {
+	start := time.Now()
+	logger := logging.Get()
+	logger.Info("Starting price history backfill")
+
+	transactions, err := loaders.AllTransactions(db)
+	if err != nil {
+		return fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	if len(*transactions) == 0 {
+		logger.Info("No transactions found, nothing to backfill")
+		return nil
+	}
+
+	from := (*transactions)[0].Date
+	for _, tr := range *transactions {
+		if tr.Date.Before(from) {
+			from = tr.Date
+		}
+	}
+
+	symbols := loaders.SymbolsFromTransactions(transactions)
+	// the history of a renamed or merged symbol stops at the action, what it became is fetched from the start
+	if actions, err := loaders.CorporateActions(db, symbols); err == nil {
+		for _, a := range actions {
+			if a.NewSymbol != "" {
+				extraSymbols = append(extraSymbols, a.NewSymbol)
+			}
+		}
+	}
+	symbols = withExtraSymbols(symbols, extraSymbols)
+	logger.Info("Fetching price history", slog.Int("symbols", len(symbols)), slog.Time("from", from))
+
+	history, err := fetcher.FetchPriceHistory(symbols, from)
+	if err != nil {
+		return fmt.Errorf("failed to fetch price history: %w", err)
+	}
+
+	prices := make([]types.SymbolPrice, 0)
+	for _, symbolPrices := range history {
+		prices = append(prices, symbolPrices...)
+	}
+
+	ctx := context.TODO()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	logger.Info("Upserting price history", slog.Int("count", len(prices)))
+	if err := batchUpsertPriceHistory(ctx, tx, prices, time.Now()); err != nil {
+		return fmt.Errorf("failed to upsert price history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit price history: %w", err)
+	}
+
+	logger.Info("Price history backfill completed", slog.Int("prices", len(prices)), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+func BackfillExchangeRates(db *sql.DB, currencies ...string) error {
+	return BackfillExchangeRatesWithFetcher(db, NewMarketStackDataFetcher(), currencies...)
+}
+
+// BackfillExchangeRatesWithFetcher loads the daily rate of currencies, of every currency the transactions are in
+// and of the currencies already in rates, since the first transaction into fx_rates, existing days are overwritten
+func BackfillExchangeRatesWithFetcher(db *sql.DB, fetcher DateFetcher, currencies ...string) error {
+	start := time.Now()
+	logger := logging.Get()
+	logger.Info("Starting exchange rates backfill")
+
+	transactions, err := loaders.AllTransactions(db)
+	if err != nil {
+		return fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	if len(*transactions) == 0 {
+		logger.Info("No transactions found, nothing to backfill")
+		return nil
+	}
+
+	from := (*transactions)[0].Date
+	for _, tr := range *transactions {
+		if tr.Date.Before(from) {
+			from = tr.Date
+		}
+	}
+
+	current, err := loaders.FxRates(db)
+	if err != nil {
+		return fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	for currency := range current {
+		currencies = append(currencies, currency)
+	}
+	currencies = rateCurrencies(db, currencies)
+	slices.Sort(currencies)
+
+	if len(currencies) == 0 {
+		logger.Info("No currencies besides USD, nothing to backfill")
+		return nil
+	}
+	logger.Info("Fetching exchange rate history", slog.Int("currencies", len(currencies)), slog.Time("from", from))
+
+	history, err := fetcher.FetchExchangeRateHistory(currencies, from)
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange rate history: %w", err)
+	}
+
+	rates := make([]types.FxRate, 0)
+	for _, currencyRates := range history {
+		rates = append(rates, currencyRates...)
+	}
+
+	ctx := context.TODO()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	logger.Info("Upserting exchange rate history", slog.Int("count", len(rates)))
+	if err := batchUpsertFxRates(ctx, tx, rates, time.Now()); err != nil {
+		return fmt.Errorf("failed to upsert exchange rate history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exchange rate history: %w", err)
+	}
+
+	logger.Info("Exchange rates backfill completed", slog.Int("rates", len(rates)), slog.Duration("duration", time.Since(start)))
+	return nil
+}
@@ -0,0 +1,120 @@
+package market
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tracker/loaders"
+	"tracker/types"
+)
+
+func TestBackfillPriceHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"tx0", "acc1", "GOOGL", "2023-03-01", "Buy", 10, 9000)
+	if err != nil {
+		t.Fatalf("failed to insert test transaction: %v", err)
+	}
+
+	fetcher := &MockFetcher{
+		PriceHistory: map[string][]types.SymbolPrice{
+			"AAPL": {
+				{Symbol: "AAPL", AdjPrice: 15000, Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+				{Symbol: "AAPL", AdjPrice: 15100, Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+			},
+			"GOOGL": {
+				{Symbol: "GOOGL", AdjPrice: 9000, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	if err := BackfillPriceHistoryWithFetcher(db, fetcher); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+
+	expectedFrom := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	if !fetcher.PriceHistoryFrom.Equal(expectedFrom) {
+		t.Errorf("expected history to be fetched from %s, got %s", expectedFrom, fetcher.PriceHistoryFrom)
+	}
+
+	history, err := loaders.PriceHistory(db, []string{"AAPL", "GOOGL"}, expectedFrom)
+	if err != nil {
+		t.Fatalf("failed to load price history: %v", err)
+	}
+	if len(history["aapl"]) != 2 || len(history["googl"]) != 1 {
+		t.Fatalf("expected 2 AAPL and 1 GOOGL history rows, got %d and %d", len(history["aapl"]), len(history["googl"]))
+	}
+	if history["aapl"][1].AdjPrice != 15100 {
+		t.Errorf("expected last AAPL price 15100, got %d", history["aapl"][1].AdjPrice)
+	}
+}
+
+func TestBackfillPriceHistory_FetchError(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fetcher := &MockFetcher{PriceHistoryErr: errors.New("boom")}
+	if err := BackfillPriceHistoryWithFetcher(db, fetcher); err == nil {
+		t.Fatalf("expected backfill to fail when the fetcher fails")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM price_history").Scan(&count); err != nil {
+		t.Fatalf("failed to query price history count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no price history rows, got %d", count)
+	}
+}
+
+func TestBackfillExchangeRates(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps, currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"tx0", "acc1", "TEVA", "2023-03-01", "Buy", 10, 3000, "ILS")
+	if err != nil {
+		t.Fatalf("failed to insert test transaction: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO rates (symbol, value, created_at) VALUES (?, ?, ?)`, "EUR", 0.92, time.Now()); err != nil {
+		t.Fatalf("failed to insert test rate: %v", err)
+	}
+
+	fetcher := &MockFetcher{
+		RateHistory: map[string][]types.FxRate{
+			"ILS": {
+				{Currency: "ILS", Value: 3.6, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+				{Currency: "ILS", Value: 3.7, Date: time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC)},
+			},
+			"EUR": {
+				{Currency: "EUR", Value: 0.94, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	if err := BackfillExchangeRatesWithFetcher(db, fetcher); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+
+	expectedFrom := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	if !fetcher.RateHistoryFrom.Equal(expectedFrom) {
+		t.Errorf("expected rates to be fetched from %s, got %s", expectedFrom, fetcher.RateHistoryFrom)
+	}
+	if len(fetcher.RateHistorySymbols) != 2 || fetcher.RateHistorySymbols[0] != "EUR" || fetcher.RateHistorySymbols[1] != "ILS" {
+		t.Errorf("expected EUR and ILS to be fetched, got %v", fetcher.RateHistorySymbols)
+	}
+
+	rates, err := loaders.FxRates(db)
+	if err != nil {
+		t.Fatalf("failed to load exchange rates: %v", err)
+	}
+	// the current EUR rate follows its history
+	if len(rates["ILS"]) != 2 || len(rates["EUR"]) != 2 {
+		t.Fatalf("expected 2 ILS and 2 EUR rates, got %v", rates)
+	}
+	if rate := rates.Rate("ILS", time.Date(2023, 3, 2, 12, 0, 0, 0, time.UTC)); rate != 3.7 {
+		t.Errorf("expected ILS rate 3.7 on 2023-03-02, got %f", rate)
+	}
+}
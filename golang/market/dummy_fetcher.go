@@ -1,6 +1,9 @@
 package market
 
-import "tracker/types"
+import (
+	"time"
+	"tracker/types"
+)
 
 // DummyMarketFetcher is a test implementation of DateFetcher that returns empty results
 type DummyMarketFetcher struct{}
@@ -25,7 +28,22 @@ func (d *DummyMarketFetcher) FetchSplits(symbols []string) (map[string][]types.T
 	return make(map[string][]types.Transaction), nil
 }
 
+// FetchPriceHistory returns an empty map of daily prices
+func (d *DummyMarketFetcher) FetchPriceHistory(symbols []string, from time.Time) (map[string][]types.SymbolPrice, error) {
+	return make(map[string][]types.SymbolPrice), nil
+}
+
 // FetchExchangeRates returns an empty map of exchange rates
-func (d *DummyMarketFetcher) FetchExchangeRates() (map[string]float64, error) {
+func (d *DummyMarketFetcher) FetchExchangeRates(currencies []string) (map[string]float64, error) {
 	return make(map[string]float64), nil
 }
+
+// FetchExchangeRateHistory returns an empty map of daily exchange rates
+func (d *DummyMarketFetcher) FetchExchangeRateHistory(currencies []string, from time.Time) (map[string][]types.FxRate, error) {
+	return make(map[string][]types.FxRate), nil
+}
+
+// FetchSymbolInfo returns an empty map of symbol metadata
+func (d *DummyMarketFetcher) FetchSymbolInfo(symbols []string) (map[string]types.SymbolInfo, error) {
+	return make(map[string]types.SymbolInfo), nil
+}
@@ -2,6 +2,7 @@ package market
 
 import (
 	"errors"
+	"time"
 	"tracker/types"
 )
 
@@ -28,9 +29,21 @@ type DateFetcher interface {
 	// Returns a map of symbol to slice of split transactions, or nil if failed
 	FetchSplits(symbols []string) (map[string][]types.Transaction, error)
 
-	// FetchExchangeRates fetches current exchange rates
+	// FetchPriceHistory fetches end of day prices for the given symbols from the given date until today
+	// Returns a map of symbol to slice of daily prices, or nil if failed
+	FetchPriceHistory(symbols []string, from time.Time) (map[string][]types.SymbolPrice, error)
+
+	// FetchExchangeRates fetches the current exchange rates of the given currencies
 	// Returns a map of currency code to exchange rate (relative to USD)
-	FetchExchangeRates() (map[string]float64, error)
+	FetchExchangeRates(currencies []string) (map[string]float64, error)
+
+	// FetchExchangeRateHistory fetches the daily exchange rates of the given currencies from the given date until today
+	// Returns a map of currency code to slice of daily rates (relative to USD), or nil if failed
+	FetchExchangeRateHistory(currencies []string, from time.Time) (map[string][]types.FxRate, error)
+
+	// FetchSymbolInfo fetches the metadata of the given symbols, fields the source doesn't know are left empty
+	// Returns a map of symbol to its metadata, or nil if failed
+	FetchSymbolInfo(symbols []string) (map[string]types.SymbolInfo, error)
 }
 
 // ErrMarketDataUnavailable is returned when market data cannot be fetched
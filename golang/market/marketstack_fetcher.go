@@ -9,7 +9,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"tracker/types"
 	"tracker/utils"
 )
@@ -32,13 +35,40 @@ func NewMarketStackDataFetcher() *MarketStackDataFetcher {
 
 // MarketStackResponse represents the response from MarketStack price API
 type MarketStackResponse struct {
-	Data []MarketStackPrice `json:"data"`
+	Pagination MarketStackPagination `json:"pagination"`
+	Data       []MarketStackPrice    `json:"data"`
+}
+
+// MarketStackPagination represents the paging information of a MarketStack response
+type MarketStackPagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+	Total  int `json:"total"`
 }
 
 // MarketStackPrice represents a single price entry from MarketStack
 type MarketStackPrice struct {
 	Symbol   string  `json:"symbol"`
 	AdjClose float64 `json:"adj_close"`
+	Close    float64 `json:"close"`
+	Date     string  `json:"date"`
+}
+
+// toSymbolPrice converts a MarketStack price into a SymbolPrice, prices are stored in cents
+func (p MarketStackPrice) toSymbolPrice() types.SymbolPrice {
+	price := types.SymbolPrice{
+		Symbol:   p.Symbol,
+		AdjPrice: types.MoneyFromFloat(p.AdjClose),
+		Close:    types.MoneyFromFloat(p.Close),
+	}
+
+	// Normalize date to YYYY-MM-DD format, MarketStack returns a full timestamp
+	if len(p.Date) >= 10 {
+		price.Date = utils.StringToDate(p.Date[:10])
+	}
+
+	return price
 }
 
 // MarketDividendsResponse represents the response from MarketStack dividends API
@@ -65,6 +95,41 @@ type MarketSplit struct {
 	Symbol      string  `json:"symbol"`
 }
 
+// MarketStackTicker represents the response from MarketStack tickers API
+type MarketStackTicker struct {
+	Name          string                   `json:"name"`
+	Symbol        string                   `json:"symbol"`
+	Sector        string                   `json:"sector"`
+	ItemType      string                   `json:"item_type"`
+	StockExchange MarketStackStockExchange `json:"stock_exchange"`
+}
+
+// MarketStackStockExchange represents the exchange a MarketStack ticker is listed on
+type MarketStackStockExchange struct {
+	Acronym     string `json:"acronym"`
+	Mic         string `json:"mic"`
+	CountryCode string `json:"country_code"`
+}
+
+// toSymbolInfo converts a MarketStack ticker into SymbolInfo, tickers that are not ETFs are taken to be stocks
+func (t MarketStackTicker) toSymbolInfo() types.SymbolInfo {
+	info := types.SymbolInfo{
+		Symbol:     t.Symbol,
+		Name:       t.Name,
+		Exchange:   t.StockExchange.Acronym,
+		AssetClass: types.AssetClassStock,
+		Sector:     t.Sector,
+		Country:    t.StockExchange.CountryCode,
+	}
+	if info.Exchange == "" {
+		info.Exchange = t.StockExchange.Mic
+	}
+	if strings.EqualFold(t.ItemType, "etf") {
+		info.AssetClass = types.AssetClassETF
+	}
+	return info
+}
+
 // ExchangeRatesResponse represents the response from Exchange Rates API
 type ExchangeRatesResponse struct {
 	Rates map[string]float64 `json:"rates"`
@@ -103,17 +168,66 @@ func (m *MarketStackDataFetcher) FetchPrices(symbols []string) (map[string]types
 
 	prices := make(map[string]types.SymbolPrice, len(marketResp.Data))
 	for _, price := range marketResp.Data {
-		// Convert float64 to int32 (assuming price is in cents or similar)
-		adjPriceInt := int32(price.AdjClose * 100)
-		prices[price.Symbol] = types.SymbolPrice{
-			Symbol:   price.Symbol,
-			AdjPrice: adjPriceInt,
-		}
+		prices[price.Symbol] = price.toSymbolPrice()
 	}
 
 	return prices, nil
 }
 
+// FetchPriceHistory fetches end of day prices from MarketStack API, following the pagination until all
+// the days since from are loaded
+func (m *MarketStackDataFetcher) FetchPriceHistory(symbols []string, from time.Time) (map[string][]types.SymbolPrice, error) {
+	if m.marketStackKey == "" {
+		return nil, fmt.Errorf("MARKETSTACK_API_KEY environment variable not set")
+	}
+
+	const limit = 1000
+	history := make(map[string][]types.SymbolPrice)
+	for offset := 0; ; offset += limit {
+		params := url.Values{}
+		params.Add("symbols", strings.Join(symbols, ","))
+		params.Add("access_key", m.marketStackKey)
+		params.Add("date_from", from.Format("2006-01-02"))
+		params.Add("limit", strconv.Itoa(limit))
+		params.Add("offset", strconv.Itoa(offset))
+
+		apiURL := "https://api.marketstack.com/v1/eod?" + params.Encode()
+
+		resp, err := m.httpClient.Get(apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch price history: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch price history: %s", resp.Status)
+		}
+
+		var marketResp MarketStackResponse
+		if err := json.Unmarshal(body, &marketResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		for _, price := range marketResp.Data {
+			p := price.toSymbolPrice()
+			if p.Date.IsZero() {
+				continue
+			}
+			history[price.Symbol] = append(history[price.Symbol], p)
+		}
+
+		if len(marketResp.Data) == 0 || offset+len(marketResp.Data) >= marketResp.Pagination.Total {
+			break
+		}
+	}
+
+	return history, nil
+}
+
 // FetchDividends fetches dividend transactions from MarketStack API
 func (m *MarketStackDataFetcher) FetchDividends(symbols []string) (map[string][]types.Transaction, error) {
 	if m.marketStackKey == "" {
@@ -163,7 +277,7 @@ func (m *MarketStackDataFetcher) FetchDividends(symbols []string) (map[string][]
 			Date:      utils.StringToDate(dateStr),
 			Type:      types.TransactionTypeDividend,
 			Quantity:  0,
-			Pps:       ppsInt,
+			Pps:       types.Money(ppsInt),
 		}
 
 		dividends[div.Symbol] = append(dividends[div.Symbol], transaction)
@@ -221,7 +335,7 @@ func (m *MarketStackDataFetcher) FetchSplits(symbols []string) (map[string][]typ
 			Date:      utils.StringToDate(dateStr),
 			Type:      types.TransactionTypeSplit,
 			Quantity:  0,
-			Pps:       ppsInt,
+			Pps:       types.Money(ppsInt),
 		}
 
 		splits[split.Symbol] = append(splits[split.Symbol], transaction)
@@ -230,15 +344,23 @@ func (m *MarketStackDataFetcher) FetchSplits(symbols []string) (map[string][]typ
 	return splits, nil
 }
 
+// ExchangeRatesTimeseriesResponse represents the daily rates of the Exchange Rates API, keyed by date and currency
+type ExchangeRatesTimeseriesResponse struct {
+	Rates map[string]map[string]float64 `json:"rates"`
+}
+
 // FetchExchangeRates fetches exchange rates from Exchange Rates API
-func (m *MarketStackDataFetcher) FetchExchangeRates() (map[string]float64, error) {
+func (m *MarketStackDataFetcher) FetchExchangeRates(currencies []string) (map[string]float64, error) {
 	if m.exchangeRatesKey == "" {
 		return nil, fmt.Errorf("EXCHANGE_RATES_API_KEY environment variable not set")
 	}
+	if len(currencies) == 0 {
+		return make(map[string]float64), nil
+	}
 
 	params := url.Values{}
 	params.Add("base", "USD")
-	params.Add("symbols", "ILS,EUR")
+	params.Add("symbols", strings.Join(currencies, ","))
 
 	apiURL := "https://api.apilayer.com/exchangerates_data/latest?" + params.Encode()
 
@@ -272,3 +394,118 @@ func (m *MarketStackDataFetcher) FetchExchangeRates() (map[string]float64, error
 
 	return exchangeResp.Rates, nil
 }
+
+// FetchExchangeRateHistory fetches the daily USD based rates from the Exchange Rates API timeseries endpoint, which
+// returns at most a year per request
+func (m *MarketStackDataFetcher) FetchExchangeRateHistory(currencies []string, from time.Time) (map[string][]types.FxRate, error) {
+	if m.exchangeRatesKey == "" {
+		return nil, fmt.Errorf("EXCHANGE_RATES_API_KEY environment variable not set")
+	}
+
+	history := make(map[string][]types.FxRate)
+	if len(currencies) == 0 {
+		return history, nil
+	}
+
+	today := time.Now()
+	for start := from; !start.After(today); start = start.AddDate(1, 0, 0) {
+		end := start.AddDate(1, 0, -1)
+		if end.After(today) {
+			end = today
+		}
+
+		params := url.Values{}
+		params.Add("base", "USD")
+		params.Add("symbols", strings.Join(currencies, ","))
+		params.Add("start_date", start.Format("2006-01-02"))
+		params.Add("end_date", end.Format("2006-01-02"))
+
+		apiURL := "https://api.apilayer.com/exchangerates_data/timeseries?" + params.Encode()
+
+		req, err := http.NewRequest("GET", apiURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("apikey", m.exchangeRatesKey)
+
+		resp, err := m.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch exchange rate history: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch exchange rate history: %s", resp.Status)
+		}
+
+		var seriesResp ExchangeRatesTimeseriesResponse
+		if err := json.Unmarshal(body, &seriesResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		for date, rates := range seriesResp.Rates {
+			day, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				continue
+			}
+			for currency, value := range rates {
+				history[currency] = append(history[currency], types.FxRate{Currency: currency, Date: day, Value: value})
+			}
+		}
+	}
+
+	for currency := range history {
+		slices.SortFunc(history[currency], func(a, b types.FxRate) int {
+			return a.Date.Compare(b.Date)
+		})
+	}
+
+	return history, nil
+}
+
+// FetchSymbolInfo fetches the name, exchange, sector and country of every symbol from MarketStack tickers API,
+// one request per symbol
+func (m *MarketStackDataFetcher) FetchSymbolInfo(symbols []string) (map[string]types.SymbolInfo, error) {
+	if m.marketStackKey == "" {
+		return nil, fmt.Errorf("MARKETSTACK_API_KEY environment variable not set")
+	}
+
+	infos := make(map[string]types.SymbolInfo, len(symbols))
+	for _, symbol := range symbols {
+		params := url.Values{}
+		params.Add("access_key", m.marketStackKey)
+
+		apiURL := "https://api.marketstack.com/v1/tickers/" + url.PathEscape(symbol) + "?" + params.Encode()
+
+		resp, err := m.httpClient.Get(apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch symbol info: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		// unknown symbols are skipped, they can still be described by hand
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		var ticker MarketStackTicker
+		if err := json.Unmarshal(body, &ticker); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if ticker.Symbol == "" {
+			continue
+		}
+
+		infos[symbol] = ticker.toSymbolInfo()
+	}
+
+	return infos, nil
+}
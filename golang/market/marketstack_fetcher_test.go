@@ -0,0 +1,44 @@
+package market
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusTransport answers every request with status and an empty JSON body
+type statusTransport struct {
+	status int
+}
+
+func (s statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: s.status,
+		Status:     http.StatusText(s.status),
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func TestMarketStackHistoryFailsOnErrorStatus(t *testing.T) {
+	fetcher := &MarketStackDataFetcher{
+		httpClient:       &http.Client{Transport: statusTransport{status: http.StatusTooManyRequests}},
+		marketStackKey:   "key",
+		exchangeRatesKey: "key",
+	}
+	from := time.Now().AddDate(0, -1, 0)
+
+	if _, err := fetcher.FetchPriceHistory([]string{"AAPL"}, from); err == nil {
+		t.Errorf("expected price history to fail on a rate limited reply")
+	}
+	if _, err := fetcher.FetchExchangeRateHistory([]string{"ILS"}, from); err == nil {
+		t.Errorf("expected exchange rate history to fail on a rate limited reply")
+	}
+
+	fetcher.httpClient.Transport = statusTransport{status: http.StatusUnauthorized}
+	if _, err := fetcher.FetchPriceHistory([]string{"AAPL"}, from); err == nil {
+		t.Errorf("expected price history to fail on an unauthorized reply")
+	}
+}
@@ -1,4 +0,0 @@
-package market
-
-var CurrencySymbolUSD = "$"
-var CurrencySymbolILS = "₪"
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 	"tracker/loaders"
@@ -11,11 +13,13 @@ import (
 	"tracker/types"
 )
 
-func UpdateMarketData(db *sql.DB) {
-	UpdateMarketDataWithFetcher(db, NewMarketStackDataFetcher())
+// UpdateMarketData fetches the market data of every traded symbol, extraSymbols (e.g. benchmarks) are fetched as well.
+// The exchange rates of currencies and of every currency the transactions are in are updated.
+func UpdateMarketData(db *sql.DB, currencies []string, extraSymbols ...string) {
+	UpdateMarketDataWithFetcher(db, NewMarketStackDataFetcher(), currencies, extraSymbols...)
 }
 
-func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
+func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher, currencies []string, extraSymbols ...string) {
 	start := time.Now()
 	logger := logging.Get()
 	logger.Info("Starting market data update")
@@ -25,8 +29,15 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 		logger.Error("failed to load all symbols", slog.Any("error", err))
 		return
 	}
+	allSymbols = withExtraSymbols(allSymbols, extraSymbols)
 	logger.Info("Loaded symbols", slog.Int("count", len(allSymbols)))
 
+	currencies = rateCurrencies(db, currencies)
+	logger.Info("Loaded currencies", slog.Int("count", len(currencies)))
+
+	missingInfo := symbolsWithoutInfo(db, allSymbols)
+	logger.Info("Loaded symbols without metadata", slog.Int("count", len(missingInfo)))
+
 	logger.Info("Fetching market data in parallel")
 	var wg sync.WaitGroup
 
@@ -65,7 +76,7 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 
 	var rates map[string]float64
 	wg.Go(func() {
-		e, err := fetcher.FetchExchangeRates()
+		e, err := fetcher.FetchExchangeRates(currencies)
 		if err != nil {
 			logger.Error("Error loading rates", slog.Any("error", err))
 			return
@@ -74,12 +85,26 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 		rates = e
 	})
 
+	var infos map[string]types.SymbolInfo
+	if len(missingInfo) > 0 {
+		wg.Go(func() {
+			i, err := fetcher.FetchSymbolInfo(missingInfo)
+			if err != nil {
+				logger.Error("Error loading symbols metadata", slog.Any("error", err))
+				return
+			}
+
+			infos = i
+		})
+	}
+
 	wg.Wait()
 	logger.Info("Finished fetching market data",
 		slog.Int("prices", len(prices)),
 		slog.Int("dividends", len(dividends)),
 		slog.Int("splits", len(splits)),
-		slog.Int("rates", len(rates)))
+		slog.Int("rates", len(rates)),
+		slog.Int("symbols", len(infos)))
 
 	ctx := context.TODO()
 	tx, err := db.BeginTx(ctx, nil)
@@ -100,6 +125,10 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 			logger.Error("error batch upserting prices", slog.Any("error", err))
 			return
 		}
+		if err := batchUpsertPriceHistory(ctx, tx, priceList, now); err != nil {
+			logger.Error("error batch upserting price history", slog.Any("error", err))
+			return
+		}
 		logger.Info("Finished upserting prices")
 	}
 
@@ -159,9 +188,36 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 			logger.Error("error batch upserting rates", slog.Any("error", err))
 			return
 		}
+		fxRates := make([]types.FxRate, 0, len(rateList))
+		for _, r := range rateList {
+			fxRates = append(fxRates, types.FxRate{Currency: r.Symbol, Date: now, Value: r.Value})
+		}
+		if err := batchUpsertFxRates(ctx, tx, fxRates, now); err != nil {
+			logger.Error("error batch upserting rate history", slog.Any("error", err))
+			return
+		}
 		logger.Info("Finished upserting rates")
 	}
 
+	if len(infos) > 0 {
+		infoList := make([]types.SymbolInfo, 0, len(infos))
+		for symbol, info := range infos {
+			if info.Symbol == "" {
+				info.Symbol = symbol
+			}
+			if p, ok := prices[symbol]; ok && info.Currency == "" {
+				info.Currency = p.Currency
+			}
+			infoList = append(infoList, info)
+		}
+		logger.Info("Upserting symbols metadata", slog.Int("count", len(infoList)))
+		if err := batchUpsertSymbolInfo(ctx, tx, infoList, now); err != nil {
+			logger.Error("error batch upserting symbols metadata", slog.Any("error", err))
+			return
+		}
+		logger.Info("Finished upserting symbols metadata")
+	}
+
 	logger.Info("Committing transaction")
 	err = tx.Commit()
 	if err != nil {
@@ -174,6 +230,49 @@ func UpdateMarketDataWithFetcher(db *sql.DB, fetcher DateFetcher) {
 
 const batchSize = 100
 
+// rateCurrencies returns currencies together with the currencies of the transactions, without USD which every
+// rate is relative to
+func rateCurrencies(db *sql.DB, currencies []string) []string {
+	if traded, err := loaders.TransactionCurrencies(db); err == nil {
+		currencies = withExtraSymbols(slices.Clone(currencies), traded)
+	}
+
+	ret := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		currency = types.NormalizeCurrency(currency)
+		if currency != types.CurrencyUSD && !slices.Contains(ret, currency) {
+			ret = append(ret, currency)
+		}
+	}
+	return ret
+}
+
+// symbolsWithoutInfo returns the symbols that have no metadata yet, metadata is fetched once per symbol
+func symbolsWithoutInfo(db *sql.DB, symbols []string) []string {
+	infos, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		return nil
+	}
+
+	ret := make([]string, 0)
+	for _, symbol := range symbols {
+		if _, ok := infos[strings.ToLower(symbol)]; !ok {
+			ret = append(ret, symbol)
+		}
+	}
+	return ret
+}
+
+// withExtraSymbols appends the extra symbols that are not already part of symbols
+func withExtraSymbols(symbols []string, extra []string) []string {
+	for _, e := range extra {
+		if !slices.ContainsFunc(symbols, func(s string) bool { return strings.EqualFold(s, e) }) {
+			symbols = append(symbols, e)
+		}
+	}
+	return symbols
+}
+
 func batchUpsertPrices(ctx context.Context, tx *sql.Tx, prices []types.SymbolPrice, now time.Time) error {
 	if len(prices) == 0 {
 		return nil
@@ -205,6 +304,48 @@ func batchUpsertPrices(ctx context.Context, tx *sql.Tx, prices []types.SymbolPri
 	return nil
 }
 
+// batchUpsertPriceHistory stores a row per symbol and trading day, prices without a trading date are
+// recorded under the date of the update. History is kept at the close of the day, adj_close is rewritten by every
+// later split while the close matches the number of shares held on that day. Prices without a close keep AdjPrice.
+func batchUpsertPriceHistory(ctx context.Context, tx *sql.Tx, prices []types.SymbolPrice, now time.Time) error {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	const cols = 4
+	for i := 0; i < len(prices); i += batchSize {
+		end := min(i+batchSize, len(prices))
+		batch := prices[i:end]
+
+		placeholders := make([]byte, 0, len(batch)*(cols*2+3))
+		for j := range batch {
+			if j > 0 {
+				placeholders = append(placeholders, ',')
+			}
+			placeholders = append(placeholders, "(?,?,?,?)"...)
+		}
+
+		query := "INSERT OR REPLACE INTO price_history (symbol, date, adj_close, created_at) VALUES " + string(placeholders)
+		args := make([]any, 0, len(batch)*cols)
+		for _, p := range batch {
+			date := p.Date
+			if date.IsZero() {
+				date = now
+			}
+			price := p.Close
+			if price == 0 {
+				price = p.AdjPrice
+			}
+			args = append(args, p.Symbol, date.Format("2006-01-02"), price, now)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type rateEntry struct {
 	Symbol string
 	Value  float64
@@ -244,6 +385,76 @@ func batchUpsertRates(ctx context.Context, tx *sql.Tx, rates []struct {
 	return nil
 }
 
+// batchUpsertFxRates stores a row per currency and day, a later rate of the same day replaces the earlier one
+func batchUpsertFxRates(ctx context.Context, tx *sql.Tx, rates []types.FxRate, now time.Time) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	const cols = 4
+	for i := 0; i < len(rates); i += batchSize {
+		end := min(i+batchSize, len(rates))
+		batch := rates[i:end]
+
+		placeholders := make([]byte, 0, len(batch)*(cols*2+3))
+		for j := range batch {
+			if j > 0 {
+				placeholders = append(placeholders, ',')
+			}
+			placeholders = append(placeholders, "(?,?,?,?)"...)
+		}
+
+		query := "INSERT OR REPLACE INTO fx_rates (currency, date, value, created_at) VALUES " + string(placeholders)
+		args := make([]any, 0, len(batch)*cols)
+		for _, r := range batch {
+			args = append(args, types.NormalizeCurrency(r.Currency), r.Date.Format("2006-01-02"), r.Value, now)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchUpsertSymbolInfo stores fetched symbols metadata, metadata edited by hand is left as is
+func batchUpsertSymbolInfo(ctx context.Context, tx *sql.Tx, infos []types.SymbolInfo, now time.Time) error {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	const cols = 9
+	for i := 0; i < len(infos); i += batchSize {
+		end := min(i+batchSize, len(infos))
+		batch := infos[i:end]
+
+		placeholders := make([]byte, 0, len(batch)*(cols*2+3))
+		for j := range batch {
+			if j > 0 {
+				placeholders = append(placeholders, ',')
+			}
+			placeholders = append(placeholders, "(?,?,?,?,?,?,?,?,?)"...)
+		}
+
+		query := "INSERT INTO symbols (symbol, name, exchange, currency, asset_class, sector, country, isin, updated_at) VALUES " +
+			string(placeholders) +
+			` ON CONFLICT(symbol) DO UPDATE SET name = excluded.name, exchange = excluded.exchange,
+			currency = excluded.currency, asset_class = excluded.asset_class, sector = excluded.sector,
+			country = excluded.country, isin = excluded.isin, updated_at = excluded.updated_at
+			WHERE symbols.manual = 0`
+		args := make([]any, 0, len(batch)*cols)
+		for _, s := range batch {
+			args = append(args, strings.ToUpper(s.Symbol), s.Name, s.Exchange, strings.ToUpper(s.Currency),
+				s.AssetClass, s.Sector, s.Country, s.ISIN, now)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func batchDeleteDividendsSplits(ctx context.Context, tx *sql.Tx, symbols []string) error {
 	if len(symbols) == 0 {
 		return nil
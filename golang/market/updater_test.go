@@ -1,28 +1,45 @@
 package market
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
+	"tracker/loaders"
 	"tracker/types"
 
 	_ "github.com/tursodatabase/go-libsql"
 )
 
 type MockFetcher struct {
-	Prices    map[string]types.SymbolPrice
-	Dividends map[string][]types.Transaction
-	Splits    map[string][]types.Transaction
-	Rates     map[string]float64
-
-	PricesErr    error
-	DividendsErr error
-	SplitsErr    error
-	RatesErr     error
+	Prices       map[string]types.SymbolPrice
+	Dividends    map[string][]types.Transaction
+	Splits       map[string][]types.Transaction
+	Rates        map[string]float64
+	PriceHistory map[string][]types.SymbolPrice
+	RateHistory  map[string][]types.FxRate
+	SymbolInfo   map[string]types.SymbolInfo
+
+	PricesErr       error
+	DividendsErr    error
+	SplitsErr       error
+	RatesErr        error
+	PriceHistoryErr error
+	RateHistoryErr  error
+	SymbolInfoErr   error
+
+	PriceHistoryFrom   time.Time
+	PricesSymbols      []string
+	RatesSymbols       []string
+	RateHistoryFrom    time.Time
+	RateHistorySymbols []string
+	InfoSymbols        []string
 }
 
 func (m *MockFetcher) FetchPrices(symbols []string) (map[string]types.SymbolPrice, error) {
+	m.PricesSymbols = symbols
 	if m.PricesErr != nil {
 		return nil, m.PricesErr
 	}
@@ -43,13 +60,39 @@ func (m *MockFetcher) FetchSplits(symbols []string) (map[string][]types.Transact
 	return m.Splits, nil
 }
 
-func (m *MockFetcher) FetchExchangeRates() (map[string]float64, error) {
+func (m *MockFetcher) FetchPriceHistory(symbols []string, from time.Time) (map[string][]types.SymbolPrice, error) {
+	m.PriceHistoryFrom = from
+	if m.PriceHistoryErr != nil {
+		return nil, m.PriceHistoryErr
+	}
+	return m.PriceHistory, nil
+}
+
+func (m *MockFetcher) FetchExchangeRates(currencies []string) (map[string]float64, error) {
+	m.RatesSymbols = currencies
 	if m.RatesErr != nil {
 		return nil, m.RatesErr
 	}
 	return m.Rates, nil
 }
 
+func (m *MockFetcher) FetchExchangeRateHistory(currencies []string, from time.Time) (map[string][]types.FxRate, error) {
+	m.RateHistoryFrom = from
+	m.RateHistorySymbols = currencies
+	if m.RateHistoryErr != nil {
+		return nil, m.RateHistoryErr
+	}
+	return m.RateHistory, nil
+}
+
+func (m *MockFetcher) FetchSymbolInfo(symbols []string) (map[string]types.SymbolInfo, error) {
+	m.InfoSymbols = symbols
+	if m.SymbolInfoErr != nil {
+		return nil, m.SymbolInfoErr
+	}
+	return m.SymbolInfo, nil
+}
+
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
 	t.Helper()
 
@@ -66,7 +109,10 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			date TEXT NOT NULL,
 			transaction_type TEXT NOT NULL,
 			quantity INTEGER NOT NULL,
-			pps INTEGER NOT NULL
+			pps INTEGER NOT NULL,
+			fee INTEGER NOT NULL DEFAULT 0,
+			transfer_id TEXT NOT NULL DEFAULT '',
+			currency TEXT NOT NULL DEFAULT 'USD'
 		)`,
 		`CREATE TABLE IF NOT EXISTS dividends_splits (
 			id TEXT PRIMARY KEY,
@@ -85,6 +131,7 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			institution_id TEXT NOT NULL,
 			description TEXT,
 			tags TEXT,
+			base_currency TEXT NOT NULL DEFAULT 'USD',
 			created_at TEXT,
 			updated_at TEXT
 		)`,
@@ -98,6 +145,42 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			value FLOAT NOT NULL,
 			created_at DATETIME NULL
 		)`,
+		`CREATE TABLE IF NOT EXISTS price_history (
+			symbol TEXT NOT NULL,
+			date TEXT NOT NULL,
+			adj_close INTEGER NOT NULL,
+			created_at DATETIME NULL,
+			PRIMARY KEY (symbol, date)
+		)`,
+		`CREATE TABLE IF NOT EXISTS fx_rates (
+			currency TEXT NOT NULL,
+			date TEXT NOT NULL,
+			value FLOAT NOT NULL,
+			created_at DATETIME NULL,
+			PRIMARY KEY (currency, date)
+		)`,
+		`CREATE TABLE IF NOT EXISTS symbols (
+			symbol TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			exchange TEXT NOT NULL DEFAULT '',
+			currency TEXT NOT NULL DEFAULT '',
+			asset_class TEXT NOT NULL DEFAULT '',
+			sector TEXT NOT NULL DEFAULT '',
+			country TEXT NOT NULL DEFAULT '',
+			isin TEXT NOT NULL DEFAULT '',
+			manual INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS corporate_actions (
+			id TEXT PRIMARY KEY,
+			date TEXT NOT NULL,
+			action_type TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			new_symbol TEXT NOT NULL DEFAULT '',
+			shares_per_share TEXT NOT NULL DEFAULT '0',
+			cash_per_share INTEGER NOT NULL DEFAULT 0,
+			basis_allocation REAL NOT NULL DEFAULT 0
+		)`,
 	}
 
 	for _, schema := range schemas {
@@ -134,7 +217,7 @@ func TestUpdateMarketData_Prices(t *testing.T) {
 		Rates:     make(map[string]float64),
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var count int
 	err := db.QueryRow("SELECT COUNT(*) FROM prices").Scan(&count)
@@ -164,19 +247,19 @@ func TestUpdateMarketData_DividendsAndSplits(t *testing.T) {
 		Prices: make(map[string]types.SymbolPrice),
 		Dividends: map[string][]types.Transaction{
 			"AAPL": {
-				{Id: "div1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: 100, Pps: 50},
-				{Id: "div2", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: 100, Pps: 55},
+				{Id: "div1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(100), Pps: 50},
+				{Id: "div2", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(100), Pps: 55},
 			},
 		},
 		Splits: map[string][]types.Transaction{
 			"AAPL": {
-				{Id: "split1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeSplit, Quantity: 4, Pps: 0},
+				{Id: "split1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeSplit, Quantity: types.NewQuantity(4), Pps: 0},
 			},
 		},
 		Rates: make(map[string]float64),
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var count int
 	err := db.QueryRow("SELECT COUNT(*) FROM dividends_splits").Scan(&count)
@@ -219,7 +302,7 @@ func TestUpdateMarketData_Rates(t *testing.T) {
 		},
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var count int
 	err := db.QueryRow("SELECT COUNT(*) FROM rates").Scan(&count)
@@ -238,6 +321,95 @@ func TestUpdateMarketData_Rates(t *testing.T) {
 	if eurRate != 0.92 {
 		t.Errorf("expected EUR rate 0.92, got %f", eurRate)
 	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM fx_rates WHERE date = ?", time.Now().Format("2006-01-02")).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to query daily rates count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 daily rates for today, got %d", count)
+	}
+}
+
+func TestUpdateMarketData_RateCurrencies(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps, currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"tx2", "acc1", "TEVA", "2024-01-01", "Buy", 10, 3000, "ILS")
+	if err != nil {
+		t.Fatalf("failed to insert test transaction: %v", err)
+	}
+
+	fetcher := &MockFetcher{
+		Rates: map[string]float64{"ILS": 3.7, "EUR": 0.92, "GBP": 0.79},
+	}
+
+	UpdateMarketDataWithFetcher(db, fetcher, []string{"EUR", "usd", "gbp"})
+
+	// the configured currencies followed by the traded ones, USD is the base of every rate
+	expected := []string{"EUR", "GBP", "ILS"}
+	if !slices.Equal(fetcher.RatesSymbols, expected) {
+		t.Errorf("expected rates of %v to be fetched, got %v", expected, fetcher.RatesSymbols)
+	}
+}
+
+func TestUpdateMarketData_SymbolInfo(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps, currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"tx2", "acc1", "MSFT", "2024-01-01", "Buy", 10, 30000, "USD")
+	if err != nil {
+		t.Fatalf("failed to insert test transaction: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO symbols (symbol, name, sector, manual) VALUES (?, ?, ?, 1)`, "MSFT", "My Microsoft", "Software")
+	if err != nil {
+		t.Fatalf("failed to insert manual symbol: %v", err)
+	}
+
+	fetcher := &MockFetcher{
+		SymbolInfo: map[string]types.SymbolInfo{
+			"AAPL": {Symbol: "AAPL", Name: "Apple Inc", Exchange: "NASDAQ", AssetClass: types.AssetClassStock, Sector: "Technology", Country: "US"},
+		},
+	}
+
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
+
+	// only symbols without metadata are fetched
+	if !slices.Equal(fetcher.InfoSymbols, []string{"AAPL"}) {
+		t.Errorf("expected metadata of [AAPL] to be fetched, got %v", fetcher.InfoSymbols)
+	}
+
+	var name, sector string
+	err = db.QueryRow("SELECT name, sector FROM symbols WHERE symbol = ?", "AAPL").Scan(&name, &sector)
+	if err != nil {
+		t.Fatalf("failed to query AAPL metadata: %v", err)
+	}
+	if name != "Apple Inc" || sector != "Technology" {
+		t.Errorf("expected AAPL to be Apple Inc in Technology, got %s in %s", name, sector)
+	}
+
+	// fetching again doesn't replace metadata edited by hand
+	fetcher.SymbolInfo = map[string]types.SymbolInfo{"MSFT": {Symbol: "MSFT", Name: "Microsoft Corp", Sector: "Technology"}}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	if err := batchUpsertSymbolInfo(context.TODO(), tx, []types.SymbolInfo{fetcher.SymbolInfo["MSFT"]}, time.Now()); err != nil {
+		t.Fatalf("failed to upsert symbol metadata: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	err = db.QueryRow("SELECT name, sector FROM symbols WHERE symbol = ?", "MSFT").Scan(&name, &sector)
+	if err != nil {
+		t.Fatalf("failed to query MSFT metadata: %v", err)
+	}
+	if name != "My Microsoft" || sector != "Software" {
+		t.Errorf("expected manual MSFT metadata to be kept, got %s in %s", name, sector)
+	}
 }
 
 func TestUpdateMarketData_PricesUpdate(t *testing.T) {
@@ -258,7 +430,7 @@ func TestUpdateMarketData_PricesUpdate(t *testing.T) {
 		Rates:     make(map[string]float64),
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var adjClose int
 	err = db.QueryRow("SELECT adj_close FROM prices WHERE symbol = ?", "AAPL").Scan(&adjClose)
@@ -295,14 +467,14 @@ func TestUpdateMarketData_DividendsSplitsReplacement(t *testing.T) {
 		Prices: make(map[string]types.SymbolPrice),
 		Dividends: map[string][]types.Transaction{
 			"AAPL": {
-				{Id: "new_div", AccountId: "acc1", Symbol: "AAPL", Date: newDate, Type: types.TransactionTypeDividend, Quantity: 100, Pps: 50},
+				{Id: "new_div", AccountId: "acc1", Symbol: "AAPL", Date: newDate, Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(100), Pps: 50},
 			},
 		},
 		Splits: make(map[string][]types.Transaction),
 		Rates:  make(map[string]float64),
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var count int
 	err = db.QueryRow("SELECT COUNT(*) FROM dividends_splits WHERE symbol = ?", "AAPL").Scan(&count)
@@ -336,7 +508,7 @@ func TestUpdateMarketData_LargeBatchDividends(t *testing.T) {
 			Symbol:    "AAPL",
 			Date:      testDate,
 			Type:      types.TransactionTypeDividend,
-			Quantity:  int32(i + 1),
+			Quantity:  types.NewQuantity(int64(i + 1)),
 			Pps:       50,
 		}
 	}
@@ -350,7 +522,7 @@ func TestUpdateMarketData_LargeBatchDividends(t *testing.T) {
 		Rates:  make(map[string]float64),
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var count int
 	err := db.QueryRow("SELECT COUNT(*) FROM dividends_splits").Scan(&count)
@@ -374,12 +546,12 @@ func TestUpdateMarketData_AllDataTypes(t *testing.T) {
 		},
 		Dividends: map[string][]types.Transaction{
 			"AAPL": {
-				{Id: "div1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: 100, Pps: 50},
+				{Id: "div1", AccountId: "acc1", Symbol: "AAPL", Date: testDate, Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(100), Pps: 50},
 			},
 		},
 		Splits: map[string][]types.Transaction{
 			"GOOGL": {
-				{Id: "split1", AccountId: "acc1", Symbol: "GOOGL", Date: testDate, Type: types.TransactionTypeSplit, Quantity: 20, Pps: 0},
+				{Id: "split1", AccountId: "acc1", Symbol: "GOOGL", Date: testDate, Type: types.TransactionTypeSplit, Quantity: types.NewQuantity(20), Pps: 0},
 			},
 		},
 		Rates: map[string]float64{
@@ -388,7 +560,7 @@ func TestUpdateMarketData_AllDataTypes(t *testing.T) {
 		},
 	}
 
-	UpdateMarketDataWithFetcher(db, fetcher)
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
 
 	var priceCount, divSplitCount, rateCount int
 	db.QueryRow("SELECT COUNT(*) FROM prices").Scan(&priceCount)
@@ -405,3 +577,116 @@ func TestUpdateMarketData_AllDataTypes(t *testing.T) {
 		t.Errorf("expected 2 rates, got %d", rateCount)
 	}
 }
+
+func TestUpdateMarketData_PriceHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	day1 := time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 6, 17, 0, 0, 0, 0, time.UTC)
+	fetcher := &MockFetcher{
+		Prices: map[string]types.SymbolPrice{
+			"AAPL": {Symbol: "AAPL", AdjPrice: 17500, Date: day1},
+		},
+		Dividends: make(map[string][]types.Transaction),
+		Splits:    make(map[string][]types.Transaction),
+		Rates:     make(map[string]float64),
+	}
+
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
+
+	// same trading day fetched again replaces the row
+	fetcher.Prices["AAPL"] = types.SymbolPrice{Symbol: "AAPL", AdjPrice: 17600, Date: day1}
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
+
+	// history keeps the close of the day like the backfill, the latest price stays adjusted
+	fetcher.Prices["AAPL"] = types.SymbolPrice{Symbol: "AAPL", AdjPrice: 18000, Close: 18100, Date: day2}
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM price_history WHERE symbol = ?", "AAPL").Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to query price history count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 AAPL price history rows, got %d", count)
+	}
+
+	var adjClose int
+	err = db.QueryRow("SELECT adj_close FROM price_history WHERE symbol = ? AND date = ?", "AAPL", "2024-06-14").Scan(&adjClose)
+	if err != nil {
+		t.Fatalf("failed to query AAPL history price: %v", err)
+	}
+	if adjClose != 17600 {
+		t.Errorf("expected AAPL 2024-06-14 price 17600, got %d", adjClose)
+	}
+
+	err = db.QueryRow("SELECT adj_close FROM price_history WHERE symbol = ? AND date = ?", "AAPL", "2024-06-17").Scan(&adjClose)
+	if err != nil {
+		t.Fatalf("failed to query AAPL history price: %v", err)
+	}
+	if adjClose != 18100 {
+		t.Errorf("expected AAPL 2024-06-17 close 18100, got %d", adjClose)
+	}
+
+	err = db.QueryRow("SELECT adj_close FROM prices WHERE symbol = ?", "AAPL").Scan(&adjClose)
+	if err != nil {
+		t.Fatalf("failed to query AAPL price: %v", err)
+	}
+	if adjClose != 18000 {
+		t.Errorf("expected latest AAPL price 18000, got %d", adjClose)
+	}
+}
+
+func TestUpdateMarketData_ExtraSymbols(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fetcher := &MockFetcher{
+		Prices:    make(map[string]types.SymbolPrice),
+		Dividends: make(map[string][]types.Transaction),
+		Splits:    make(map[string][]types.Transaction),
+		Rates:     make(map[string]float64),
+	}
+
+	UpdateMarketDataWithFetcher(db, fetcher, nil, "SPY", "aapl")
+
+	if len(fetcher.PricesSymbols) != 2 || fetcher.PricesSymbols[0] != "AAPL" || fetcher.PricesSymbols[1] != "SPY" {
+		t.Errorf("expected AAPL and SPY to be fetched once, got %v", fetcher.PricesSymbols)
+	}
+}
+
+func TestUpdateMarketData_CorporateActions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO transactions (id, account_id, symbol, date, transaction_type, quantity, pps) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"tx2", "acc1", "FB", "2020-01-01", "Buy", 10, 20000)
+	if err != nil {
+		t.Fatalf("failed to insert test transaction: %v", err)
+	}
+
+	actions := []types.CorporateAction{
+		{Id: "ca1", Date: time.Date(2022, 6, 9, 0, 0, 0, 0, time.UTC), Type: types.CorporateActionRename, Symbol: "FB", NewSymbol: "META"},
+		{Id: "ca2", Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Type: types.CorporateActionSpinOff, Symbol: "META", NewSymbol: "SPUN", SharesPerShare: types.NewQuantity(1), BasisAllocation: 0.1},
+	}
+	for _, a := range actions {
+		if err := loaders.AddCorporateAction(db, a); err != nil {
+			t.Fatalf("failed to insert corporate action: %v", err)
+		}
+	}
+
+	fetcher := &MockFetcher{
+		Prices:    make(map[string]types.SymbolPrice),
+		Dividends: make(map[string][]types.Transaction),
+		Splits:    make(map[string][]types.Transaction),
+		Rates:     make(map[string]float64),
+	}
+
+	UpdateMarketDataWithFetcher(db, fetcher, nil)
+
+	expected := []string{"AAPL", "META", "SPUN"}
+	if fmt.Sprint(fetcher.PricesSymbols) != fmt.Sprint(expected) {
+		t.Errorf("expected %v to be fetched after following FB's lineage, got %v", expected, fetcher.PricesSymbols)
+	}
+}
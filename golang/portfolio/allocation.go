@@ -0,0 +1,109 @@
+package portfolio
+
+import (
+	"sort"
+	"tracker/types"
+)
+
+const (
+	unknownAllocation  = "Unknown"
+	untaggedAllocation = "Untagged"
+)
+
+var allocationGroups = []string{
+	types.AllocationAssetClass,
+	types.AllocationSector,
+	types.AllocationCountry,
+	types.AllocationCurrency,
+	types.AllocationInstitution,
+	types.AllocationTag,
+}
+
+// BuildAllocation splits the market value of the current holdings of accounts, as analyzed in accountsData, by asset
+// class, sector, country, currency, institution and tag. Holdings without metadata are grouped as Unknown. An account
+// with several tags counts toward each of them, so the tag percentages may add up to more than 100%.
+func BuildAllocation(accounts []types.Account, accountsData map[string]types.AnalyzedPortfolio, prices map[string]types.SymbolPrice, symbols map[string]types.SymbolInfo) (types.Allocation, error) {
+	var acc moneyAccumulator
+	values := make(map[string]map[string]types.Money, len(allocationGroups))
+	for _, name := range allocationGroups {
+		values[name] = make(map[string]types.Money)
+	}
+	add := func(group, label string, value types.Money) {
+		if label == "" {
+			label = unknownAllocation
+		}
+		sum := values[group][label]
+		acc.add(&sum, value)
+		values[group][label] = sum
+	}
+
+	var total types.Money
+	for _, ac := range accounts {
+		p, ok := accountsData[ac.Id]
+		if !ok {
+			continue
+		}
+
+		holdings, err := BuildHoldingRows(p, prices, symbols)
+		if err != nil {
+			return types.Allocation{}, err
+		}
+		currencies := newCurrencyBook(p.Transactions, prices, AnalyzeOptions{})
+		for _, h := range holdings {
+			if h.MarketValue <= 0 {
+				continue
+			}
+			acc.add(&total, h.MarketValue)
+
+			add(types.AllocationAssetClass, string(h.Info.AssetClass), h.MarketValue)
+			add(types.AllocationSector, h.Info.Sector, h.MarketValue)
+			add(types.AllocationCountry, h.Info.Country, h.MarketValue)
+			currency := h.Info.Currency
+			if currency == "" {
+				currency = currencies.symbolCurrency(h.Symbol)
+			}
+			add(types.AllocationCurrency, types.NormalizeCurrency(currency), h.MarketValue)
+			add(types.AllocationInstitution, ac.Institution, h.MarketValue)
+			if len(ac.Tags) == 0 {
+				add(types.AllocationTag, untaggedAllocation, h.MarketValue)
+			}
+			for _, tag := range ac.Tags {
+				add(types.AllocationTag, tag, h.MarketValue)
+			}
+		}
+	}
+	if acc.err != nil {
+		return types.Allocation{}, acc.err
+	}
+
+	allocation := types.Allocation{Total: total, Groups: make([]types.AllocationGroup, 0, len(allocationGroups))}
+	for _, name := range allocationGroups {
+		allocation.Groups = append(allocation.Groups, types.AllocationGroup{
+			Name:   name,
+			Slices: allocationSlices(values[name], total),
+		})
+	}
+
+	return allocation, nil
+}
+
+// allocationSlices returns a slice per label sorted by value, with its percent of total
+func allocationSlices(values map[string]types.Money, total types.Money) []types.AllocationSlice {
+	slices := make([]types.AllocationSlice, 0, len(values))
+	for label, value := range values {
+		slice := types.AllocationSlice{Label: label, Value: value}
+		if total != 0 {
+			slice.Percent = float32(float64(value) / float64(total))
+		}
+		slices = append(slices, slice)
+	}
+
+	sort.SliceStable(slices, func(i, j int) bool {
+		if slices[i].Value == slices[j].Value {
+			return slices[i].Label < slices[j].Label
+		}
+		return slices[i].Value > slices[j].Value
+	})
+
+	return slices
+}
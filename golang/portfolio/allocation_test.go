@@ -0,0 +1,75 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestBuildAllocation(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 300},
+		"vwrl": {Symbol: "VWRL", AdjPrice: 100},
+		"teva": {Symbol: "TEVA", AdjPrice: 100},
+	}
+	symbols := map[string]types.SymbolInfo{
+		"aapl": {Symbol: "AAPL", AssetClass: types.AssetClassStock, Sector: "Technology", Country: "US"},
+		"vwrl": {Symbol: "VWRL", AssetClass: types.AssetClassETF, Currency: "EUR"},
+	}
+
+	accounts := []types.Account{
+		{Id: "1", Institution: "Broker", Tags: []string{"Long", "Kids"}},
+		{Id: "2", Institution: "Bank"},
+	}
+	first, err := AnalyzeTransactions([]types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "VWRL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(20), Pps: 100, Currency: "EUR", Date: utils.StringToDate("2023-01-01")},
+	}, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	second, err := AnalyzeTransactions([]types.Transaction{
+		{Id: "b3", AccountId: "2", Symbol: "TEVA", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(30), Pps: 100, Currency: "ILS", Date: utils.StringToDate("2023-01-01")},
+	}, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	accountsData := map[string]types.AnalyzedPortfolio{"1": first, "2": second}
+
+	allocation, err := BuildAllocation(accounts, accountsData, prices, symbols)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if allocation.Total != 8000 {
+		t.Fatalf("Expected total to be 8000 but got %d\n", allocation.Total)
+	}
+
+	expected := map[string][]types.AllocationSlice{
+		types.AllocationAssetClass:  {{Label: "Stock", Value: 3000, Percent: 0.375}, {Label: "Unknown", Value: 3000, Percent: 0.375}, {Label: "ETF", Value: 2000, Percent: 0.25}},
+		types.AllocationSector:      {{Label: "Unknown", Value: 5000, Percent: 0.625}, {Label: "Technology", Value: 3000, Percent: 0.375}},
+		types.AllocationCurrency:    {{Label: "ILS", Value: 3000, Percent: 0.375}, {Label: "USD", Value: 3000, Percent: 0.375}, {Label: "EUR", Value: 2000, Percent: 0.25}},
+		types.AllocationInstitution: {{Label: "Broker", Value: 5000, Percent: 0.625}, {Label: "Bank", Value: 3000, Percent: 0.375}},
+		// an account counts toward each of its tags
+		types.AllocationTag: {{Label: "Kids", Value: 5000, Percent: 0.625}, {Label: "Long", Value: 5000, Percent: 0.625}, {Label: "Untagged", Value: 3000, Percent: 0.375}},
+	}
+	for name, slices := range expected {
+		group := allocation.Group(name)
+		if len(group.Slices) != len(slices) {
+			t.Fatalf("Expected %d %s slices but got %d\n", len(slices), name, len(group.Slices))
+		}
+		for i, slice := range slices {
+			if group.Slices[i] != slice {
+				t.Fatalf("Expected %s slice %d to be %v but got %v\n", name, i, slice, group.Slices[i])
+			}
+		}
+	}
+
+	// the tag filter is applied by passing only the accounts of the tag
+	allocation, err = BuildAllocation(accounts[1:], accountsData, prices, symbols)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if allocation.Total != 3000 || len(allocation.Group(types.AllocationInstitution).Slices) != 1 {
+		t.Fatalf("Expected only the Bank account in the allocation but got %v\n", allocation)
+	}
+}
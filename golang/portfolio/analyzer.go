@@ -2,13 +2,19 @@ package portfolio
 
 import (
 	"fmt"
+	"log/slog"
 	"math"
 	"strings"
 	"time"
+	"tracker/logging"
 	"tracker/types"
 )
 
 func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[string]types.SymbolPrice) (types.AnalyzedPortfolio, error) {
+	return AnalyzeTransactionsWithOptions(transactions, pricesTable, DefaultAnalyzeOptions())
+}
+
+func AnalyzeTransactionsWithOptions(transactions []types.Transaction, pricesTable map[string]types.SymbolPrice, opts AnalyzeOptions) (types.AnalyzedPortfolio, error) {
 	portfolio := types.NewAnalyzedPortfolio()
 	totalTransactions := len(transactions)
 	if totalTransactions == 0 {
@@ -16,7 +22,11 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 	}
 
 	// symbolsValues := make(map[string]int64, len(pricesTable))
-	symbolsCount := make(map[string]int32, len(pricesTable))
+	symbolsCount := make(map[string]types.Quantity, len(pricesTable))
+	lots := newLotBook(opts)
+	symbolDividends := make(map[string]types.Money)
+	cashFlows := make([]CashFlow, 0, totalTransactions+1)
+	reportingTransactions := make([]types.Transaction, 0, totalTransactions)
 
 	//todo add first and last transaction to portfolio
 	firstTransaction := transactions[0]
@@ -28,29 +38,127 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 	// todo transform to days...
 	daysSinceInception := int64(today.Sub(firstTransaction.AsDate()).Hours() / 24)
 
-	var totalInvested int64
-	var totalWithdrawn int64
-	var totalDividends int64
-	var portfolioValue int64
-	var weigthedCashFlow int64 = 0
+	var totalInvested types.Money
+	var totalWithdrawn types.Money
+	var totalDividends types.Money
+	var externalDividends types.Money
+	var portfolioValue types.Money
+	var totalInterest types.Money
+	var totalFees types.Money
+	var totalTaxes types.Money
+	ledger := newCashLedger(transactions)
+	paired := pairedTransfers(transactions)
+	replaced := replacedSymbols(transactions)
+	currencies := newCurrencyBook(transactions, pricesTable, opts)
+	reportingPrices := currencies.convertPrices(pricesTable, today)
+	var weigthedCashFlow types.Money = 0
+	var acc moneyAccumulator
+
+	// weight of a cash flow in the modified dietz denominator, the share of the period it was invested for
+	weight := func(value types.Money, daysSinceTransaction int64) types.Money {
+		if daysSinceInception == 0 {
+			return 0
+		}
+		return acc.ratio(value, daysSinceInception-daysSinceTransaction, daysSinceInception)
+	}
 
-	for _, t := range transactions {
+	// every amount is converted at the rate of its day, cash is kept in its own currency and valued at today's rate
+	for _, local := range transactions {
+		t := currencies.convert(local)
+		currency := currencies.currencyOf(local)
 		symbol := strings.ToLower(t.Symbol)
+		reported := t
+		reported.Currency = currencies.reporting
+		reportingTransactions = append(reportingTransactions, reported)
+
+		if t.Type.IsCash() {
+			daysSinceTransaction := int64(today.Sub(t.AsDate()).Hours() / 24)
+			currencies.addCash(currency, cashDelta(local))
+
+			switch t.Type {
+			case types.TransactionTypeDeposit:
+				acc.add(&totalInvested, t.Pps)
+				currencies.invested(currency, local.Pps, t.Pps)
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: -float64(t.Pps)})
+				acc.add(&weigthedCashFlow, weight(t.Pps, daysSinceTransaction))
+			case types.TransactionTypeWithdrawal:
+				acc.add(&totalWithdrawn, t.Pps)
+				currencies.withdrawn(currency, local.Pps, t.Pps)
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: float64(t.Pps)})
+				acc.add(&weigthedCashFlow, -weight(t.Pps, daysSinceTransaction))
+			case types.TransactionTypeInterest:
+				acc.add(&totalInterest, t.Pps)
+			case types.TransactionTypeFee:
+				acc.add(&totalFees, t.Pps)
+			case types.TransactionTypeTax:
+				acc.add(&totalTaxes, t.Pps)
+			}
+
+			acc.keep(currencies.acc.err)
+			if acc.err != nil {
+				return portfolio, fmt.Errorf("analyzing transaction %s: %w", t.Id, acc.err)
+			}
+			continue
+		}
+
+		if t.Type == types.TransactionTypeCorporateAction {
+			a := *t.Action
+			newSymbol := strings.ToLower(a.NewSymbol)
+			count := symbolsCount[symbol]
+			ledgerShares := ledger.corporateAction(a)
+			reinvested := acc.value(ledgerShares, a.CashPerShare)
+			currencies.addCash(currency, acc.value(ledgerShares, local.Action.CashPerShare))
+			symbolsCount[newSymbol] += a.NewShares(count)
+			if a.Replaces() && newSymbol != symbol {
+				delete(symbolsCount, symbol)
+			}
+			lots.apply(t, false)
+
+			// merger cash lands in the cash of ledger accounts and is paid out of the rest
+			paidOut := acc.value(count, a.CashPerShare) - reinvested
+			if paidOut != 0 {
+				daysSinceTransaction := int64(today.Sub(t.AsDate()).Hours() / 24)
+				acc.add(&totalWithdrawn, paidOut)
+				currencies.withdrawn(currency, acc.value(count-ledgerShares, local.Action.CashPerShare), paidOut)
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: float64(paidOut)})
+				acc.add(&weigthedCashFlow, -weight(paidOut, daysSinceTransaction))
+			}
 
+			acc.keep(lots.err)
+			acc.keep(currencies.acc.err)
+			if acc.err != nil {
+				return portfolio, fmt.Errorf("analyzing transaction %s: %w", t.Id, acc.err)
+			}
+			continue
+		}
+
+		// a renamed or merged symbol has no price of its own anymore, its trades still count
 		_, ok := pricesTable[symbol]
-		if !ok {
-			fmt.Printf("[AnalyzeTransactions] for %s missing price in table\n", t.Symbol)
+		if !ok && !replaced[symbol] {
+			logging.Get().Warn("missing price in table", slog.String("symbol", t.Symbol))
 			continue
 		}
 
-		trValue := int64(t.Quantity * t.Pps)
+		trValue := acc.value(t.Quantity, t.Pps)
+		localValue := acc.value(local.Quantity, local.Pps)
+		if t.Type == types.TransactionTypeBuy || t.Type == types.TransactionTypeSell {
+			// buys cost their fee on top and sells receive their proceeds after it
+			trValue = acc.cost(t)
+			localValue = acc.cost(local)
+			acc.add(&totalFees, t.Fee)
+		}
 		daysSinceTransaction := int64(today.Sub(t.AsDate()).Hours() / 24)
 
 		switch t.Type {
 		case types.TransactionTypeBuy:
-			totalInvested += trValue
-			trCashFlow := trValue * (daysSinceInception - daysSinceTransaction) / daysSinceInception
-			weigthedCashFlow += trCashFlow
+			if ledger.tracks(t) {
+				currencies.addCash(currency, -localValue)
+			} else {
+				acc.add(&totalInvested, trValue)
+				currencies.invested(currency, localValue, trValue)
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: -float64(trValue)})
+				acc.add(&weigthedCashFlow, weight(trValue, daysSinceTransaction))
+			}
 
 			count, ok := symbolsCount[symbol]
 			if !ok {
@@ -58,11 +166,17 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 			}
 			count += t.Quantity
 			symbolsCount[symbol] = count
+			lots.apply(t, false)
 
 		case types.TransactionTypeSell:
-			totalWithdrawn += trValue
-			trCashFlow := trValue * (daysSinceInception - daysSinceTransaction) / daysSinceInception
-			weigthedCashFlow -= trCashFlow
+			if ledger.tracks(t) {
+				currencies.addCash(currency, localValue)
+			} else {
+				acc.add(&totalWithdrawn, trValue)
+				currencies.withdrawn(currency, localValue, trValue)
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: float64(trValue)})
+				acc.add(&weigthedCashFlow, -weight(trValue, daysSinceTransaction))
+			}
 
 			count, ok := symbolsCount[symbol]
 			if !ok {
@@ -70,16 +184,52 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 			}
 			count -= t.Quantity
 			symbolsCount[symbol] = count
+			lots.apply(t, false)
+
+		case types.TransactionTypeTransfer:
+			symbolsCount[symbol] += t.Quantity
+			ledger.tracks(t)
+			lots.apply(t, paired[t.TransferId])
+
+			// a transfer between analyzed accounts is internal, one side alone moves shares in or out at cost
+			if !paired[t.TransferId] {
+				// incoming shares are worth a positive value and outgoing ones a negative value
+				if trValue > 0 {
+					acc.add(&totalInvested, trValue)
+					currencies.invested(currency, localValue, trValue)
+				} else {
+					acc.add(&totalWithdrawn, -trValue)
+					currencies.withdrawn(currency, -localValue, -trValue)
+				}
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: -float64(trValue)})
+				acc.add(&weigthedCashFlow, weight(trValue, daysSinceTransaction))
+			}
 
 		case types.TransactionTypeDividend:
 			count, ok := symbolsCount[symbol]
 			if !ok {
 				count = 0
 			}
-			trValue := t.Pps * count
-			totalDividends += int64(trValue)
-			dividendCashFlow := int64(trValue) * (daysSinceInception - daysSinceTransaction) / daysSinceInception
-			weigthedCashFlow += dividendCashFlow
+			trValue := acc.value(count, t.Pps)
+			acc.add(&totalDividends, trValue)
+			dividends := symbolDividends[t.Symbol]
+			acc.add(&dividends, trValue)
+			symbolDividends[t.Symbol] = dividends
+			if trValue != 0 {
+				portfolio.DividendFlows = append(portfolio.DividendFlows, types.DividendFlow{Date: t.AsDate(), Symbol: t.Symbol, Amount: trValue})
+			}
+
+			// dividends on shares of ledger accounts stay in the portfolio as cash, the rest are paid out
+			reinvested := acc.value(ledger.shares[symbol], t.Pps)
+			localReinvested := acc.value(ledger.shares[symbol], local.Pps)
+			currencies.addCash(currency, localReinvested)
+			paidOut := trValue - reinvested
+			acc.add(&externalDividends, paidOut)
+			currencies.dividends(currency, acc.value(count, local.Pps)-localReinvested, paidOut)
+			if paidOut != 0 {
+				cashFlows = append(cashFlows, CashFlow{Date: t.AsDate(), Amount: float64(paidOut)})
+				acc.add(&weigthedCashFlow, weight(paidOut, daysSinceTransaction))
+			}
 
 		case types.TransactionTypeSplit:
 			count, ok := symbolsCount[symbol]
@@ -87,24 +237,66 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 				count = 0
 			}
 
-			pps := float32(t.Pps) / 100
-			count = int32(float32(count) * pps)
+			count = count.MulRatio(int64(t.Pps), 100)
 			symbolsCount[symbol] = count
+			ledger.split(symbol, t.Pps)
+			lots.apply(t, false)
+		}
+
+		acc.keep(lots.err)
+		acc.keep(currencies.acc.err)
+		if acc.err != nil {
+			return portfolio, fmt.Errorf("analyzing transaction %s: %w", t.Id, acc.err)
 		}
 	}
 
 	for s, c := range symbolsCount {
 		sp := pricesTable[s]
-		portfolioValue += int64(sp.AdjPrice * c)
+		acc.add(&portfolioValue, currencies.toReporting(acc.value(c, sp.AdjPrice), currencies.symbolCurrency(s), today))
 	}
+	cash := currencies.cashValue(today)
+	acc.add(&portfolioValue, cash)
+	acc.keep(currencies.acc.err)
 
 	portfolio.Value = portfolioValue
 	portfolio.TotalInvested = totalInvested
 	portfolio.TotalWithdrawn = totalWithdrawn
 	portfolio.TotalDividends = totalDividends
+	portfolio.CashBalance = cash
+	portfolio.TotalInterest = totalInterest
+	portfolio.TotalFees = totalFees
+	portfolio.TotalTaxes = totalTaxes
+
+	// dividends paid into cash are already part of the value
+	var portfolioGainValue types.Money
+	acc.add(&portfolioGainValue, portfolioValue)
+	acc.add(&portfolioGainValue, externalDividends)
+	acc.add(&portfolioGainValue, totalWithdrawn)
+	acc.add(&portfolioGainValue, -totalInvested)
+	if acc.err != nil {
+		return portfolio, fmt.Errorf("analyzing portfolio value: %w", acc.err)
+	}
 
-	portfolioGainValue := (portfolioValue + totalDividends + totalWithdrawn) - totalInvested
 	portfolio.GainValue = portfolioGainValue
+	portfolio.ReportingCurrency = currencies.reporting
+	portfolio.Prices = reportingPrices
+	currencyReturns, err := currencies.currencyReturns(symbolsCount, pricesTable, today)
+	if err != nil {
+		return portfolio, fmt.Errorf("analyzing currency returns: %w", err)
+	}
+	portfolio.Currencies = currencyReturns
+	var localInvested types.Money
+	for currency, r := range portfolio.Currencies {
+		acc.add(&portfolio.FxGainValue, r.FxGain)
+		acc.add(&localInvested, currencies.toReporting(r.Invested, currency, today))
+	}
+	acc.keep(currencies.acc.err)
+	acc.add(&portfolio.LocalGainValue, portfolioGainValue)
+	acc.add(&portfolio.LocalGainValue, -portfolio.FxGainValue)
+	if localInvested != 0 {
+		portfolio.LocalGain = float32(portfolio.LocalGainValue) / float32(localInvested)
+	}
+
 	if totalInvested == 0 {
 		portfolio.Gain = 0
 	} else {
@@ -114,7 +306,13 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 	if weigthedCashFlow == 0 {
 		portfolio.ModifiedDietzYield = 0
 	} else {
-		portfolio.ModifiedDietzYield = float32(float64(portfolioGainValue) / float64(totalInvested+weigthedCashFlow))
+		portfolio.ModifiedDietzYield = float32(float64(portfolioGainValue) / (float64(totalInvested) + float64(weigthedCashFlow)))
+	}
+
+	// the portfolio is treated as sold at its current value today, an error leaves XIRR at zero
+	cashFlows = append(cashFlows, CashFlow{Date: today, Amount: float64(portfolioValue)})
+	if xirr, err := XIRR(cashFlows); err == nil {
+		portfolio.XIRR = float32(xirr)
 	}
 
 	yearsSinceInception := float64(daysSinceInception) / 365
@@ -122,6 +320,76 @@ func AnalyzeTransactions(transactions []types.Transaction, pricesTable map[strin
 
 	portfolio.SymbolsCount = symbolsCount
 	portfolio.Transactions = transactions
+	portfolio.ReportingTransactions = reportingTransactions
+	portfolio.OpenLots = lots.openLots()
+	portfolio.ClosedLots = lots.closed
+	gains, err := buildSymbolGains(portfolio.OpenLots, portfolio.ClosedLots, symbolDividends, reportingPrices)
+	if err != nil {
+		return portfolio, fmt.Errorf("analyzing gains: %w", err)
+	}
+	portfolio.SymbolGains = gains
+	for _, g := range portfolio.SymbolGains {
+		acc.add(&portfolio.RealizedGain, g.Realized)
+		acc.add(&portfolio.UnrealizedGain, g.Unrealized)
+	}
+	if acc.err != nil {
+		return portfolio, fmt.Errorf("analyzing gains: %w", acc.err)
+	}
 
 	return portfolio, nil
 }
+
+// moneyAccumulator runs checked money arithmetic and keeps the first overflow, so a long calculation can check
+// for an error once instead of after every operation
+type moneyAccumulator struct {
+	err error
+}
+
+func (a *moneyAccumulator) add(total *types.Money, v types.Money) {
+	if a.err != nil {
+		return
+	}
+	sum, err := total.Add(v)
+	if err != nil {
+		a.err = err
+		return
+	}
+	*total = sum
+}
+
+// money returns v and keeps err, for a checked result computed elsewhere
+func (a *moneyAccumulator) money(v types.Money, err error) types.Money {
+	a.keep(err)
+	return v
+}
+
+// keep records err when it is the first, for checked arithmetic done elsewhere
+func (a *moneyAccumulator) keep(err error) {
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+func (a *moneyAccumulator) value(q types.Quantity, price types.Money) types.Money {
+	v, err := price.MulQuantity(q)
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	return v
+}
+
+func (a *moneyAccumulator) cost(t types.Transaction) types.Money {
+	v, err := t.Cost()
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	return v
+}
+
+func (a *moneyAccumulator) ratio(v types.Money, num, den int64) types.Money {
+	r, err := v.MulRatio(num, den)
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	return r
+}
@@ -1,8 +1,10 @@
 package portfolio
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"strings"
 	"testing"
@@ -13,8 +15,8 @@ import (
 
 func TestFirstLastTransaction(t *testing.T) {
 	transactions := []types.Transaction{
-		{Id: "id1", Date: utils.StringToDate("2025-01-01"), Type: types.TransactionTypeBuy, Symbol: "AAPL", Pps: 1, Quantity: 1},
-		{Id: "id2", Date: utils.StringToDate("2025-02-01"), Type: types.TransactionTypeBuy, Symbol: "AAPL", Pps: 1, Quantity: 1},
+		{Id: "id1", Date: utils.StringToDate("2025-01-01"), Type: types.TransactionTypeBuy, Symbol: "AAPL", Pps: 1, Quantity: types.NewQuantity(1)},
+		{Id: "id2", Date: utils.StringToDate("2025-02-01"), Type: types.TransactionTypeBuy, Symbol: "AAPL", Pps: 1, Quantity: types.NewQuantity(1)},
 	}
 	portfolio, err := AnalyzeTransactions(transactions, map[string]types.SymbolPrice{
 		"aapl": {AdjPrice: 12},
@@ -63,7 +65,7 @@ func TestTotals(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeBuy,
-			Quantity:  2,
+			Quantity:  types.NewQuantity(2),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-01-01"),
 		},
@@ -71,7 +73,7 @@ func TestTotals(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeBuy,
-			Quantity:  3,
+			Quantity:  types.NewQuantity(3),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-02-01"),
 		},
@@ -79,7 +81,7 @@ func TestTotals(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeBuy,
-			Quantity:  1,
+			Quantity:  types.NewQuantity(1),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-03-01"),
 		},
@@ -87,7 +89,7 @@ func TestTotals(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeSell,
-			Quantity:  4,
+			Quantity:  types.NewQuantity(4),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-04-01"),
 		},
@@ -117,7 +119,7 @@ func TestDividends(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeBuy,
-			Quantity:  2,
+			Quantity:  types.NewQuantity(2),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-01-01"),
 		},
@@ -125,7 +127,7 @@ func TestDividends(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeBuy,
-			Quantity:  3,
+			Quantity:  types.NewQuantity(3),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-02-01"),
 		},
@@ -133,7 +135,7 @@ func TestDividends(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeDividend,
-			Quantity:  0,
+			Quantity:  types.NewQuantity(0),
 			Pps:       10,
 			Date:      utils.StringToDate("2024-02-15"),
 		},
@@ -141,7 +143,7 @@ func TestDividends(t *testing.T) {
 			AccountId: "1",
 			Symbol:    "AAPL",
 			Type:      types.TransactionTypeSell,
-			Quantity:  4,
+			Quantity:  types.NewQuantity(4),
 			Pps:       100,
 			Date:      utils.StringToDate("2024-05-01"),
 		},
@@ -174,40 +176,40 @@ func TestSymbolsValue(t *testing.T) {
 		{
 			Symbol:   "AAPL",
 			Type:     types.TransactionTypeBuy,
-			Quantity: int32(rand.Intn(95) + 5), // 5-100
+			Quantity: types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
 			Pps:      1,
 			Date:     utils.StringToDate("2023-01-01"),
 		},
 		{
 			Symbol:   "AAPL",
 			Type:     types.TransactionTypeBuy,
-			Quantity: int32(rand.Intn(95) + 5), // 5-100
+			Quantity: types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
 			Pps:      1,
 			Date:     utils.StringToDate("2023-02-01"),
 		},
 		{
 			Symbol:   "AAPL",
 			Type:     types.TransactionTypeBuy,
-			Quantity: int32(rand.Intn(95) + 5), // 5-100
+			Quantity: types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
 			Pps:      1,
 			Date:     utils.StringToDate("2023-03-01"),
 		},
 		{
 			Symbol:   "AAPL",
 			Type:     types.TransactionTypeSell,
-			Quantity: int32(rand.Intn(5) + 5), // 5-10
+			Quantity: types.NewQuantity(int64(rand.Intn(5) + 5)), // 5-10
 			Pps:      1,
 			Date:     utils.StringToDate("2023-04-01"),
 		},
 	}
 
-	price := int32(rand.Intn(100) + 100) // 100-200
+	price := types.Money(rand.Intn(100) + 100) // 100-200
 	var expectedValue int64 = 0
 	for _, t := range transactions {
 		if t.Type == types.TransactionTypeSell {
-			expectedValue -= int64(t.Quantity * price)
+			expectedValue -= t.Quantity.MulPrice(int64(price))
 		} else {
-			expectedValue += int64(t.Quantity * price)
+			expectedValue += t.Quantity.MulPrice(int64(price))
 		}
 	}
 
@@ -228,14 +230,14 @@ func TestSymbolsValue(t *testing.T) {
 func TestYields(t *testing.T) {
 	t.Skip("Skipping TestYields due to precision calculation differences between test and analyzer implementation")
 	today := time.Now()
-	price := int32(rand.Intn(100) + 100) // 100-200
+	price := types.Money(rand.Intn(100) + 100) // 100-200
 
 	numTransactions := 4
-	quantities := []int32{
-		int32(rand.Intn(95) + 5), // 5-100
-		int32(rand.Intn(95) + 5), // 5-100
-		int32(rand.Intn(95) + 5), // 5-100
-		int32(rand.Intn(5) + 5),  // 5-10
+	quantities := []types.Quantity{
+		types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
+		types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
+		types.NewQuantity(int64(rand.Intn(95) + 5)), // 5-100
+		types.NewQuantity(int64(rand.Intn(5) + 5)),  // 5-10
 	}
 	transactionTypes := []types.TransactionType{
 		types.TransactionTypeBuy,
@@ -276,14 +278,14 @@ func TestYields(t *testing.T) {
 
 		switch t.Type {
 		case types.TransactionTypeBuy:
-			currentPortfolioValue += int64(t.Quantity * price)
-			totalInvested += int64(t.Quantity * t.Pps)
-			trCashFlow := int64(t.Quantity*t.Pps) * daysSinceTransaction / daysSinceInception
+			currentPortfolioValue += t.Quantity.MulPrice(int64(price))
+			totalInvested += t.Quantity.MulPrice(int64(t.Pps))
+			trCashFlow := t.Quantity.MulPrice(int64(t.Pps)) * daysSinceTransaction / daysSinceInception
 			weightedCashFlows += trCashFlow
 		case types.TransactionTypeSell:
-			currentPortfolioValue -= int64(t.Quantity * price)
-			totalWithdrawn += int64(t.Quantity * t.Pps)
-			trCashFlow := int64(t.Quantity*t.Pps) * daysSinceTransaction / daysSinceInception
+			currentPortfolioValue -= t.Quantity.MulPrice(int64(price))
+			totalWithdrawn += t.Quantity.MulPrice(int64(t.Pps))
+			trCashFlow := t.Quantity.MulPrice(int64(t.Pps)) * daysSinceTransaction / daysSinceInception
 			weightedCashFlows -= trCashFlow
 		}
 	}
@@ -348,8 +350,8 @@ func generateRandomTransactions(seed int64, count int) []types.Transaction {
 			Symbol:    symbols[r.Intn(len(symbols))],
 			Date:      date,
 			Type:      transactionTypes[r.Intn(len(transactionTypes))],
-			Quantity:  int32(r.Intn(1000) + 1), // 1-1000
-			Pps:       int32(r.Intn(500) + 1),  // 1-500
+			Quantity:  types.NewQuantity(int64(r.Intn(1000) + 1)), // 1-1000
+			Pps:       types.Money(r.Intn(500) + 1), // 1-500
 		}
 	}
 
@@ -360,29 +362,29 @@ func generateRandomTransactions(seed int64, count int) []types.Transaction {
 func generateEdgeCaseTransactions() []types.Transaction {
 	return []types.Transaction{
 		// Zero values
-		{Id: "zero_qty", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 0, Pps: 100},
-		{Id: "zero_pps", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 0},
+		{Id: "zero_qty", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(0), Pps: 100},
+		{Id: "zero_pps", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 0},
 
 		// Maximum values
-		{Id: "max_qty", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: math.MaxInt32, Pps: 1},
-		{Id: "max_pps", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 1, Pps: math.MaxInt32},
+		{Id: "max_qty", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(math.MaxInt32), Pps: 1},
+		{Id: "max_pps", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: math.MaxInt32},
 
 		// Edge dates
-		{Id: "old_date", Symbol: "AAPL", Date: utils.StringToDate("1900-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "future_date", Symbol: "AAPL", Date: utils.StringToDate("2100-12-31"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "same_date_1", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "same_date_2", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSell, Quantity: 50, Pps: 100},
+		{Id: "old_date", Symbol: "AAPL", Date: utils.StringToDate("1900-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "future_date", Symbol: "AAPL", Date: utils.StringToDate("2100-12-31"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "same_date_1", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "same_date_2", Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSell, Quantity: types.NewQuantity(50), Pps: 100},
 
 		// Edge symbols
-		{Id: "empty_symbol", Symbol: "", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "long_symbol", Symbol: strings.Repeat("A", 100), Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "special_chars", Symbol: "AAPL!@#$%", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
-		{Id: "unicode_symbol", Symbol: "AAPL🚀", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 100},
+		{Id: "empty_symbol", Symbol: "", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "long_symbol", Symbol: strings.Repeat("A", 100), Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "special_chars", Symbol: "AAPL!@#$%", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "unicode_symbol", Symbol: "AAPL🚀", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 100},
 
 		// Business logic edge cases
-		{Id: "sell_before_buy", Symbol: "SELL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSell, Quantity: 100, Pps: 100},
-		{Id: "dividend_no_shares", Symbol: "DIV", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeDividend, Quantity: 0, Pps: 10},
-		{Id: "split_no_shares", Symbol: "SPLIT", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSplit, Quantity: 0, Pps: 2},
+		{Id: "sell_before_buy", Symbol: "SELL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSell, Quantity: types.NewQuantity(100), Pps: 100},
+		{Id: "dividend_no_shares", Symbol: "DIV", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(0), Pps: 10},
+		{Id: "split_no_shares", Symbol: "SPLIT", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeSplit, Quantity: types.NewQuantity(0), Pps: 2},
 	}
 }
 
@@ -393,7 +395,7 @@ func generateRandomPriceTable(seed int64, symbols []string) map[string]types.Sym
 
 	for _, symbol := range symbols {
 		if r.Float32() < 0.9 { // 90% chance to include symbol
-			price := int32(r.Intn(1000) + 1) // 1-1000
+			price := types.Money(r.Intn(1000) + 1) // 1-1000
 			priceTable[symbol] = types.SymbolPrice{
 				Symbol:   symbol,
 				AdjPrice: price,
@@ -433,22 +435,22 @@ func validateNoPanic(t *testing.T, transactions []types.Transaction, priceTable
 
 // validateInvariants checks mathematical invariants
 func validateInvariants(t *testing.T, transactions []types.Transaction, priceTable map[string]types.SymbolPrice, portfolio types.AnalyzedPortfolio, testName string) {
-	var expectedInvested, expectedWithdrawn, expectedDividends int64
-	symbolCounts := make(map[string]int32)
+	var expectedInvested, expectedWithdrawn, expectedDividends types.Money
+	symbolCounts := make(map[string]types.Quantity)
 
 	for _, tx := range transactions {
 		switch tx.Type {
 		case types.TransactionTypeBuy:
-			expectedInvested += int64(tx.Quantity * tx.Pps)
+			expectedInvested += types.Money(tx.Quantity.MulPrice(int64(tx.Pps)))
 			symbolCounts[tx.Symbol] += tx.Quantity
 		case types.TransactionTypeSell:
-			expectedWithdrawn += int64(tx.Quantity * tx.Pps)
+			expectedWithdrawn += types.Money(tx.Quantity.MulPrice(int64(tx.Pps)))
 			symbolCounts[tx.Symbol] -= tx.Quantity
 		case types.TransactionTypeDividend:
 			count := symbolCounts[tx.Symbol]
-			expectedDividends += int64(tx.Pps * count)
+			expectedDividends += types.Money(count.MulPrice(int64(tx.Pps)))
 		case types.TransactionTypeSplit:
-			symbolCounts[tx.Symbol] *= tx.Pps
+			symbolCounts[tx.Symbol] = symbolCounts[tx.Symbol].MulRatio(int64(tx.Pps), 100)
 		}
 	}
 
@@ -572,8 +574,8 @@ func TestFuzzInvariants(t *testing.T) {
 			name: "simple_buy_sell",
 			setup: func() ([]types.Transaction, map[string]types.SymbolPrice) {
 				transactions := []types.Transaction{
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 150},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeSell, Quantity: 50, Pps: 160},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 150},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeSell, Quantity: types.NewQuantity(50), Pps: 160},
 				}
 				priceTable := map[string]types.SymbolPrice{
 					"aapl": {Symbol: "AAPL", AdjPrice: 170},
@@ -594,10 +596,10 @@ func TestFuzzInvariants(t *testing.T) {
 			name: "dividend_scenario",
 			setup: func() ([]types.Transaction, map[string]types.SymbolPrice) {
 				transactions := []types.Transaction{
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 150},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeDividend, Quantity: 0, Pps: 5},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-03-01"), Type: types.TransactionTypeBuy, Quantity: 50, Pps: 160},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-04-01"), Type: types.TransactionTypeDividend, Quantity: 0, Pps: 6},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 150},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(0), Pps: 5},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-03-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(50), Pps: 160},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-04-01"), Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(0), Pps: 6},
 				}
 				priceTable := map[string]types.SymbolPrice{
 					"aapl": {Symbol: "AAPL", AdjPrice: 170},
@@ -609,9 +611,9 @@ func TestFuzzInvariants(t *testing.T) {
 			name: "split_scenario",
 			setup: func() ([]types.Transaction, map[string]types.SymbolPrice) {
 				transactions := []types.Transaction{
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: 100, Pps: 200},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeSplit, Quantity: 0, Pps: 2},
-					{Symbol: "AAPL", Date: utils.StringToDate("2024-03-01"), Type: types.TransactionTypeSell, Quantity: 50, Pps: 110},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-01-01"), Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 200},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-02-01"), Type: types.TransactionTypeSplit, Quantity: types.NewQuantity(0), Pps: 2},
+					{Symbol: "AAPL", Date: utils.StringToDate("2024-03-01"), Type: types.TransactionTypeSell, Quantity: types.NewQuantity(50), Pps: 110},
 				}
 				priceTable := map[string]types.SymbolPrice{
 					"aapl": {Symbol: "AAPL", AdjPrice: 105},
@@ -696,7 +698,7 @@ func TestFuzzMalformedDates(t *testing.T) {
 					Symbol:   "AAPL",
 					Date:     utils.StringToDate(date),
 					Type:     types.TransactionTypeBuy,
-					Quantity: 100,
+					Quantity: types.NewQuantity(100),
 					Pps:      100,
 				},
 			}
@@ -748,3 +750,299 @@ func TestFuzzPropertyBased(t *testing.T) {
 		validateDeterminism(t, transactions, priceTable, testName)
 	}
 }
+
+// generateLargeValueTransactions creates buys and sells of whole shares with quantities and prices large enough
+// that the products overflow int32, and with maxShares large enough, int64
+func generateLargeValueTransactions(seed int64, count int, maxShares int64) []types.Transaction {
+	r := rand.New(rand.NewSource(seed))
+	symbols := []string{"AAPL", "BRK.A", "NVR"}
+	baseDate := utils.StringToDate("2020-01-01")
+
+	transactions := make([]types.Transaction, count)
+	for i := 0; i < count; i++ {
+		txType := types.TransactionTypeBuy
+		if i%4 == 3 {
+			txType = types.TransactionTypeSell
+		}
+
+		transactions[i] = types.Transaction{
+			Id:       fmt.Sprintf("large_%d", i),
+			Symbol:   symbols[r.Intn(len(symbols))],
+			Date:     baseDate.AddDate(0, 0, i),
+			Type:     txType,
+			Quantity: types.NewQuantity(r.Int63n(maxShares) + 1),
+			Pps:      types.Money(r.Int63n(math.MaxInt32) + 1),
+		}
+	}
+
+	return transactions
+}
+
+// bigTotals computes the invested, withdrawn and current value of whole share transactions without any overflow
+func bigTotals(transactions []types.Transaction, priceTable map[string]types.SymbolPrice) (invested, withdrawn, value *big.Int) {
+	invested, withdrawn, value = new(big.Int), new(big.Int), new(big.Int)
+	shares := make(map[string]*big.Int)
+
+	for _, tx := range transactions {
+		symbol := strings.ToLower(tx.Symbol)
+		if shares[symbol] == nil {
+			shares[symbol] = new(big.Int)
+		}
+
+		quantity := big.NewInt(int64(tx.Quantity / types.QuantityScale))
+		total := new(big.Int).Mul(quantity, big.NewInt(int64(tx.Pps)))
+		switch tx.Type {
+		case types.TransactionTypeBuy:
+			invested.Add(invested, total)
+			shares[symbol].Add(shares[symbol], quantity)
+		case types.TransactionTypeSell:
+			withdrawn.Add(withdrawn, total)
+			shares[symbol].Sub(shares[symbol], quantity)
+		}
+	}
+
+	for symbol, count := range shares {
+		value.Add(value, new(big.Int).Mul(count, big.NewInt(int64(priceTable[symbol].AdjPrice))))
+	}
+
+	return invested, withdrawn, value
+}
+
+// bigGains computes the FIFO realized and unrealized gains of whole share transactions without any overflow, fits
+// is false when the basis, value or gains of a symbol don't fit in an int64 on the way
+func bigGains(transactions []types.Transaction, priceTable map[string]types.SymbolPrice) (realized, unrealized *big.Int, fits bool) {
+	type lot struct {
+		shares int64
+		pps    int64
+	}
+	realized, unrealized = new(big.Int), new(big.Int)
+	lots := make(map[string][]lot)
+	symbolRealized := make(map[string]*big.Int)
+	maxInt64 := big.NewInt(math.MaxInt64)
+	fits = true
+
+	for _, tx := range transactions {
+		symbol := strings.ToLower(tx.Symbol)
+		shares := int64(tx.Quantity / types.QuantityScale)
+		if symbolRealized[symbol] == nil {
+			symbolRealized[symbol] = new(big.Int)
+		}
+
+		switch tx.Type {
+		case types.TransactionTypeBuy:
+			lots[symbol] = append(lots[symbol], lot{shares: shares, pps: int64(tx.Pps)})
+		case types.TransactionTypeSell:
+			for shares > 0 && len(lots[symbol]) > 0 {
+				l := &lots[symbol][0]
+				take := min(shares, l.shares)
+				gain := new(big.Int).Mul(big.NewInt(take), big.NewInt(int64(tx.Pps)-l.pps))
+				symbolRealized[symbol].Add(symbolRealized[symbol], gain)
+				realized.Add(realized, gain)
+				if new(big.Int).Mul(big.NewInt(take), big.NewInt(int64(tx.Pps))).Cmp(maxInt64) > 0 {
+					fits = false
+				}
+
+				shares -= take
+				l.shares -= take
+				if l.shares == 0 {
+					lots[symbol] = lots[symbol][1:]
+				}
+			}
+		}
+	}
+
+	for symbol, open := range lots {
+		basis, value := new(big.Int), new(big.Int)
+		for _, l := range open {
+			basis.Add(basis, new(big.Int).Mul(big.NewInt(l.shares), big.NewInt(l.pps)))
+			value.Add(value, new(big.Int).Mul(big.NewInt(l.shares), big.NewInt(int64(priceTable[symbol].AdjPrice))))
+		}
+		gain := new(big.Int).Sub(value, basis)
+		unrealized.Add(unrealized, gain)
+
+		total := new(big.Int).Add(gain, symbolRealized[symbol])
+		for _, v := range []*big.Int{basis, value, gain, total} {
+			if new(big.Int).Abs(v).Cmp(maxInt64) > 0 {
+				fits = false
+			}
+		}
+	}
+	for _, v := range symbolRealized {
+		if new(big.Int).Abs(v).Cmp(maxInt64) > 0 {
+			fits = false
+		}
+	}
+
+	return realized, unrealized, fits
+}
+
+func TestFuzzLargeValues(t *testing.T) {
+	// 50,000 shares at $500 is 2.5 billion cents, more than an int32 holds
+	transactions := []types.Transaction{
+		{Id: "b1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(50000), Pps: 50000, Date: utils.StringToDate("2024-01-01")},
+		{Id: "d1", Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 50000, Date: utils.StringToDate("2024-02-01")},
+	}
+	portfolio, err := AnalyzeTransactions(transactions, map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 50000},
+	})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if portfolio.TotalInvested != 2_500_000_000 || portfolio.Value != 2_500_000_000 || portfolio.TotalDividends != 2_500_000_000 {
+		t.Fatalf("Expected invested/value/dividends of 2500000000 but got %d/%d/%d\n",
+			portfolio.TotalInvested, portfolio.Value, portfolio.TotalDividends)
+	}
+
+	maxInt64 := big.NewInt(math.MaxInt64)
+	for i := 0; i < 50; i++ {
+		seed := int64(i)
+		testName := fmt.Sprintf("large_values_%d", i)
+
+		// every other seed uses positions big enough to overflow int64
+		maxShares := int64(1_000_000)
+		if i%2 == 1 {
+			maxShares = 10_000_000_000
+		}
+
+		transactions := generateLargeValueTransactions(seed, 20, maxShares)
+		priceTable := map[string]types.SymbolPrice{
+			"aapl":  {Symbol: "AAPL", AdjPrice: math.MaxInt32},
+			"brk.a": {Symbol: "BRK.A", AdjPrice: 75_000_000},
+			"nvr":   {Symbol: "NVR", AdjPrice: 800_000},
+		}
+
+		portfolio, err := validateNoPanic(t, transactions, priceTable, testName)
+		invested, withdrawn, value := bigTotals(transactions, priceTable)
+		realized, unrealized, gainsFit := bigGains(transactions, priceTable)
+
+		// the gain adds the value and withdrawals, they have to fit as well as the gains of the lots
+		gain := new(big.Int).Add(value, withdrawn)
+		fits := invested.Cmp(maxInt64) <= 0 && withdrawn.Cmp(maxInt64) <= 0 && gain.Cmp(maxInt64) <= 0 &&
+			new(big.Int).Abs(value).Cmp(maxInt64) <= 0 && gainsFit &&
+			new(big.Int).Abs(realized).Cmp(maxInt64) <= 0 && new(big.Int).Abs(unrealized).Cmp(maxInt64) <= 0
+		if !fits {
+			if !errors.Is(err, types.ErrMoneyOverflow) {
+				t.Errorf("%s: Expected an overflow error but got %v (invested %d)", testName, err, portfolio.TotalInvested)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Error wasn't nil: %v", testName, err)
+			continue
+		}
+
+		if big.NewInt(int64(portfolio.TotalInvested)).Cmp(invested) != 0 ||
+			big.NewInt(int64(portfolio.TotalWithdrawn)).Cmp(withdrawn) != 0 ||
+			big.NewInt(int64(portfolio.Value)).Cmp(value) != 0 {
+			t.Errorf("%s: Expected invested/withdrawn/value %s/%s/%s but got %d/%d/%d", testName,
+				invested, withdrawn, value, portfolio.TotalInvested, portfolio.TotalWithdrawn, portfolio.Value)
+		}
+
+		if big.NewInt(int64(portfolio.RealizedGain)).Cmp(realized) != 0 ||
+			big.NewInt(int64(portfolio.UnrealizedGain)).Cmp(unrealized) != 0 {
+			t.Errorf("%s: Expected realized/unrealized gain %s/%s but got %d/%d", testName,
+				realized, unrealized, portfolio.RealizedGain, portfolio.UnrealizedGain)
+		}
+	}
+}
+
+func TestLargeValuesInReportingCurrency(t *testing.T) {
+	opts := DefaultAnalyzeOptions()
+	opts.ReportingCurrency = "ILS"
+	opts.Rates = types.FxRates{"ILS": {{Currency: "ILS", Date: utils.StringToDate("2024-01-01"), Value: 4}}}
+	history := func(price types.Money) map[string][]types.SymbolPrice {
+		return map[string][]types.SymbolPrice{"aapl": {{Symbol: "AAPL", AdjPrice: price, Date: utils.StringToDate("2024-01-01")}}}
+	}
+
+	// 2^60 cents fit in dollars and at 4 shekels a dollar
+	transactions := []types.Transaction{
+		{Id: "b1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: 1 << 60, Date: utils.StringToDate("2024-01-01")},
+	}
+	portfolio, err := AnalyzeTransactionsWithOptions(transactions, map[string]types.SymbolPrice{"aapl": {Symbol: "AAPL", AdjPrice: 1 << 60}}, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if portfolio.TotalInvested != 1<<62 || portfolio.Value != 1<<62 {
+		t.Fatalf("Expected invested/value of %d but got %d/%d\n", int64(1<<62), portfolio.TotalInvested, portfolio.Value)
+	}
+
+	// 2^62 cents fit in dollars but not in shekels
+	transactions[0].Pps = 1 << 62
+	if _, err := AnalyzeTransactionsWithOptions(transactions, map[string]types.SymbolPrice{"aapl": {Symbol: "AAPL", AdjPrice: 1 << 62}}, opts); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected an overflow error but got %v\n", err)
+	}
+	if _, err := BuildTimeSeriesWithOptions(transactions, history(1<<62), utils.StringToDate("2024-01-02"), opts); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected an overflow error in the time series but got %v\n", err)
+	}
+}
+
+func TestFractionalQuantities(t *testing.T) {
+	half, _ := types.ParseQuantity("0.5")
+	quarter, _ := types.ParseQuantity("0.25")
+	transactions := []types.Transaction{
+		{Id: "b1", Symbol: "BTC", Type: types.TransactionTypeBuy, Quantity: half, Pps: 2000000, Date: utils.StringToDate("2023-01-01")},
+		{Id: "b2", Symbol: "BTC", Type: types.TransactionTypeBuy, Quantity: half, Pps: 3000000, Date: utils.StringToDate("2023-06-01")},
+		{Id: "s1", Symbol: "BTC", Type: types.TransactionTypeSell, Quantity: quarter, Pps: 4000000, Date: utils.StringToDate("2024-01-01")},
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, map[string]types.SymbolPrice{
+		"btc": {Symbol: "BTC", AdjPrice: 5000000},
+	})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if portfolio.SymbolsCount["btc"].String() != "0.75" {
+		t.Fatalf("Expected 0.75 BTC to be held but got %s\n", portfolio.SymbolsCount["btc"])
+	}
+
+	if portfolio.TotalInvested != 2500000 || portfolio.TotalWithdrawn != 1000000 || portfolio.Value != 3750000 {
+		t.Fatalf("Expected invested/withdrawn/value 2500000/1000000/3750000 but got %d/%d/%d\n",
+			portfolio.TotalInvested, portfolio.TotalWithdrawn, portfolio.Value)
+	}
+
+	rows := BuildTransactionRows(transactions, true)
+	if rows[0].Quantity != quarter || rows[0].Total != 1000000 {
+		t.Fatalf("Expected the sell row to be 0.25 for 1000000 but got %s for %d\n", rows[0].Quantity, rows[0].Total)
+	}
+}
+
+func TestTransactionFees(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Fee: 5, Date: utils.StringToDate("2024-01-01")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 200, Fee: 4, Date: utils.StringToDate("2024-06-01")},
+	}
+	portfolio, err := AnalyzeTransactions(transactions, map[string]types.SymbolPrice{
+		"aapl": {AdjPrice: 200},
+	})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if portfolio.TotalInvested != 1005 {
+		t.Fatalf("Expected TotalInvested to be %d but got %d\n", 1005, portfolio.TotalInvested)
+	}
+
+	if portfolio.TotalWithdrawn != 996 {
+		t.Fatalf("Expected TotalWithdrawn to be %d but got %d\n", 996, portfolio.TotalWithdrawn)
+	}
+
+	if portfolio.TotalFees != 9 {
+		t.Fatalf("Expected TotalFees to be %d but got %d\n", 9, portfolio.TotalFees)
+	}
+
+	// half of the 1005 cost basis, 502.5 rounded
+	if portfolio.RealizedGain != 996-503 {
+		t.Fatalf("Expected RealizedGain to be %d but got %d\n", 996-503, portfolio.RealizedGain)
+	}
+
+	if portfolio.GainValue != 1000+996-1005 {
+		t.Fatalf("Expected GainValue to be %d but got %d\n", 1000+996-1005, portfolio.GainValue)
+	}
+
+	rows := BuildTransactionRows(transactions, false)
+	if rows[0].Total != 996 || rows[1].Total != 1005 {
+		t.Fatalf("Expected net totals 996/1005 but got %d/%d\n", rows[0].Total, rows[1].Total)
+	}
+}
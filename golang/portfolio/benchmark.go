@@ -0,0 +1,110 @@
+package portfolio
+
+import (
+	"strings"
+	"time"
+	"tracker/types"
+)
+
+// SimulateBenchmark buys and sells symbol with the daily cash flows of series, at the benchmark price of the same
+// day, and compares the result with the portfolio. history must be sorted by date and cover the first cash flow,
+// events are the dividends and splits of symbol. Benchmark dividends are kept as income like portfolio dividends.
+func SimulateBenchmark(series []types.PortfolioSnapshot, symbol string, history []types.SymbolPrice, events []types.Transaction, asOf time.Time) (types.BenchmarkComparison, error) {
+	return SimulateBenchmarkWithOptions(series, symbol, types.CurrencyUSD, history, events, asOf, DefaultAnalyzeOptions())
+}
+
+// SimulateBenchmarkWithOptions simulates a benchmark priced in currency against a series built in
+// opts.ReportingCurrency, its prices are converted at the rate of every day and its dividends at the rate of their day
+func SimulateBenchmarkWithOptions(series []types.PortfolioSnapshot, symbol, currency string, history []types.SymbolPrice, events []types.Transaction, asOf time.Time, opts AnalyzeOptions) (types.BenchmarkComparison, error) {
+	comparison := types.BenchmarkComparison{Symbol: symbol}
+	if len(series) == 0 || len(history) == 0 || truncateDay(history[0].Date).After(series[0].Date) {
+		return comparison, nil
+	}
+
+	benchmarkSeries := make([]types.PortfolioSnapshot, 0, len(series))
+	var acc moneyAccumulator
+	var units float64
+	var localPrice types.Money
+	var dividends types.Money
+	var prev types.PortfolioSnapshot
+	priceIdx, eventIdx := 0, 0
+
+	for _, s := range series {
+		for ; priceIdx < len(history) && !truncateDay(history[priceIdx].Date).After(s.Date); priceIdx++ {
+			localPrice = history[priceIdx].AdjPrice
+		}
+		price := acc.money(opts.Rates.Convert(localPrice, currency, opts.ReportingCurrency, s.Date))
+
+		for ; eventIdx < len(events) && !truncateDay(events[eventIdx].AsDate()).After(s.Date); eventIdx++ {
+			e := events[eventIdx]
+			if !strings.EqualFold(e.Symbol, symbol) {
+				continue
+			}
+
+			switch e.Type {
+			case types.TransactionTypeDividend:
+				paid := acc.money(types.RoundMoney(units * float64(e.Pps)))
+				acc.add(&dividends, acc.money(opts.Rates.Convert(paid, currency, opts.ReportingCurrency, e.AsDate())))
+			case types.TransactionTypeSplit:
+				units *= float64(e.Pps) / 100
+			}
+		}
+
+		if flow := acc.money(s.NetContributions.Sub(prev.NetContributions)); flow != 0 && price > 0 {
+			units += float64(flow) / float64(price)
+		}
+
+		benchmarkSeries = append(benchmarkSeries, types.PortfolioSnapshot{
+			Date:             s.Date,
+			Value:            acc.money(types.RoundMoney(units * float64(price))),
+			NetContributions: s.NetContributions,
+			Dividends:        dividends,
+		})
+		prev = s
+	}
+
+	invested, withdrawn := seriesCashFlows(series, &acc)
+	last := series[len(series)-1]
+	benchmarkLast := benchmarkSeries[len(benchmarkSeries)-1]
+	benchmarkTotal := acc.money(benchmarkLast.Value.Add(dividends))
+	portfolioTotal := acc.money(last.Value.Add(last.Dividends))
+	if acc.err != nil {
+		return comparison, acc.err
+	}
+
+	comparison.Available = true
+	comparison.Value = benchmarkLast.Value
+	comparison.Dividends = dividends
+	comparison.Gain = simpleGain(benchmarkTotal, invested, withdrawn)
+	comparison.PortfolioGain = simpleGain(portfolioTotal, invested, withdrawn)
+	comparison.TWR = TimeWeightedReturnsFromSeries(benchmarkSeries, asOf).Inception.Annualized
+	comparison.PortfolioTWR = TimeWeightedReturnsFromSeries(series, asOf).Inception.Annualized
+	comparison.ReturnDifference = comparison.PortfolioGain - comparison.Gain
+	comparison.TrackingDifference = comparison.PortfolioTWR - comparison.TWR
+
+	return comparison, nil
+}
+
+// seriesCashFlows returns the total bought and sold over series, the sums are checked by acc
+func seriesCashFlows(series []types.PortfolioSnapshot, acc *moneyAccumulator) (types.Money, types.Money) {
+	var invested, withdrawn types.Money
+	var prev types.Money
+	for _, s := range series {
+		flow := acc.money(s.NetContributions.Sub(prev))
+		if flow > 0 {
+			acc.add(&invested, flow)
+		} else {
+			withdrawn = acc.money(withdrawn.Sub(flow))
+		}
+		prev = s.NetContributions
+	}
+	return invested, withdrawn
+}
+
+// simpleGain is the gain of value and withdrawn on invested, in floats so the difference can't overflow
+func simpleGain(value, invested, withdrawn types.Money) float32 {
+	if invested == 0 {
+		return 0
+	}
+	return float32((float64(value) + float64(withdrawn) - float64(invested)) / float64(invested))
+}
@@ -0,0 +1,137 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestSimulateBenchmark(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-03")},
+	}
+	history := map[string][]types.SymbolPrice{
+		"aapl": {{Symbol: "AAPL", AdjPrice: 110, Date: utils.StringToDate("2024-01-04")}},
+	}
+	benchmarkHistory := []types.SymbolPrice{
+		{Symbol: "SPY", AdjPrice: 50, Date: utils.StringToDate("2024-01-01")},
+		{Symbol: "SPY", AdjPrice: 100, Date: utils.StringToDate("2024-01-03")},
+	}
+	events := []types.Transaction{
+		{Symbol: "SPY", Type: types.TransactionTypeDividend, Pps: 2, Date: utils.StringToDate("2024-01-02")},
+	}
+
+	asOf := utils.StringToDate("2024-01-04")
+	series, err := BuildTimeSeries(transactions, history, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	comparison, err := SimulateBenchmark(series, "SPY", benchmarkHistory, events, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if !comparison.Available {
+		t.Fatalf("Expected the benchmark comparison to be available\n")
+	}
+
+	// 1000 buys 20 units at 50, the 40 dividend is paid on 20 units, 1000 more buys 10 units at 100
+	if comparison.Value != 3000 || comparison.Dividends != 40 {
+		t.Fatalf("Expected benchmark value 3000 and dividends 40 but got %d and %d\n", comparison.Value, comparison.Dividends)
+	}
+
+	if math.Abs(float64(comparison.Gain)-0.52) > 1e-6 || math.Abs(float64(comparison.PortfolioGain)-0.1) > 1e-6 {
+		t.Fatalf("Expected benchmark gain 0.52 and portfolio gain 0.1 but got %f and %f\n", comparison.Gain, comparison.PortfolioGain)
+	}
+
+	if math.Abs(float64(comparison.ReturnDifference)+0.42) > 1e-6 {
+		t.Fatalf("Expected return difference -0.42 but got %f\n", comparison.ReturnDifference)
+	}
+
+	if comparison.TrackingDifference != comparison.PortfolioTWR-comparison.TWR || comparison.TrackingDifference >= 0 {
+		t.Fatalf("Expected a negative tracking difference but got %f\n", comparison.TrackingDifference)
+	}
+}
+
+func TestSimulateBenchmarkInOtherCurrency(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-03")},
+	}
+	history := map[string][]types.SymbolPrice{
+		"aapl": {{Symbol: "AAPL", AdjPrice: 110, Date: utils.StringToDate("2024-01-04")}},
+	}
+	// the index doesn't move in shekels, the shekel doubles against the dollar
+	benchmarkHistory := []types.SymbolPrice{
+		{Symbol: "TA125", AdjPrice: 200, Date: utils.StringToDate("2024-01-01")},
+	}
+	events := []types.Transaction{
+		{Symbol: "TA125", Type: types.TransactionTypeDividend, Pps: 8, Date: utils.StringToDate("2024-01-02")},
+	}
+	opts := DefaultAnalyzeOptions()
+	opts.Rates = types.FxRates{"ILS": {
+		{Currency: "ILS", Date: utils.StringToDate("2024-01-01"), Value: 4},
+		{Currency: "ILS", Date: utils.StringToDate("2024-01-03"), Value: 2},
+	}}
+
+	asOf := utils.StringToDate("2024-01-04")
+	series, err := BuildTimeSeries(transactions, history, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	comparison, err := SimulateBenchmarkWithOptions(series, "TA125", "ILS", benchmarkHistory, events, asOf, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// 1000 buys 20 units at 50 dollars, the 160 shekel dividend is 40 dollars, 1000 more buys 10 units at 100 dollars
+	if comparison.Value != 3000 || comparison.Dividends != 40 {
+		t.Fatalf("Expected benchmark value 3000 and dividends 40 but got %d and %d\n", comparison.Value, comparison.Dividends)
+	}
+
+	if math.Abs(float64(comparison.Gain)-0.52) > 1e-6 {
+		t.Fatalf("Expected benchmark gain 0.52 but got %f\n", comparison.Gain)
+	}
+}
+
+func TestSimulateBenchmarkWithoutHistory(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+	}
+	benchmarkHistory := []types.SymbolPrice{
+		{Symbol: "SPY", AdjPrice: 50, Date: utils.StringToDate("2024-01-02")},
+	}
+
+	asOf := utils.StringToDate("2024-01-04")
+	series, err := BuildTimeSeries(transactions, nil, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	comparison, err := SimulateBenchmark(series, "SPY", benchmarkHistory, nil, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if comparison.Available || comparison.Symbol != "SPY" {
+		t.Fatalf("Expected the comparison to be unavailable when the history starts after the first cash flow\n")
+	}
+}
+
+func TestSimulateBenchmarkOverflow(t *testing.T) {
+	// the cash flow buys 2^61 units at 1, their value doesn't fit once the price quadruples
+	series := []types.PortfolioSnapshot{
+		{Date: utils.StringToDate("2024-01-01"), Value: 1 << 61, NetContributions: 1 << 61},
+		{Date: utils.StringToDate("2024-01-02"), Value: 1 << 61, NetContributions: 1 << 61},
+	}
+	benchmarkHistory := []types.SymbolPrice{
+		{Symbol: "SPY", AdjPrice: 1, Date: utils.StringToDate("2024-01-01")},
+		{Symbol: "SPY", AdjPrice: 4, Date: utils.StringToDate("2024-01-02")},
+	}
+
+	if _, err := SimulateBenchmark(series, "SPY", benchmarkHistory, nil, utils.StringToDate("2024-01-02")); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the benchmark value to overflow but got %v\n", err)
+	}
+}
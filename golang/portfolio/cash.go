@@ -0,0 +1,66 @@
+package portfolio
+
+import (
+	"strings"
+	"tracker/types"
+)
+
+// cashLedger knows which accounts keep a cash ledger. An account keeps one once it records a deposit or a
+// withdrawal, from then on its buys and sells only move cash, deposits and withdrawals are its external flows
+// and dividends on its shares are paid into cash. Accounts without one keep treating buys, sells and dividends
+// as money moving in and out of the portfolio.
+type cashLedger struct {
+	accounts map[string]bool
+	// shares held by ledger accounts by lowercase symbol, dividends don't carry an account so they are split
+	// between ledger and non ledger holdings by share count
+	shares map[string]types.Quantity
+}
+
+func newCashLedger(transactions []types.Transaction) *cashLedger {
+	l := &cashLedger{
+		accounts: make(map[string]bool),
+		shares:   make(map[string]types.Quantity),
+	}
+	for _, t := range transactions {
+		if t.Type.IsExternalFlow() {
+			l.accounts[t.AccountId] = true
+		}
+	}
+	return l
+}
+
+// tracks reports whether the trade t is settled against the cash of its account, the shares it moves are recorded
+func (l *cashLedger) tracks(t types.Transaction) bool {
+	if !l.accounts[t.AccountId] {
+		return false
+	}
+
+	symbol := strings.ToLower(t.Symbol)
+	switch t.Type {
+	case types.TransactionTypeBuy:
+		l.shares[symbol] += t.Quantity
+	case types.TransactionTypeSell:
+		l.shares[symbol] -= t.Quantity
+	case types.TransactionTypeTransfer:
+		// outgoing transfers have a negative quantity
+		l.shares[symbol] += t.Quantity
+	}
+	return true
+}
+
+func (l *cashLedger) split(symbol string, pps types.Money) {
+	if count, ok := l.shares[symbol]; ok {
+		l.shares[symbol] = count.MulRatio(int64(pps), 100)
+	}
+}
+
+// cashDelta is the change in cash of a cash transaction, its amount is kept in Pps
+func cashDelta(t types.Transaction) types.Money {
+	switch t.Type {
+	case types.TransactionTypeDeposit, types.TransactionTypeInterest:
+		return t.Pps
+	case types.TransactionTypeWithdrawal, types.TransactionTypeFee, types.TransactionTypeTax:
+		return -t.Pps
+	}
+	return 0
+}
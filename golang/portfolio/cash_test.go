@@ -0,0 +1,103 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func cashLedgerTransactions() []types.Transaction {
+	return []types.Transaction{
+		{Id: "d1", AccountId: "1", Type: types.TransactionTypeDeposit, Pps: 10000, Date: utils.StringToDate("2024-01-01")},
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 500, Date: utils.StringToDate("2024-01-02")},
+		// an account without deposits keeps treating its buys as contributions
+		{Id: "b2", AccountId: "2", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 500, Date: utils.StringToDate("2024-01-02")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2024-03-01")},
+		{Id: "f1", AccountId: "1", Type: types.TransactionTypeFee, Pps: 50, Date: utils.StringToDate("2024-03-02")},
+		{Id: "i1", AccountId: "1", Type: types.TransactionTypeInterest, Pps: 20, Date: utils.StringToDate("2024-03-03")},
+		{Id: "t1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeTax, Pps: 25, Date: utils.StringToDate("2024-03-04")},
+		{Id: "w1", AccountId: "1", Type: types.TransactionTypeWithdrawal, Pps: 1000, Date: utils.StringToDate("2024-06-01")},
+	}
+}
+
+func TestCashLedger(t *testing.T) {
+	portfolio, err := AnalyzeTransactions(cashLedgerTransactions(), map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 600},
+	})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// 10000 deposited - 5000 bought + 100 dividends - 50 fee + 20 interest - 25 tax - 1000 withdrawn
+	if portfolio.CashBalance != 4045 {
+		t.Fatalf("Expected CashBalance to be %d but got %d\n", 4045, portfolio.CashBalance)
+	}
+
+	// the deposit and the buy of the account without a ledger
+	if portfolio.TotalInvested != 15000 {
+		t.Fatalf("Expected TotalInvested to be %d but got %d\n", 15000, portfolio.TotalInvested)
+	}
+
+	if portfolio.TotalWithdrawn != 1000 {
+		t.Fatalf("Expected TotalWithdrawn to be %d but got %d\n", 1000, portfolio.TotalWithdrawn)
+	}
+
+	if portfolio.TotalDividends != 200 {
+		t.Fatalf("Expected TotalDividends to be %d but got %d\n", 200, portfolio.TotalDividends)
+	}
+
+	if portfolio.TotalFees != 50 || portfolio.TotalInterest != 20 || portfolio.TotalTaxes != 25 {
+		t.Fatalf("Expected fees/interest/taxes 50/20/25 but got %d/%d/%d\n", portfolio.TotalFees, portfolio.TotalInterest, portfolio.TotalTaxes)
+	}
+
+	// 20 shares at 600 plus cash
+	if portfolio.Value != 16045 {
+		t.Fatalf("Expected Value to be %d but got %d\n", 16045, portfolio.Value)
+	}
+
+	// only the dividend of the account without a ledger is paid out of the portfolio
+	expectedGain := types.Money(16045 + 100 + 1000 - 15000)
+	if portfolio.GainValue != expectedGain {
+		t.Fatalf("Expected GainValue to be %d but got %d\n", expectedGain, portfolio.GainValue)
+	}
+
+	if portfolio.XIRR <= 0 {
+		t.Fatalf("Expected a positive XIRR but got %f\n", portfolio.XIRR)
+	}
+}
+
+func TestCashLedgerTimeSeries(t *testing.T) {
+	history := map[string][]types.SymbolPrice{
+		"aapl": {{Symbol: "AAPL", AdjPrice: 600, Date: utils.StringToDate("2024-05-01")}},
+	}
+
+	series, err := BuildTimeSeries(cashLedgerTransactions(), history, utils.StringToDate("2024-06-01"))
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	testCases := []struct {
+		date          string
+		value         types.Money
+		contributions types.Money
+		dividends     types.Money
+	}{
+		{date: "2024-01-01", value: 10000, contributions: 10000, dividends: 0},
+		{date: "2024-01-02", value: 15000, contributions: 15000, dividends: 0},
+		{date: "2024-03-01", value: 15100, contributions: 15000, dividends: 100},
+		{date: "2024-05-01", value: 17045, contributions: 15000, dividends: 100},
+		{date: "2024-06-01", value: 16045, contributions: 14000, dividends: 100},
+	}
+
+	for _, tc := range testCases {
+		s, ok := SnapshotAt(series, utils.StringToDate(tc.date))
+		if !ok {
+			t.Fatalf("Expected a snapshot for %s\n", tc.date)
+		}
+
+		if s.Value != tc.value || s.NetContributions != tc.contributions || s.Dividends != tc.dividends {
+			t.Fatalf("Expected %s value/contributions/dividends %d/%d/%d but got %d/%d/%d\n", tc.date,
+				tc.value, tc.contributions, tc.dividends, s.Value, s.NetContributions, s.Dividends)
+		}
+	}
+}
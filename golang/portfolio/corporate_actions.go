@@ -0,0 +1,133 @@
+package portfolio
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"tracker/types"
+)
+
+// corporateActionTransactions turns the corporate actions after from into rows of the transaction stream
+func corporateActionTransactions(actions []types.CorporateAction, from time.Time) []types.Transaction {
+	rows := make([]types.Transaction, 0, len(actions))
+	for _, a := range actions {
+		if a.Date.Before(from) {
+			continue
+		}
+		rows = append(rows, types.Transaction{
+			Id:     a.Id,
+			Symbol: a.Symbol,
+			Date:   a.Date,
+			Type:   types.TransactionTypeCorporateAction,
+			Action: &a,
+		})
+	}
+	return rows
+}
+
+// replacedSymbols returns the lowercase symbols ended by a rename or a merger in transactions, they have no price
+// of their own anymore but their trades still count
+func replacedSymbols(transactions []types.Transaction) map[string]bool {
+	replaced := make(map[string]bool)
+	for _, t := range transactions {
+		if t.Action != nil && t.Action.Replaces() {
+			replaced[strings.ToLower(t.Action.Symbol)] = true
+		}
+	}
+	return replaced
+}
+
+// corporateAction applies a to the open lots of its symbol. The lots of a renamed symbol move as they are, a merger
+// closes the BasisAllocation share of every lot against its cash and moves the rest of the basis to the new shares,
+// a spin-off moves that share of the basis to the new shares and keeps the parent lots open.
+func (b *lotBook) corporateAction(a types.CorporateAction, id string) {
+	symbol := strings.ToLower(a.Symbol)
+	newSymbol := strings.ToLower(a.NewSymbol)
+
+	keys := make([]lotKey, 0)
+	for key := range b.open {
+		if key.symbol == symbol {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b lotKey) int { return strings.Compare(a.accountId, b.accountId) })
+
+	for _, key := range keys {
+		newKey := lotKey{accountId: key.accountId, symbol: newSymbol}
+
+		for _, lot := range b.open[key] {
+			// the allocation is a share of the basis, at most all of it
+			allocated := types.Money(math.Round(float64(lot.CostBasis) * a.BasisAllocation))
+			newShares := a.NewShares(lot.Quantity)
+
+			switch a.Type {
+			case types.CorporateActionRename:
+				lot.Symbol = a.NewSymbol
+				b.open[newKey] = append(b.open[newKey], lot)
+
+			case types.CorporateActionMerger:
+				if newShares == 0 {
+					// an all cash merger closes the whole lot
+					allocated = lot.CostBasis
+				}
+				if allocated != 0 || a.CashPerShare != 0 {
+					b.closed = append(b.closed, types.ClosedLot{
+						LotId:     lot.Id,
+						SellId:    id,
+						AccountId: lot.AccountId,
+						Symbol:    lot.Symbol,
+						OpenDate:  lot.OpenDate,
+						CloseDate: a.Date,
+						Quantity:  lot.Quantity,
+						CostBasis: allocated,
+						Proceeds:  b.money(a.CashPerShare.MulQuantity(lot.Quantity)),
+					})
+				}
+				if newShares > 0 {
+					b.open[newKey] = append(b.open[newKey], &types.Lot{
+						Id:        lot.Id,
+						AccountId: lot.AccountId,
+						Symbol:    a.NewSymbol,
+						OpenDate:  lot.OpenDate,
+						Quantity:  newShares,
+						CostBasis: lot.CostBasis - allocated,
+					})
+				}
+
+			case types.CorporateActionSpinOff:
+				lot.CostBasis -= allocated
+				b.open[newKey] = append(b.open[newKey], &types.Lot{
+					Id:        lot.Id + "/" + newSymbol,
+					AccountId: lot.AccountId,
+					Symbol:    a.NewSymbol,
+					OpenDate:  lot.OpenDate,
+					Quantity:  newShares,
+					CostBasis: allocated,
+				})
+			}
+		}
+
+		if a.Replaces() && newKey != key {
+			delete(b.open, key)
+		}
+		slices.SortStableFunc(b.open[newKey], func(a, b *types.Lot) int {
+			return a.OpenDate.Compare(b.OpenDate)
+		})
+	}
+}
+
+// corporateAction moves the ledger shares of the symbol of a and returns how many were held before
+func (l *cashLedger) corporateAction(a types.CorporateAction) types.Quantity {
+	symbol := strings.ToLower(a.Symbol)
+	held := l.shares[symbol]
+	if held == 0 {
+		return 0
+	}
+
+	l.shares[strings.ToLower(a.NewSymbol)] += a.NewShares(held)
+	if a.Replaces() {
+		delete(l.shares, symbol)
+	}
+	return held
+}
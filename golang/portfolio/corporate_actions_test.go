@@ -0,0 +1,119 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func actionRow(a types.CorporateAction) types.Transaction {
+	return corporateActionTransactions([]types.CorporateAction{a}, a.Date)[0]
+}
+
+func TestCorporateActionRename(t *testing.T) {
+	rename := types.CorporateAction{Id: "ca1", Date: utils.StringToDate("2024-02-01"), Type: types.CorporateActionRename, Symbol: "FB", NewSymbol: "META"}
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "FB", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		actionRow(rename),
+		{Id: "s1", AccountId: "a", Symbol: "META", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(4), Pps: 300, Date: utils.StringToDate("2024-03-01")},
+	}
+
+	// FB has no price anymore, its buy still counts
+	prices := map[string]types.SymbolPrice{"meta": {AdjPrice: 300}}
+	p, err := AnalyzeTransactions(transactions, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if _, ok := p.SymbolsCount["fb"]; ok || p.SymbolsCount["meta"] != types.NewQuantity(6) {
+		t.Fatalf("Expected 6 META shares and no FB but got %v\n", p.SymbolsCount)
+	}
+
+	if p.Value != 1800 || p.TotalInvested != 1000 {
+		t.Fatalf("Expected value/invested 1800/1000 but got %d/%d\n", p.Value, p.TotalInvested)
+	}
+
+	if p.RealizedGain != 800 || p.ClosedLots[0].LotId != "b1" {
+		t.Fatalf("Expected the META sell to close the FB lot with a gain of 800 but got %d %+v\n", p.RealizedGain, p.ClosedLots)
+	}
+
+	if p.OpenLots[0].Symbol != "META" || p.OpenLots[0].CostBasis != 600 {
+		t.Fatalf("Expected a META lot with a basis of 600 but got %+v\n", p.OpenLots)
+	}
+}
+
+func TestCorporateActionMerger(t *testing.T) {
+	// 0.5 shares of BIG and 10 in cash for every share of SMALL, a fifth of the basis goes to the cash
+	merger := types.CorporateAction{Id: "ca1", Date: utils.StringToDate("2024-02-01"), Type: types.CorporateActionMerger, Symbol: "SMALL", NewSymbol: "BIG",
+		SharesPerShare: types.QuantityFromFloat(0.5), CashPerShare: 10, BasisAllocation: 0.2}
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "SMALL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 50, Date: utils.StringToDate("2024-01-01")},
+		actionRow(merger),
+	}
+
+	prices := map[string]types.SymbolPrice{"big": {AdjPrice: 120}}
+	p, err := AnalyzeTransactions(transactions, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if p.SymbolsCount["big"] != types.NewQuantity(5) || p.Value != 600 {
+		t.Fatalf("Expected 5 BIG shares worth 600 but got %v %d\n", p.SymbolsCount, p.Value)
+	}
+
+	if p.TotalWithdrawn != 100 {
+		t.Fatalf("Expected the merger cash of 100 to be withdrawn but got %d\n", p.TotalWithdrawn)
+	}
+
+	if p.RealizedGain != 0 || p.ClosedLots[0].CostBasis != 100 || p.ClosedLots[0].SellId != "ca1" {
+		t.Fatalf("Expected the cash to close 100 of basis at no gain but got %d %+v\n", p.RealizedGain, p.ClosedLots)
+	}
+
+	if p.OpenLots[0].Symbol != "BIG" || p.OpenLots[0].CostBasis != 400 || p.OpenLots[0].Quantity != types.NewQuantity(5) {
+		t.Fatalf("Expected 5 BIG shares with a basis of 400 but got %+v\n", p.OpenLots)
+	}
+
+	if p.GainValue != 200 {
+		t.Fatalf("Expected GainValue to be %d but got %d\n", 200, p.GainValue)
+	}
+}
+
+func TestCorporateActionSpinOff(t *testing.T) {
+	spinOff := types.CorporateAction{Id: "ca1", Date: utils.StringToDate("2024-02-01"), Type: types.CorporateActionSpinOff, Symbol: "PARENT", NewSymbol: "CHILD",
+		SharesPerShare: types.QuantityFromFloat(0.25), BasisAllocation: 0.1}
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "PARENT", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(8), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		actionRow(spinOff),
+	}
+
+	prices := map[string]types.SymbolPrice{"parent": {AdjPrice: 100}, "child": {AdjPrice: 40}}
+	p, err := AnalyzeTransactions(transactions, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if p.SymbolsCount["parent"] != types.NewQuantity(8) || p.SymbolsCount["child"] != types.NewQuantity(2) {
+		t.Fatalf("Expected 8 PARENT and 2 CHILD shares but got %v\n", p.SymbolsCount)
+	}
+
+	if p.Value != 880 || p.TotalInvested != 800 || len(p.ClosedLots) != 0 {
+		t.Fatalf("Expected value/invested 880/800 and nothing closed but got %d/%d %+v\n", p.Value, p.TotalInvested, p.ClosedLots)
+	}
+
+	basis := map[string]types.Money{}
+	for _, l := range p.OpenLots {
+		basis[l.Symbol] = l.CostBasis
+	}
+	if basis["PARENT"] != 720 || basis["CHILD"] != 80 {
+		t.Fatalf("Expected the basis to split 720/80 but got %v\n", basis)
+	}
+
+	series, err := BuildTimeSeries(transactions, nil, utils.StringToDate("2024-02-01"))
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	last := series[len(series)-1]
+	if last.Holdings["child"] != types.NewQuantity(2) || last.NetContributions != 800 {
+		t.Fatalf("Expected the spun off shares in the last snapshot but got %+v\n", last)
+	}
+}
@@ -0,0 +1,177 @@
+package portfolio
+
+import (
+	"strings"
+	"time"
+	"tracker/types"
+)
+
+// currencyBook converts transactions to the reporting currency at the rate of their day. It keeps the flows and
+// the cash of every currency both in the currency itself and converted, so the gain of the portfolio can be split
+// into the gain in the currencies it trades in and the gain from the exchange rates moving.
+type currencyBook struct {
+	rates     types.FxRates
+	reporting string
+	// currency of every lowercase symbol, from the prices or else from the first trade in it
+	symbols map[string]string
+	returns map[string]*types.CurrencyReturn
+	cash    map[string]types.Money
+	// acc keeps the first overflow of the sums, the analysis reports it
+	acc moneyAccumulator
+}
+
+func newCurrencyBook(transactions []types.Transaction, pricesTable map[string]types.SymbolPrice, opts AnalyzeOptions) *currencyBook {
+	b := &currencyBook{
+		rates:     opts.Rates,
+		reporting: types.NormalizeCurrency(opts.ReportingCurrency),
+		symbols:   make(map[string]string),
+		returns:   make(map[string]*types.CurrencyReturn),
+		cash:      make(map[string]types.Money),
+	}
+
+	for symbol, p := range pricesTable {
+		if p.Currency != "" {
+			b.symbols[symbol] = types.NormalizeCurrency(p.Currency)
+		}
+	}
+	for _, t := range transactions {
+		if t.Type.IsCash() || t.Currency == "" {
+			continue
+		}
+		if _, ok := b.symbols[strings.ToLower(t.Symbol)]; !ok {
+			b.symbols[strings.ToLower(t.Symbol)] = types.NormalizeCurrency(t.Currency)
+		}
+		// what a symbol becomes trades in the same currency unless its price says otherwise
+		if t.Action != nil && t.Action.NewSymbol != "" {
+			if _, ok := b.symbols[strings.ToLower(t.Action.NewSymbol)]; !ok {
+				b.symbols[strings.ToLower(t.Action.NewSymbol)] = types.NormalizeCurrency(t.Currency)
+			}
+		}
+	}
+
+	return b
+}
+
+// currencyOf returns the currency of the amounts of t, market events without one are in the currency of their symbol
+func (b *currencyBook) currencyOf(t types.Transaction) string {
+	if t.Currency != "" || t.Type.IsCash() {
+		return types.NormalizeCurrency(t.Currency)
+	}
+	return b.symbolCurrency(t.Symbol)
+}
+
+func (b *currencyBook) symbolCurrency(symbol string) string {
+	if currency, ok := b.symbols[strings.ToLower(symbol)]; ok {
+		return currency
+	}
+	return types.CurrencyUSD
+}
+
+func (b *currencyBook) toReporting(amount types.Money, currency string, date time.Time) types.Money {
+	return b.acc.money(b.rates.Convert(amount, currency, b.reporting, date))
+}
+
+// convert returns t with its amounts in the reporting currency at the rate of its day
+func (b *currencyBook) convert(t types.Transaction) types.Transaction {
+	currency := b.currencyOf(t)
+	if currency == b.reporting {
+		return t
+	}
+
+	t.Pps = b.toReporting(t.Pps, currency, t.Date)
+	t.Fee = b.toReporting(t.Fee, currency, t.Date)
+	if t.Action != nil {
+		a := *t.Action
+		a.CashPerShare = b.toReporting(a.CashPerShare, currency, t.Date)
+		t.Action = &a
+	}
+	return t
+}
+
+// convertPrices returns the prices in the reporting currency at today's rate
+func (b *currencyBook) convertPrices(pricesTable map[string]types.SymbolPrice, today time.Time) map[string]types.SymbolPrice {
+	converted := make(map[string]types.SymbolPrice, len(pricesTable))
+	for symbol, p := range pricesTable {
+		p.AdjPrice = b.toReporting(p.AdjPrice, b.symbolCurrency(symbol), today)
+		p.Currency = b.reporting
+		converted[symbol] = p
+	}
+	return converted
+}
+
+func (b *currencyBook) get(currency string) *types.CurrencyReturn {
+	r, ok := b.returns[currency]
+	if !ok {
+		r = &types.CurrencyReturn{Currency: currency}
+		b.returns[currency] = r
+	}
+	return r
+}
+
+func (b *currencyBook) invested(currency string, local, reporting types.Money) {
+	r := b.get(currency)
+	b.acc.add(&r.Invested, local)
+	b.acc.add(&r.ReportingInvested, reporting)
+}
+
+func (b *currencyBook) withdrawn(currency string, local, reporting types.Money) {
+	r := b.get(currency)
+	b.acc.add(&r.Withdrawn, local)
+	b.acc.add(&r.ReportingWithdrawn, reporting)
+}
+
+func (b *currencyBook) dividends(currency string, local, reporting types.Money) {
+	r := b.get(currency)
+	b.acc.add(&r.Dividends, local)
+	b.acc.add(&r.ReportingDividends, reporting)
+}
+
+func (b *currencyBook) addCash(currency string, local types.Money) {
+	cash := b.cash[currency]
+	b.acc.add(&cash, local)
+	b.cash[currency] = cash
+}
+
+// cashValue returns the cash held in every currency at today's rate
+func (b *currencyBook) cashValue(today time.Time) types.Money {
+	var value types.Money
+	for currency, amount := range b.cash {
+		b.acc.add(&value, b.toReporting(amount, currency, today))
+	}
+	return value
+}
+
+// currencyReturns values the holdings and cash of every currency and splits its gain, symbolsCount is keyed by
+// lowercase symbol and the prices are in the currency of their symbol
+func (b *currencyBook) currencyReturns(symbolsCount map[string]types.Quantity, pricesTable map[string]types.SymbolPrice, today time.Time) (map[string]types.CurrencyReturn, error) {
+	for symbol, count := range symbolsCount {
+		r := b.get(b.symbolCurrency(symbol))
+		b.acc.add(&r.Value, b.acc.value(count, pricesTable[symbol].AdjPrice))
+	}
+	for currency, amount := range b.cash {
+		b.acc.add(&b.get(currency).Value, amount)
+	}
+
+	returns := make(map[string]types.CurrencyReturn, len(b.returns))
+	for currency, r := range b.returns {
+		r.GainValue = 0
+		b.acc.add(&r.GainValue, r.Value)
+		b.acc.add(&r.GainValue, r.Withdrawn)
+		b.acc.add(&r.GainValue, r.Dividends)
+		b.acc.add(&r.GainValue, -r.Invested)
+		if r.Invested != 0 {
+			r.Gain = float32(r.GainValue) / float32(r.Invested)
+		}
+
+		r.ReportingValue = b.toReporting(r.Value, currency, today)
+		r.ReportingGain = 0
+		b.acc.add(&r.ReportingGain, r.ReportingValue)
+		b.acc.add(&r.ReportingGain, r.ReportingWithdrawn)
+		b.acc.add(&r.ReportingGain, r.ReportingDividends)
+		b.acc.add(&r.ReportingGain, -r.ReportingInvested)
+		r.FxGain = r.ReportingGain
+		b.acc.add(&r.FxGain, -b.toReporting(r.GainValue, currency, today))
+		returns[currency] = *r
+	}
+	return returns, b.acc.err
+}
@@ -0,0 +1,121 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestMultiCurrencyReturns(t *testing.T) {
+	opts := DefaultAnalyzeOptions()
+	opts.Rates = types.FxRates{"ILS": {
+		{Currency: "ILS", Date: utils.StringToDate("2024-01-01"), Value: 4},
+		{Currency: "ILS", Date: utils.StringToDate("2024-06-01"), Value: 3.5},
+	}}
+
+	transactions := []types.Transaction{
+		// a cash ledger account holding shekels
+		{Id: "d1", AccountId: "b", Type: types.TransactionTypeDeposit, Pps: 40000, Currency: "ILS", Date: utils.StringToDate("2024-01-01")},
+		{Id: "b1", AccountId: "a", Symbol: "TEVA", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 10000, Currency: "ILS", Date: utils.StringToDate("2024-01-02")},
+		{Id: "b2", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: 10000, Date: utils.StringToDate("2024-01-02")},
+	}
+	prices := map[string]types.SymbolPrice{
+		"teva": {Symbol: "TEVA", AdjPrice: 12000},
+		"aapl": {Symbol: "AAPL", AdjPrice: 11000},
+	}
+
+	p, err := AnalyzeTransactionsWithOptions(transactions, prices, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// 400 ILS and 1000 ILS of shares at 4 to the dollar plus 100 dollars of AAPL
+	if p.TotalInvested != 45000 {
+		t.Fatalf("Expected TotalInvested to be %d but got %d\n", 45000, p.TotalInvested)
+	}
+
+	// the shekel holdings and cash are valued at today's 3.5
+	if p.CashBalance != 11429 || p.Value != 11429+34286+11000 {
+		t.Fatalf("Expected cash/value %d/%d but got %d/%d\n", 11429, 11429+34286+11000, p.CashBalance, p.Value)
+	}
+
+	ils := p.Currencies["ILS"]
+	if ils.Invested != 140000 || ils.GainValue != 20000 || ils.Gain != float32(20000)/140000 {
+		t.Fatalf("Expected a local gain of 20000 on 140000 shekels but got %+v\n", ils)
+	}
+
+	// the shekel gained 1/4 - 1/3.5 of a dollar per shekel on the 1400 shekels invested
+	if ils.ReportingGain != 10714 || ils.FxGain != 5000 {
+		t.Fatalf("Expected reporting/fx gain 10714/5000 but got %d/%d\n", ils.ReportingGain, ils.FxGain)
+	}
+
+	if p.Currencies["USD"].FxGain != 0 || p.Currencies["USD"].GainValue != 1000 {
+		t.Fatalf("Expected no FX gain on dollars but got %+v\n", p.Currencies["USD"])
+	}
+
+	if p.FxGainValue != 5000 || p.LocalGainValue != p.GainValue-5000 || p.GainValue != 11715 {
+		t.Fatalf("Expected gain %d split into local %d and fx %d but got %d/%d/%d\n", 11715, 11715-5000, 5000, p.GainValue, p.LocalGainValue, p.FxGainValue)
+	}
+
+	// lots carry their cost in dollars at the rate of the buy
+	if p.CostBasis("TEVA") != 25000 {
+		t.Fatalf("Expected the TEVA cost basis to be %d but got %d\n", 25000, p.CostBasis("TEVA"))
+	}
+	// the transactions shown are converted at the rate of their day as well
+	teva := p.ReportingTransactions[1]
+	if teva.Id != "b1" || teva.Pps != 2500 || teva.Currency != types.CurrencyUSD || p.Transactions[1].Pps != 10000 {
+		t.Fatalf("Expected the TEVA buy at 2500 USD but got %+v\n", teva)
+	}
+}
+
+func TestSingleCurrencyHasNoFxGain(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+	}
+
+	p, err := AnalyzeTransactions(transactions, map[string]types.SymbolPrice{"aapl": {AdjPrice: 150}})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if p.ReportingCurrency != types.CurrencyUSD || p.FxGainValue != 0 || p.LocalGainValue != p.GainValue {
+		t.Fatalf("Expected the whole gain to be local but got %+v\n", p)
+	}
+}
+
+func TestReportingCurrencyUsesRateOfEachDay(t *testing.T) {
+	opts := DefaultAnalyzeOptions()
+	opts.ReportingCurrency = "ILS"
+	opts.Rates = types.FxRates{"ILS": {
+		{Currency: "ILS", Date: utils.StringToDate("2024-01-01"), Value: 4},
+		{Currency: "ILS", Date: utils.StringToDate("2024-06-01"), Value: 3.5},
+	}}
+
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: 10000, Date: utils.StringToDate("2024-01-02")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(1), Pps: 1000, Date: utils.StringToDate("2024-07-01")},
+	}
+	prices := map[string]types.SymbolPrice{"aapl": {Symbol: "AAPL", AdjPrice: 11000}}
+
+	p, err := AnalyzeTransactionsWithOptions(transactions, prices, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// the buy converts at 4 and the dividend at 3.5, not both at today's rate
+	if p.TotalInvested != 40000 || p.TotalDividends != 3500 {
+		t.Fatalf("Expected invested/dividends 40000/3500 but got %d/%d\n", p.TotalInvested, p.TotalDividends)
+	}
+
+	if p.Value != 38500 || p.FxGainValue != -5000 {
+		t.Fatalf("Expected value %d and fx gain %d but got %d/%d\n", 38500, -5000, p.Value, p.FxGainValue)
+	}
+
+	rows, err := BuildHoldingRows(p, prices, nil)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if rows[0].Price != 38500 || rows[0].MarketValue != 38500 || rows[0].Unrealized != -1500 {
+		t.Fatalf("Expected the holding priced in shekels but got %+v\n", rows[0])
+	}
+}
@@ -0,0 +1,275 @@
+package portfolio
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tracker/config"
+	"tracker/loaders"
+	"tracker/types"
+)
+
+// DividendTax resolves the tax rate of dividends from Rates, by the account receiving them and the country of the
+// symbol paying them as found in Symbols
+type DividendTax struct {
+	Rates   config.DividendTaxConfig
+	Symbols map[string]types.SymbolInfo
+}
+
+// LoadDividendTax resolves rates with the symbol metadata in the database
+func LoadDividendTax(db *sql.DB, rates config.DividendTaxConfig) DividendTax {
+	symbols, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		symbols = map[string]types.SymbolInfo{}
+	}
+	return DividendTax{Rates: rates, Symbols: symbols}
+}
+
+// Rate returns the rate of the dividends symbol pays in the account
+func (d DividendTax) Rate(accountId, symbol string) float64 {
+	return d.Rates.Rate(accountId, d.Symbols[strings.ToLower(symbol)].Country)
+}
+
+// AfterTax returns the dividend flows received in the account after tax
+func (d DividendTax) AfterTax(accountId string, flows []types.DividendFlow) (types.Money, error) {
+	var acc moneyAccumulator
+	taxed := make(taxedDividends)
+	for _, f := range flows {
+		taxed.add(&acc, d.Rate(accountId, f.Symbol), f.Amount)
+	}
+	net := taxed.net(&acc)
+	return net, acc.err
+}
+
+// EffectiveRate is the part of gross the tax takes when it nets to net, the default rate when nothing was paid
+func (d DividendTax) EffectiveRate(gross, net types.Money) float64 {
+	if gross == 0 {
+		return d.Rates.Default
+	}
+	return 1 - float64(net)/float64(gross)
+}
+
+// HoldingRates returns the rate of every symbol held in accountsData, keyed by lowercase symbol. A symbol held in
+// accounts taxed differently gets their rates weighted by the shares each account holds.
+func (d DividendTax) HoldingRates(accountsData map[string]types.AnalyzedPortfolio) map[string]float64 {
+	weighted := make(map[string]float64)
+	shares := make(map[string]float64)
+	for accountId, p := range accountsData {
+		for symbol, count := range p.SymbolsCount {
+			if count <= 0 {
+				continue
+			}
+			key := strings.ToLower(symbol)
+			weighted[key] += d.Rate(accountId, key) * count.Float64()
+			shares[key] += count.Float64()
+		}
+	}
+
+	rates := make(map[string]float64, len(shares))
+	for key, total := range shares {
+		rates[key] = weighted[key] / total
+	}
+	return rates
+}
+
+// taxedDividends sums dividends by the rate they are taxed at. The tax is taken once per rate, dividends taxed at a
+// single rate net the same as config.DividendsAfterTax on their total. The sums are checked by acc.
+type taxedDividends map[float64]types.Money
+
+func (t taxedDividends) add(acc *moneyAccumulator, rate float64, amount types.Money) {
+	total := t[rate]
+	acc.add(&total, amount)
+	t[rate] = total
+}
+
+func (t taxedDividends) gross(acc *moneyAccumulator) types.Money {
+	var gross types.Money
+	for _, amount := range t {
+		acc.add(&gross, amount)
+	}
+	return gross
+}
+
+func (t taxedDividends) net(acc *moneyAccumulator) types.Money {
+	var net types.Money
+	for rate, amount := range t {
+		acc.add(&net, config.DividendsAfterTax(amount, rate))
+	}
+	return net
+}
+
+// LoadDividendReport reports the dividends received by each of accounts, analyzed in currency. Net amounts are after
+// the rates of taxes.
+func LoadDividendReport(db *sql.DB, accounts []types.Account, currency string, taxes config.DividendTaxConfig) (types.DividendReport, error) {
+	accountsData := make(map[string]types.AnalyzedPortfolio, len(accounts))
+	for _, ac := range accounts {
+		p, err := LoadAndAnalyzeIn(db, ac, currency)
+		if err != nil {
+			return types.DividendReport{}, err
+		}
+		accountsData[ac.Id] = p
+	}
+
+	return BuildDividendReport(accounts, accountsData, time.Now(), LoadDividendTax(db, taxes))
+}
+
+// BuildDividendReport groups the dividend flows of accounts, as analyzed in accountsData, by year, month, symbol and
+// account up to now. Net amounts are after the rate tax resolves for each flow.
+func BuildDividendReport(accounts []types.Account, accountsData map[string]types.AnalyzedPortfolio, now time.Time, tax DividendTax) (types.DividendReport, error) {
+	var acc moneyAccumulator
+	asOf := truncateDay(now)
+	ttmFrom := asOf.AddDate(-1, 0, 0)
+	report := types.DividendReport{AsOf: asOf}
+
+	total, ttmTotal := make(taxedDividends), make(taxedDividends)
+	// years and months are keyed by the day they start
+	years := make(map[time.Time]taxedDividends)
+	months := make(map[time.Time]taxedDividends)
+	symbols := make(map[string]*dividendGroup)
+	accountGroups := make(map[string]*dividendGroup)
+	// the part of last year up to the day of now, the current year is compared with it
+	var lastYearToDate types.Money
+	var first time.Time
+
+	for _, ac := range accounts {
+		p, ok := accountsData[ac.Id]
+		if !ok {
+			continue
+		}
+
+		for _, f := range p.DividendFlows {
+			date := truncateDay(f.Date)
+			if date.After(asOf) {
+				continue
+			}
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+
+			rate := tax.Rate(ac.Id, f.Symbol)
+			ttm := types.Money(0)
+			if date.After(ttmFrom) {
+				ttm = f.Amount
+				ttmTotal.add(&acc, rate, f.Amount)
+			}
+			acc.add(&report.Gross, f.Amount)
+			acc.add(&report.TTM, ttm)
+			total.add(&acc, rate, f.Amount)
+			addTaxedDividends(&acc, years, yearStart(date.Year()), rate, f.Amount)
+			addTaxedDividends(&acc, months, monthStart(date), rate, f.Amount)
+			if date.Year() == asOf.Year()-1 && !date.After(ttmFrom) {
+				acc.add(&lastYearToDate, f.Amount)
+			}
+
+			addDividendGroup(&acc, symbols, strings.ToUpper(f.Symbol), rate, f.Amount, ttm)
+			addDividendGroup(&acc, accountGroups, ac.Name, rate, f.Amount, ttm)
+		}
+	}
+
+	if acc.err != nil {
+		return types.DividendReport{}, acc.err
+	}
+	if first.IsZero() {
+		return report, nil
+	}
+	report.Net = total.net(&acc)
+	report.TTMNet = ttmTotal.net(&acc)
+
+	for year := first.Year(); year <= asOf.Year(); year++ {
+		start := yearStart(year)
+		period := types.DividendPeriod{
+			Label: strconv.Itoa(year),
+			Start: start,
+			Gross: years[start].gross(&acc),
+			Net:   years[start].net(&acc),
+		}
+		previous := years[yearStart(year-1)].gross(&acc)
+		if year == asOf.Year() {
+			period.Label += " YTD"
+			previous = lastYearToDate
+		}
+		setDividendGrowth(&period, previous)
+		report.Years = append(report.Years, period)
+	}
+
+	for month := monthStart(first); !month.After(asOf); month = month.AddDate(0, 1, 0) {
+		period := types.DividendPeriod{
+			Label: month.Format("Jan 2006"),
+			Start: month,
+			Gross: months[month].gross(&acc),
+			Net:   months[month].net(&acc),
+		}
+		setDividendGrowth(&period, months[month.AddDate(-1, 0, 0)].gross(&acc))
+		report.Months = append(report.Months, period)
+	}
+
+	report.Symbols = dividendGroups(&acc, symbols, report.Gross)
+	report.Accounts = dividendGroups(&acc, accountGroups, report.Gross)
+	if acc.err != nil {
+		return types.DividendReport{}, acc.err
+	}
+
+	return report, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func setDividendGrowth(period *types.DividendPeriod, previous types.Money) {
+	if previous > 0 {
+		period.Growth = float32(float64(period.Gross-previous) / float64(previous))
+		period.HasGrowth = true
+	}
+}
+
+func yearStart(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func addTaxedDividends(acc *moneyAccumulator, periods map[time.Time]taxedDividends, start time.Time, rate float64, amount types.Money) {
+	if periods[start] == nil {
+		periods[start] = make(taxedDividends)
+	}
+	periods[start].add(acc, rate, amount)
+}
+
+// dividendGroup is a symbol or an account of the report while its flows are added
+type dividendGroup struct {
+	types.DividendGroup
+	taxed taxedDividends
+}
+
+func addDividendGroup(acc *moneyAccumulator, groups map[string]*dividendGroup, label string, rate float64, amount, ttm types.Money) {
+	g, ok := groups[label]
+	if !ok {
+		g = &dividendGroup{DividendGroup: types.DividendGroup{Label: label}, taxed: make(taxedDividends)}
+		groups[label] = g
+	}
+	acc.add(&g.Gross, amount)
+	acc.add(&g.TTM, ttm)
+	g.taxed.add(acc, rate, amount)
+}
+
+// dividendGroups returns the groups sorted by their gross dividends, with their net and percent of total
+func dividendGroups(acc *moneyAccumulator, groups map[string]*dividendGroup, total types.Money) []types.DividendGroup {
+	result := make([]types.DividendGroup, 0, len(groups))
+	for _, g := range groups {
+		g.Net = g.taxed.net(acc)
+		if total != 0 {
+			g.Percent = float32(float64(g.Gross) / float64(total))
+		}
+		result = append(result, g.DividendGroup)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Gross == result[j].Gross {
+			return result[i].Label < result[j].Label
+		}
+		return result[i].Gross > result[j].Gross
+	})
+
+	return result
+}
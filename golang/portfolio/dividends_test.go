@@ -0,0 +1,153 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestBuildDividendReport(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 100},
+		"msft": {Symbol: "MSFT", AdjPrice: 100},
+	}
+	taxable, err := AnalyzeTransactions([]types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2024-05-15")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2025-05-15")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2025-11-15")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2026-05-15")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 10, Date: utils.StringToDate("2026-11-20")},
+	}, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	ira, err := AnalyzeTransactions([]types.Transaction{
+		{Symbol: "MSFT", Type: types.TransactionTypeDividend, Pps: 50, Date: utils.StringToDate("2024-12-01")},
+		{Id: "b2", AccountId: "2", Symbol: "MSFT", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(5), Pps: 100, Date: utils.StringToDate("2025-01-01")},
+		{Symbol: "msft", Type: types.TransactionTypeDividend, Pps: 20, Date: utils.StringToDate("2025-03-10")},
+		{Symbol: "msft", Type: types.TransactionTypeDividend, Pps: 20, Date: utils.StringToDate("2026-03-10")},
+	}, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	accounts := []types.Account{{Id: "1", Name: "Taxable"}, {Id: "2", Name: "IRA"}}
+	accountsData := map[string]types.AnalyzedPortfolio{"1": taxable, "2": ira}
+	report, err := BuildDividendReport(accounts, accountsData, utils.StringToDate("2026-10-17"), DividendTax{Rates: config.DividendTaxConfig{Default: 0.25}})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// the dividend paid before MSFT was bought and the one after the report are left out
+	if report.Gross != 600 || report.Net != 450 {
+		t.Fatalf("Expected gross/net 600/450 but got %d/%d\n", report.Gross, report.Net)
+	}
+	if report.TTM != 300 || report.TTMNet != 225 {
+		t.Fatalf("Expected TTM gross/net 300/225 but got %d/%d\n", report.TTM, report.TTMNet)
+	}
+
+	if len(report.Years) != 3 {
+		t.Fatalf("Expected 3 years but got %v\n", report.Years)
+	}
+	if y := report.Years[0]; y.Label != "2024" || y.Gross != 100 || y.HasGrowth {
+		t.Fatalf("Expected 2024 to pay 100 without growth but got %v\n", y)
+	}
+	if y := report.Years[1]; y.Gross != 300 || !y.HasGrowth || y.Growth != 2 {
+		t.Fatalf("Expected 2025 to pay 300, up 200%% but got %v\n", y)
+	}
+	// 2026 so far is compared with 2025 up to October 17th
+	if y := report.Years[2]; y.Label != "2026 YTD" || y.Gross != 200 || !y.HasGrowth || y.Growth != 0 {
+		t.Fatalf("Expected 2026 YTD to pay 200, flat but got %v\n", y)
+	}
+
+	if len(report.Months) != 30 || report.Months[0].Label != "May 2024" || report.Months[29].Label != "Oct 2026" {
+		t.Fatalf("Expected the months from May 2024 to Oct 2026 but got %d\n", len(report.Months))
+	}
+	if m := report.Months[12]; m.Label != "May 2025" || m.Gross != 100 || !m.HasGrowth || m.Growth != 0 {
+		t.Fatalf("Expected May 2025 to pay 100, flat but got %v\n", m)
+	}
+
+	expectedSymbols := []types.DividendGroup{
+		{Label: "AAPL", Gross: 400, Net: 300, TTM: 200, Percent: float32(400) / 600},
+		{Label: "MSFT", Gross: 200, Net: 150, TTM: 100, Percent: float32(200) / 600},
+	}
+	if len(report.Symbols) != 2 || report.Symbols[0] != expectedSymbols[0] || report.Symbols[1] != expectedSymbols[1] {
+		t.Fatalf("Expected symbols %v but got %v\n", expectedSymbols, report.Symbols)
+	}
+	if len(report.Accounts) != 2 || report.Accounts[0].Label != "Taxable" || report.Accounts[1].Gross != 200 {
+		t.Fatalf("Expected Taxable then IRA but got %v\n", report.Accounts)
+	}
+
+	// the IRA is tax exempt, only the dividends of the taxable account are taxed
+	tax := DividendTax{Rates: config.DividendTaxConfig{Default: 0.25, Accounts: map[string]float64{"2": 0}}}
+	report, err = BuildDividendReport(accounts, accountsData, utils.StringToDate("2026-10-17"), tax)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if report.Net != 500 || report.TTMNet != 250 || report.Accounts[1].Net != 200 || report.Symbols[1].Net != 200 {
+		t.Fatalf("Expected net/TTM net 500/250 with the IRA untaxed but got %d/%d\n", report.Net, report.TTMNet)
+	}
+	if y := report.Years[1]; y.Net != 250 {
+		t.Fatalf("Expected 2025 to net 250 but got %d\n", y.Net)
+	}
+}
+
+func TestDividendTax(t *testing.T) {
+	tax := DividendTax{
+		Rates: config.DividendTaxConfig{
+			Default:   0.25,
+			Accounts:  map[string]float64{"ira": 0, "ira:IL": 0.25, "il": 0.3},
+			Countries: map[string]float64{"IL": 0.15},
+		},
+		Symbols: map[string]types.SymbolInfo{
+			"teva": {Symbol: "TEVA", Country: "IL"},
+			"aapl": {Symbol: "AAPL", Country: "US"},
+		},
+	}
+
+	rates := []struct {
+		account, symbol string
+		expected        float64
+	}{
+		{"taxable", "AAPL", 0.25},
+		{"taxable", "TEVA", 0.15},
+		{"taxable", "MSFT", 0.25},
+		{"ira", "AAPL", 0},
+		{"ira", "teva", 0.25},
+		{"il", "TEVA", 0.3},
+	}
+	for _, r := range rates {
+		if rate := tax.Rate(r.account, r.symbol); rate != r.expected {
+			t.Fatalf("Expected %s in %s to be taxed at %f but got %f\n", r.symbol, r.account, r.expected, rate)
+		}
+	}
+
+	flows := []types.DividendFlow{
+		{Symbol: "AAPL", Amount: 100},
+		{Symbol: "TEVA", Amount: 200},
+		{Symbol: "AAPL", Amount: 101},
+	}
+	// AAPL is taxed once on 201, rounding to 151, TEVA nets 170
+	if net, err := tax.AfterTax("taxable", flows); err != nil || net != 321 {
+		t.Fatalf("Expected the dividends to net 321 but got %d, %v\n", net, err)
+	}
+	overflow := append(flows, types.DividendFlow{Symbol: "AAPL", Amount: math.MaxInt64})
+	if _, err := tax.AfterTax("taxable", overflow); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the dividends to overflow but got %v\n", err)
+	}
+	if rate := tax.EffectiveRate(401, 321); rate < 0.199 || rate > 0.2 {
+		t.Fatalf("Expected an effective rate of about 0.1995 but got %f\n", rate)
+	}
+
+	holdingRates := tax.HoldingRates(map[string]types.AnalyzedPortfolio{
+		"taxable": {SymbolsCount: map[string]types.Quantity{"AAPL": types.NewQuantity(30), "TEVA": types.NewQuantity(10)}},
+		"ira":     {SymbolsCount: map[string]types.Quantity{"aapl": types.NewQuantity(10), "MSFT": 0}},
+	})
+	if len(holdingRates) != 2 || holdingRates["aapl"] != 0.1875 || holdingRates["teva"] != 0.15 {
+		t.Fatalf("Expected AAPL weighted to 0.1875 and TEVA at 0.15 but got %v\n", holdingRates)
+	}
+}
@@ -0,0 +1,203 @@
+package portfolio
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+	"tracker/config"
+	"tracker/loaders"
+	"tracker/types"
+)
+
+// dividendHistoryYears is how far back the dividends of the held symbols are loaded to guess their frequency
+const dividendHistoryYears = 2
+
+// LoadDividendForecast projects the dividends of the current holdings of the accounts, together, over the next 12
+// months. Amounts are in currency, net amounts after the rates of taxes.
+func LoadDividendForecast(db *sql.DB, accountIds []string, currency string, taxes config.DividendTaxConfig) (types.DividendForecast, error) {
+	now := time.Now()
+	p, err := LoadAndAnalyzeAccountsIn(db, accountIds, currency)
+	if err != nil {
+		return types.DividendForecast{}, err
+	}
+
+	prices := loaders.AllPrices(db)
+	holdings, err := BuildHoldingRows(p, prices, nil)
+	if err != nil {
+		return types.DividendForecast{}, err
+	}
+	if len(holdings) == 0 {
+		return ForecastDividends(nil, nil, prices, now, nil)
+	}
+
+	// without account rates every account resolves the same rate, otherwise the holdings of each account are needed
+	// to weight them
+	accountsData := map[string]types.AnalyzedPortfolio{accountIds[0]: p}
+	if len(accountIds) > 1 && len(taxes.Accounts) > 0 {
+		accountsData = make(map[string]types.AnalyzedPortfolio, len(accountIds))
+		for _, id := range accountIds {
+			accountData, err := LoadAndAnalyzeAccountsIn(db, []string{id}, currency)
+			if err != nil {
+				return types.DividendForecast{}, err
+			}
+			accountsData[id] = accountData
+		}
+	}
+	taxRates := LoadDividendTax(db, taxes).HoldingRates(accountsData)
+
+	symbols := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		symbols = append(symbols, h.Symbol)
+	}
+	dividends, err := loaders.DividendsAndSplits(db, symbols, now.AddDate(-dividendHistoryYears, 0, 0))
+	if err != nil {
+		return types.DividendForecast{}, err
+	}
+
+	return ForecastDividends(holdings, *dividends, prices, now, taxRates)
+}
+
+// ForecastDividends projects the dividends of holdings over the 12 months from now. Each holding keeps paying its
+// latest dividend per share, every month, quarter, half year or year going by the median interval between its recent
+// dividends in history. Symbols without a dividend in the last 18 months are left out. Dividends are paid in the
+// currency of the symbol, they convert at the ratio between the holding price and the symbol price in prices.
+//
+// Months has an entry for each of the 12 months starting with the month of now, the first one also gets the
+// payments of that month next year before the day of now. Net amounts are after the rate of each holding in taxRates,
+// keyed by lowercase symbol as DividendTax.HoldingRates returns them. It returns types.ErrMoneyOverflow when an amount
+// or a sum doesn't fit.
+func ForecastDividends(holdings []HoldingRow, history []types.Transaction, prices map[string]types.SymbolPrice, now time.Time, taxRates map[string]float64) (types.DividendForecast, error) {
+	var acc moneyAccumulator
+	from := truncateDay(now)
+	to := from.AddDate(1, 0, 0)
+	forecast := types.DividendForecast{From: from, Months: make([]types.DividendMonth, 12)}
+	start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	for i := range forecast.Months {
+		forecast.Months[i].Month = start.AddDate(0, i, 0)
+	}
+
+	dividends := make(map[string][]time.Time)
+	latest := make(map[string]types.Transaction)
+	for _, t := range history {
+		if t.Type != types.TransactionTypeDividend || t.Pps <= 0 {
+			continue
+		}
+		key := strings.ToLower(t.Symbol)
+		dividends[key] = append(dividends[key], t.AsDate())
+		if last, ok := latest[key]; !ok || t.AsDate().After(last.AsDate()) {
+			latest[key] = t
+		}
+	}
+
+	for _, h := range holdings {
+		key := strings.ToLower(h.Symbol)
+		last, ok := latest[key]
+		if !ok || h.Quantity <= 0 || last.AsDate().Before(from.AddDate(0, -18, 0)) {
+			continue
+		}
+
+		rate := 1.0
+		if local := prices[key].AdjPrice; local > 0 && h.Price > 0 {
+			rate = float64(h.Price) / float64(local)
+		}
+		amount := acc.money(types.RoundMoney(float64(acc.value(h.Quantity, last.Pps)) * rate))
+		net := config.DividendsAfterTax(amount, taxRates[key])
+
+		frequency, months := dividendFrequency(dividends[key])
+		holding := types.DividendHolding{Symbol: h.Symbol, Quantity: h.Quantity, Frequency: frequency, PerShare: last.Pps}
+		for i := 1; ; i++ {
+			date := last.AsDate().AddDate(0, months*i, 0)
+			if !date.Before(to) {
+				break
+			}
+			if date.Before(from) {
+				continue
+			}
+
+			if holding.NextDate.IsZero() {
+				holding.NextDate = date
+			}
+			acc.add(&holding.Annual, amount)
+			acc.add(&holding.Net, net)
+			forecast.Payments = append(forecast.Payments, types.DividendPayment{
+				Date:     date,
+				Symbol:   h.Symbol,
+				Quantity: h.Quantity,
+				PerShare: last.Pps,
+				Amount:   amount,
+				Net:      net,
+			})
+
+			month := (date.Year()-start.Year())*12 + int(date.Month()) - int(start.Month())
+			acc.add(&forecast.Months[month%12].Amount, amount)
+			acc.add(&forecast.Months[month%12].Net, net)
+		}
+		if acc.err != nil {
+			return types.DividendForecast{}, acc.err
+		}
+
+		if holding.Annual == 0 {
+			continue
+		}
+		if h.CostBasis > 0 {
+			holding.YieldOnCost = float32(float64(holding.Annual) / float64(h.CostBasis))
+		}
+		if h.MarketValue > 0 {
+			holding.Yield = float32(float64(holding.Annual) / float64(h.MarketValue))
+		}
+		acc.add(&forecast.Total, holding.Annual)
+		acc.add(&forecast.Net, holding.Net)
+		forecast.Holdings = append(forecast.Holdings, holding)
+	}
+	if acc.err != nil {
+		return types.DividendForecast{}, acc.err
+	}
+
+	sort.SliceStable(forecast.Holdings, func(i, j int) bool {
+		if forecast.Holdings[i].Annual == forecast.Holdings[j].Annual {
+			return forecast.Holdings[i].Symbol < forecast.Holdings[j].Symbol
+		}
+		return forecast.Holdings[i].Annual > forecast.Holdings[j].Annual
+	})
+	sort.SliceStable(forecast.Payments, func(i, j int) bool {
+		if forecast.Payments[i].Date.Equal(forecast.Payments[j].Date) {
+			return forecast.Payments[i].Symbol < forecast.Payments[j].Symbol
+		}
+		return forecast.Payments[i].Date.Before(forecast.Payments[j].Date)
+	})
+
+	return forecast, nil
+}
+
+// dividendFrequency guesses how often a symbol pays from the median interval between its latest dividends, a single
+// dividend is taken as annual. It returns the frequency and the months between payments.
+func dividendFrequency(dates []time.Time) (types.DividendFrequency, int) {
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	if len(dates) > 5 {
+		dates = dates[len(dates)-5:]
+	}
+	if len(dates) < 2 {
+		return types.DividendAnnual, 12
+	}
+
+	intervals := make([]float64, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		intervals = append(intervals, dates[i].Sub(dates[i-1]).Hours()/24)
+	}
+	sort.Float64s(intervals)
+	median := intervals[len(intervals)/2]
+	if len(intervals)%2 == 0 {
+		median = (intervals[len(intervals)/2-1] + median) / 2
+	}
+
+	switch {
+	case median <= 45:
+		return types.DividendMonthly, 1
+	case median <= 135:
+		return types.DividendQuarterly, 3
+	case median <= 270:
+		return types.DividendSemiAnnual, 6
+	}
+	return types.DividendAnnual, 12
+}
@@ -0,0 +1,80 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestForecastDividends(t *testing.T) {
+	holdings := []HoldingRow{
+		{Symbol: "AAPL", Quantity: types.NewQuantity(10), Price: 20000, MarketValue: 200000, CostBasis: 100000},
+		{Symbol: "TEVA", Quantity: types.NewQuantity(5), Price: 10000, MarketValue: 50000, CostBasis: 50000},
+		{Symbol: "OLD", Quantity: types.NewQuantity(5), Price: 100, MarketValue: 500},
+	}
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 20000},
+		"teva": {Symbol: "TEVA", AdjPrice: 40000},
+	}
+	history := []types.Transaction{
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 20, Date: utils.StringToDate("2025-11-10")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 20, Date: utils.StringToDate("2026-02-10")},
+		{Symbol: "AAPL", Type: types.TransactionTypeSplit, Pps: 2, Date: utils.StringToDate("2026-03-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 25, Date: utils.StringToDate("2026-08-10")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 20, Date: utils.StringToDate("2026-05-10")},
+		{Symbol: "TEVA", Type: types.TransactionTypeDividend, Pps: 100, Date: utils.StringToDate("2026-10-03")},
+		{Symbol: "OLD", Type: types.TransactionTypeDividend, Pps: 100, Date: utils.StringToDate("2024-12-01")},
+	}
+
+	forecast, err := ForecastDividends(holdings, history, prices, utils.StringToDate("2026-10-17"), map[string]float64{"aapl": 0.25, "teva": 0.25})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// four quarterly AAPL dividends at the latest 25 and a yearly TEVA dividend converted at a quarter of its price
+	if forecast.Total != 1125 || forecast.Net != 846 {
+		t.Fatalf("Expected total/net 1125/846 but got %d/%d\n", forecast.Total, forecast.Net)
+	}
+
+	if len(forecast.Holdings) != 2 {
+		t.Fatalf("Expected 2 paying holdings but got %v\n", forecast.Holdings)
+	}
+	aapl := forecast.Holdings[0]
+	if aapl.Symbol != "AAPL" || aapl.Frequency != types.DividendQuarterly || aapl.Annual != 1000 || aapl.NextDate != utils.StringToDate("2026-11-10") {
+		t.Fatalf("Expected AAPL to pay 1000 quarterly from 2026-11-10 but got %v\n", aapl)
+	}
+	if aapl.YieldOnCost != 0.01 || aapl.Yield != 0.005 {
+		t.Fatalf("Expected AAPL yield on cost/yield 0.01/0.005 but got %f/%f\n", aapl.YieldOnCost, aapl.Yield)
+	}
+	if teva := forecast.Holdings[1]; teva.Frequency != types.DividendAnnual || teva.Annual != 125 {
+		t.Fatalf("Expected TEVA to pay 125 yearly but got %v\n", teva)
+	}
+
+	if len(forecast.Payments) != 5 || forecast.Payments[4].Date != utils.StringToDate("2027-10-03") {
+		t.Fatalf("Expected 5 payments ending with TEVA on 2027-10-03 but got %v\n", forecast.Payments)
+	}
+
+	// the TEVA dividend of next October lands in the month the forecast starts
+	expected := map[int]types.Money{0: 125, 1: 250, 4: 250, 7: 250, 10: 250}
+	for i, month := range forecast.Months {
+		if month.Amount != expected[i] {
+			t.Fatalf("Expected %s to be %d but got %d\n", month.Month.Format("2006-01"), expected[i], month.Amount)
+		}
+	}
+}
+
+func TestForecastDividendsOverflow(t *testing.T) {
+	// a quarterly payment fits but not the four of them in the annual total
+	holdings := []HoldingRow{{Symbol: "AAPL", Quantity: types.NewQuantity(1), Price: 100, MarketValue: 100}}
+	history := []types.Transaction{
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: math.MaxInt64 / 3, Date: utils.StringToDate("2026-04-10")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: math.MaxInt64 / 3, Date: utils.StringToDate("2026-07-10")},
+	}
+
+	_, err := ForecastDividends(holdings, history, nil, utils.StringToDate("2026-10-17"), nil)
+	if !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the forecast to overflow but got %v\n", err)
+	}
+}
@@ -0,0 +1,66 @@
+package portfolio
+
+import (
+	"strings"
+	"tracker/types"
+)
+
+// buildSymbolGains splits the gain of every symbol into realized (closed lots), unrealized (open lots at the
+// current price) and dividend income
+func buildSymbolGains(openLots []types.Lot, closedLots []types.ClosedLot, dividends map[string]types.Money, pricesTable map[string]types.SymbolPrice) (map[string]types.SymbolGain, error) {
+	gains := make(map[string]types.SymbolGain)
+	var acc moneyAccumulator
+
+	get := func(symbol string) types.SymbolGain {
+		key := strings.ToLower(symbol)
+		g, ok := gains[key]
+		if !ok {
+			g.Symbol = symbol
+		}
+		return g
+	}
+
+	for _, l := range openLots {
+		key := strings.ToLower(l.Symbol)
+		g := get(l.Symbol)
+		value := acc.value(l.Quantity, pricesTable[key].AdjPrice)
+		acc.add(&g.CostBasis, l.CostBasis)
+		acc.add(&g.MarketValue, value)
+		unrealized, err := value.Sub(l.CostBasis)
+		if err != nil {
+			return gains, err
+		}
+		acc.add(&g.Unrealized, unrealized)
+		gains[key] = g
+	}
+
+	for _, l := range closedLots {
+		key := strings.ToLower(l.Symbol)
+		g := get(l.Symbol)
+		gain, err := l.Gain()
+		if err != nil {
+			return gains, err
+		}
+		acc.add(&g.Realized, gain)
+		gains[key] = g
+	}
+
+	for symbol, amount := range dividends {
+		if amount == 0 {
+			continue
+		}
+		key := strings.ToLower(symbol)
+		g := get(symbol)
+		acc.add(&g.Dividends, amount)
+		gains[key] = g
+	}
+
+	for key, g := range gains {
+		acc.add(&g.Total, g.Realized)
+		acc.add(&g.Total, g.Unrealized)
+		acc.add(&g.Total, g.Dividends)
+		gains[key] = g
+	}
+
+	return gains, acc.err
+}
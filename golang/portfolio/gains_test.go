@@ -0,0 +1,48 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestRealizedUnrealizedGains(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "MSFT", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(5), Pps: 200, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 2, Date: utils.StringToDate("2023-03-01")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(4), Pps: 150, Date: utils.StringToDate("2023-06-01")},
+	}
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 120},
+		"msft": {Symbol: "MSFT", AdjPrice: 180},
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, priceTable)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	aapl := portfolio.SymbolGains["aapl"]
+	if aapl.Realized != 4*50 || aapl.Unrealized != 6*20 || aapl.Dividends != 10*2 || aapl.Total != 340 {
+		t.Fatalf("Unexpected AAPL gains %+v\n", aapl)
+	}
+
+	msft := portfolio.SymbolGains["msft"]
+	if msft.Realized != 0 || msft.Unrealized != -5*20 || msft.MarketValue != 5*180 {
+		t.Fatalf("Unexpected MSFT gains %+v\n", msft)
+	}
+
+	if portfolio.RealizedGain != 200 {
+		t.Fatalf("Expected RealizedGain to be 200 but got %d\n", portfolio.RealizedGain)
+	}
+
+	if portfolio.UnrealizedGain != 20 {
+		t.Fatalf("Expected UnrealizedGain to be 20 but got %d\n", portfolio.UnrealizedGain)
+	}
+
+	// realized + unrealized + dividends should explain the whole gain when every position is priced
+	if portfolio.RealizedGain+portfolio.UnrealizedGain+portfolio.TotalDividends != portfolio.GainValue {
+		t.Fatalf("Expected the gain breakdown to add up to GainValue %d\n", portfolio.GainValue)
+	}
+}
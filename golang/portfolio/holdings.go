@@ -0,0 +1,86 @@
+package portfolio
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"tracker/types"
+)
+
+type HoldingRow struct {
+	Symbol      string
+	Info        types.SymbolInfo
+	Quantity    types.Quantity
+	Price       types.Money
+	PriceDate   time.Time
+	MarketValue types.Money
+	CostBasis   types.Money
+	Unrealized  types.Money
+	Allocation  float32
+	Dividends   types.Money
+}
+
+// BuildHoldingRows returns a row per currently held symbol, sorted by market value. Prices come from the portfolio
+// when it has them, in the currency it was analyzed in, and from prices otherwise. Rows carry the metadata symbols
+// has for them. It returns types.ErrMoneyOverflow when a value or their total doesn't fit.
+func BuildHoldingRows(p types.AnalyzedPortfolio, prices map[string]types.SymbolPrice, symbols map[string]types.SymbolInfo) ([]HoldingRow, error) {
+	rows := make([]HoldingRow, 0, len(p.SymbolsCount))
+	var acc moneyAccumulator
+	var totalValue types.Money
+
+	for symbol, count := range p.SymbolsCount {
+		if count <= 0 {
+			continue
+		}
+
+		key := strings.ToLower(symbol)
+		price, ok := p.Prices[key]
+		if !ok {
+			price = prices[key]
+		}
+		gain := p.SymbolGains[key]
+		displaySymbol := gain.Symbol
+		if displaySymbol == "" {
+			displaySymbol = strings.ToUpper(symbol)
+		}
+
+		value := acc.value(count, price.AdjPrice)
+		acc.add(&totalValue, value)
+		unrealized := value
+		acc.add(&unrealized, -gain.CostBasis)
+		info := symbols[key]
+		if info.Symbol == "" {
+			info.Symbol = displaySymbol
+		}
+
+		rows = append(rows, HoldingRow{
+			Symbol:      displaySymbol,
+			Info:        info,
+			Quantity:    count,
+			Price:       price.AdjPrice,
+			PriceDate:   price.CreatedAt,
+			MarketValue: value,
+			CostBasis:   gain.CostBasis,
+			Unrealized:  unrealized,
+			Dividends:   gain.Dividends,
+		})
+	}
+	if acc.err != nil {
+		return nil, acc.err
+	}
+
+	if totalValue != 0 {
+		for i := range rows {
+			rows[i].Allocation = float32(float64(rows[i].MarketValue) / float64(totalValue))
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].MarketValue == rows[j].MarketValue {
+			return rows[i].Symbol < rows[j].Symbol
+		}
+		return rows[i].MarketValue > rows[j].MarketValue
+	})
+
+	return rows, nil
+}
@@ -0,0 +1,81 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestBuildHoldingRows(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "MSFT", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "MSFT", Type: types.TransactionTypeDividend, Quantity: types.NewQuantity(1), Pps: 50, Date: utils.StringToDate("2023-02-01")},
+		{Id: "s1", AccountId: "1", Symbol: "MSFT", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 200, Date: utils.StringToDate("2023-03-01")},
+		{Id: "b3", AccountId: "1", Symbol: "GOOG", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(5), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Id: "s2", AccountId: "1", Symbol: "GOOG", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 100, Date: utils.StringToDate("2023-04-01")},
+	}
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 300, CreatedAt: utils.StringToDate("2024-01-02")},
+		"msft": {Symbol: "MSFT", AdjPrice: 200, CreatedAt: utils.StringToDate("2024-01-03")},
+		"goog": {Symbol: "GOOG", AdjPrice: 100},
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, priceTable)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	rows, err := BuildHoldingRows(portfolio, priceTable, nil)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 holdings but got %d\n", len(rows))
+	}
+
+	aapl, msft := rows[0], rows[1]
+	if aapl.Symbol != "AAPL" || msft.Symbol != "MSFT" {
+		t.Fatalf("Expected holdings sorted by value to be AAPL, MSFT but got %s, %s\n", aapl.Symbol, msft.Symbol)
+	}
+
+	if aapl.MarketValue != 3000 || aapl.CostBasis != 1000 || aapl.Unrealized != 2000 {
+		t.Fatalf("Expected AAPL value/basis/unrealized 3000/1000/2000 but got %d/%d/%d\n", aapl.MarketValue, aapl.CostBasis, aapl.Unrealized)
+	}
+
+	if msft.Quantity != types.NewQuantity(5) || msft.CostBasis != 500 || msft.Dividends != 500 {
+		t.Fatalf("Expected MSFT shares/basis/dividends 5/500/500 but got %s/%d/%d\n", msft.Quantity, msft.CostBasis, msft.Dividends)
+	}
+
+	if !msft.PriceDate.Equal(utils.StringToDate("2024-01-03")) {
+		t.Fatalf("Expected MSFT price date to be 2024-01-03 but got %s\n", msft.PriceDate)
+	}
+
+	if aapl.Allocation != 0.75 || msft.Allocation != 0.25 {
+		t.Fatalf("Expected allocation 0.75/0.25 but got %f/%f\n", aapl.Allocation, msft.Allocation)
+	}
+}
+
+func TestBuildHoldingRowsOverflow(t *testing.T) {
+	// each holding fits on its own but not their total
+	p := types.AnalyzedPortfolio{SymbolsCount: map[string]types.Quantity{"aapl": types.NewQuantity(1), "msft": types.NewQuantity(1)}}
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: math.MaxInt64 / 3 * 2},
+		"msft": {Symbol: "MSFT", AdjPrice: math.MaxInt64 / 3 * 2},
+	}
+
+	if _, err := BuildHoldingRows(p, priceTable, nil); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the total value to overflow but got %v\n", err)
+	}
+
+	// a negative basis larger than the value can't be subtracted
+	p = types.AnalyzedPortfolio{
+		SymbolsCount: map[string]types.Quantity{"aapl": types.NewQuantity(1)},
+		SymbolGains:  map[string]types.SymbolGain{"aapl": {Symbol: "AAPL", CostBasis: math.MinInt64 + 1}},
+	}
+	if _, err := BuildHoldingRows(p, priceTable, nil); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the unrealized gain to overflow but got %v\n", err)
+	}
+}
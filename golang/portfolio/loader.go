@@ -3,11 +3,19 @@ package portfolio
 import (
 	"database/sql"
 	"slices"
+	"strings"
+	"time"
+	"tracker/config"
 	"tracker/loaders"
 	"tracker/types"
 )
 
 func LoadAndAnalyze(db *sql.DB, account types.Account) (types.AnalyzedPortfolio, error) {
+	return LoadAndAnalyzeIn(db, account, types.CurrencyUSD)
+}
+
+// LoadAndAnalyzeIn analyzes the account with every amount in currency, flows convert at the rate of their day
+func LoadAndAnalyzeIn(db *sql.DB, account types.Account, currency string) (types.AnalyzedPortfolio, error) {
 	var transactions *[]types.Transaction
 
 	if account.Id != "" {
@@ -16,50 +24,247 @@ func LoadAndAnalyze(db *sql.DB, account types.Account) (types.AnalyzedPortfolio,
 		transactions, _ = loaders.AllTransactions(db)
 	}
 
-	return analyzeTransactionSet(db, transactions)
+	return analyzeTransactionSet(db, transactions, currency)
 }
 
 func LoadAndAnalyzeAccounts(db *sql.DB, accountIds []string) (types.AnalyzedPortfolio, error) {
+	return LoadAndAnalyzeAccountsIn(db, accountIds, types.CurrencyUSD)
+}
+
+// LoadAndAnalyzeAccountsIn analyzes the given accounts together with every amount in currency
+func LoadAndAnalyzeAccountsIn(db *sql.DB, accountIds []string, currency string) (types.AnalyzedPortfolio, error) {
 	if len(accountIds) == 0 {
 		return types.AnalyzedPortfolio{}, nil
 	}
 
 	transactions, _ := loaders.AccountsTransactions(db, accountIds)
-	return analyzeTransactionSet(db, transactions)
+	return analyzeTransactionSet(db, transactions, currency)
+}
+
+func LoadTimeSeries(db *sql.DB, account types.Account) ([]types.PortfolioSnapshot, error) {
+	var transactions *[]types.Transaction
+
+	if account.Id != "" {
+		transactions, _ = loaders.AccountTransactions(db, account.Id)
+	} else {
+		transactions, _ = loaders.AllTransactions(db)
+	}
+
+	return timeSeriesForTransactionSet(db, transactions)
+}
+
+func LoadAccountsTimeSeries(db *sql.DB, accountIds []string) ([]types.PortfolioSnapshot, error) {
+	if len(accountIds) == 0 {
+		return nil, nil
+	}
+
+	transactions, _ := loaders.AccountsTransactions(db, accountIds)
+	return timeSeriesForTransactionSet(db, transactions)
 }
 
-func analyzeTransactionSet(db *sql.DB, transactions *[]types.Transaction) (types.AnalyzedPortfolio, error) {
+// LoadTransfer builds the pair of rows moving quantity shares of symbol from one account to another,
+// the cost basis comes from the stored history of the sending account
+func LoadTransfer(db *sql.DB, fromAccountId, toAccountId, symbol string, quantity types.Quantity, date time.Time) (types.Transaction, types.Transaction, error) {
+	transactions, err := loaders.AccountTransactions(db, fromAccountId)
+	if err != nil {
+		return types.Transaction{}, types.Transaction{}, err
+	}
+
+	var source []types.Transaction
+	if len(*transactions) > 0 {
+		source = withMarketEvents(db, transactions)
+	}
+
+	return NewTransfer(source, fromAccountId, toAccountId, symbol, quantity, date, analyzeOptions(db))
+}
+
+func analyzeTransactionSet(db *sql.DB, transactions *[]types.Transaction, currency string) (types.AnalyzedPortfolio, error) {
 	if len(*transactions) == 0 {
 		return types.AnalyzedPortfolio{}, nil
 	}
 
-	symbols := loaders.SymbolsFromTransactions(transactions)
+	allTransactions := withMarketEvents(db, transactions)
+	prices := loaders.AllPrices(db)
+	opts := analyzeOptions(db)
+	opts.ReportingCurrency = currency
 
-	firstTr := (*transactions)[0]
-	dividends, _ := loaders.DividendsAndSplits(db, symbols, firstTr.Date)
+	data, err := AnalyzeTransactionsWithOptions(allTransactions, prices, opts)
+	if err != nil {
+		return types.AnalyzedPortfolio{}, err
+	}
 
-	allTransactions := append(*dividends, *transactions...)
-	slices.SortFunc(allTransactions, func(a types.Transaction, b types.Transaction) int {
-		ad := a.AsDate()
-		bd := b.AsDate()
+	// the price history is optional, without it the portfolio is still analyzed against the latest prices
+	now := time.Now()
+	if series, err := buildTimeSeries(db, allTransactions, prices, now, opts); err == nil {
+		data.TimeWeightedReturns = TimeWeightedReturnsFromSeries(series, now)
+	}
 
-		if ad.After(bd) {
-			return 1
+	return data, nil
+}
+
+func timeSeriesForTransactionSet(db *sql.DB, transactions *[]types.Transaction) ([]types.PortfolioSnapshot, error) {
+	if transactions == nil || len(*transactions) == 0 {
+		return nil, nil
+	}
+
+	allTransactions := withMarketEvents(db, transactions)
+	return buildTimeSeries(db, allTransactions, loaders.AllPrices(db), time.Now(), analyzeOptions(db))
+}
+
+// analyzeOptions returns the options with the configured lot method, the stored exchange rates and the stored lot
+// selections. Without rates every currency converts at 1.
+func analyzeOptions(db *sql.DB) AnalyzeOptions {
+	opts := DefaultAnalyzeOptions()
+	opts.LotMethod = config.Load().LotMethod
+	if rates, err := loaders.FxRates(db); err == nil {
+		opts.Rates = rates
+	}
+	if selections, err := loaders.LotSelections(db); err == nil {
+		opts.LotSelections = selections
+	}
+	return opts
+}
+
+// buildTimeSeries values allTransactions against the stored price history
+func buildTimeSeries(db *sql.DB, allTransactions []types.Transaction, prices map[string]types.SymbolPrice, to time.Time, opts AnalyzeOptions) ([]types.PortfolioSnapshot, error) {
+	symbols := loaders.SymbolsFromTransactions(&allTransactions)
+	history, err := priceHistory(db, symbols, allTransactions[0].AsDate(), prices)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTimeSeriesWithOptions(allTransactions, history, to, opts)
+}
+
+// priceHistory loads the stored daily prices of symbols, the latest prices fill in for symbols whose history
+// stops before the day they were last updated
+func priceHistory(db *sql.DB, symbols []string, from time.Time, prices map[string]types.SymbolPrice) (map[string][]types.SymbolPrice, error) {
+	history, err := loaders.PriceHistory(db, symbols, from)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, symbol := range symbols {
+		key := strings.ToLower(symbol)
+		p, ok := prices[key]
+		if !ok || p.CreatedAt.IsZero() {
+			continue
 		}
 
-		if bd.After(ad) {
-			return -1
+		symbolHistory := history[key]
+		if len(symbolHistory) == 0 || symbolHistory[len(symbolHistory)-1].Date.Before(truncateDay(p.CreatedAt)) {
+			p.Date = truncateDay(p.CreatedAt)
+			history[key] = append(symbolHistory, p)
 		}
+	}
 
-		return 0
-	})
+	return history, nil
+}
+
+// LoadBenchmarkComparison simulates investing the cash flows of the given accounts in the benchmark symbol, both
+// valued in currency at the rates of their days
+func LoadBenchmarkComparison(db *sql.DB, accountIds []string, symbol, currency string) (types.BenchmarkComparison, error) {
+	if symbol == "" || len(accountIds) == 0 {
+		return types.BenchmarkComparison{Symbol: symbol}, nil
+	}
+
+	transactions, _ := loaders.AccountsTransactions(db, accountIds)
+	if transactions == nil || len(*transactions) == 0 {
+		return types.BenchmarkComparison{Symbol: symbol}, nil
+	}
 
+	now := time.Now()
+	allTransactions := withMarketEvents(db, transactions)
 	prices := loaders.AllPrices(db)
+	opts := analyzeOptions(db)
+	opts.ReportingCurrency = currency
+	series, err := buildTimeSeries(db, allTransactions, prices, now, opts)
+	if err != nil {
+		return types.BenchmarkComparison{Symbol: symbol}, err
+	}
 
-	data, err := AnalyzeTransactions(allTransactions, prices)
+	from := allTransactions[0].AsDate()
+	history, err := priceHistory(db, []string{symbol}, from, prices)
 	if err != nil {
-		return types.AnalyzedPortfolio{}, err
+		return types.BenchmarkComparison{Symbol: symbol}, err
 	}
 
-	return data, nil
+	events, err := loaders.DividendsAndSplits(db, []string{symbol}, from)
+	if err != nil {
+		return types.BenchmarkComparison{Symbol: symbol}, err
+	}
+	sortTransactions(*events)
+
+	// the benchmark is priced in its own currency, e.g. an index of another market
+	symbols, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		return types.BenchmarkComparison{Symbol: symbol}, err
+	}
+	benchmarkCurrency := symbols[strings.ToLower(symbol)].Currency
+
+	return SimulateBenchmarkWithOptions(series, symbol, benchmarkCurrency, history[strings.ToLower(symbol)], *events, now, opts)
+}
+
+// LoadRiskMetrics computes the risk metrics of the combined daily valuation of the given accounts
+func LoadRiskMetrics(db *sql.DB, accountIds []string, riskFreeRate float64) (types.RiskMetrics, error) {
+	if len(accountIds) == 0 {
+		return types.RiskMetrics{}, nil
+	}
+
+	series, err := LoadAccountsTimeSeries(db, accountIds)
+	if err != nil {
+		return types.RiskMetrics{}, err
+	}
+
+	return RiskMetricsFromSeries(series, riskFreeRate), nil
+}
+
+// withMarketEvents merges the dividends, splits and corporate actions of the traded symbols into transactions,
+// sorted by date. Symbols are followed through their corporate actions so the dividends of what they became are
+// included as well.
+func withMarketEvents(db *sql.DB, transactions *[]types.Transaction) []types.Transaction {
+	symbols := loaders.SymbolsFromTransactions(transactions)
+
+	firstTr := (*transactions)[0]
+	actions, _ := loaders.CorporateActions(db, symbols)
+	actionRows := corporateActionTransactions(actions, firstTr.Date)
+	symbols = append(symbols, loaders.SymbolsFromTransactions(&actionRows)...)
+
+	dividends, _ := loaders.DividendsAndSplits(db, symbols, firstTr.Date)
+
+	allTransactions := append(*dividends, actionRows...)
+	allTransactions = append(allTransactions, *transactions...)
+	sortTransactions(allTransactions)
+
+	return allTransactions
+}
+
+// sortTransactions sorts transactions by date. Rows of the same day are ordered so the lots they need exist: splits
+// and corporate actions first, then buys and incoming transfers, then sells and outgoing transfers, then dividends
+// and cash. Rows of the same kind keep their order.
+func sortTransactions(transactions []types.Transaction) {
+	slices.SortStableFunc(transactions, func(a types.Transaction, b types.Transaction) int {
+		if c := a.AsDate().Compare(b.AsDate()); c != 0 {
+			return c
+		}
+		return sameDayRank(a) - sameDayRank(b)
+	})
+}
+
+func sameDayRank(t types.Transaction) int {
+	switch t.Type {
+	case types.TransactionTypeSplit, types.TransactionTypeCorporateAction:
+		return 0
+	case types.TransactionTypeBuy:
+		return 1
+	case types.TransactionTypeTransfer:
+		if t.Quantity > 0 {
+			return 1
+		}
+		return 2
+	case types.TransactionTypeSell:
+		return 2
+	default:
+		return 3
+	}
 }
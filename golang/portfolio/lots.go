@@ -0,0 +1,307 @@
+package portfolio
+
+import (
+	"slices"
+	"strings"
+	"tracker/types"
+)
+
+type AnalyzeOptions struct {
+	LotMethod types.LotMethod
+	// LotSelections maps a sell transaction id to the lots it closes, overriding LotMethod for that sell
+	LotSelections map[string][]types.LotSelection
+	// Rates convert the transactions and prices into ReportingCurrency, USD when empty
+	Rates             types.FxRates
+	ReportingCurrency string
+}
+
+func DefaultAnalyzeOptions() AnalyzeOptions {
+	return AnalyzeOptions{LotMethod: types.LotMethodFIFO}
+}
+
+type lotKey struct {
+	accountId string
+	symbol    string
+}
+
+type lotBook struct {
+	method     types.LotMethod
+	selections map[string][]types.LotSelection
+	open       map[lotKey][]*types.Lot
+	closed     []types.ClosedLot
+	// lots taken out by a transfer whose incoming row wasn't seen yet, and incoming rows waiting for their lots
+	transfersOut map[string][]types.Lot
+	transfersIn  map[string]types.Transaction
+	// err is the first overflow of the lot arithmetic, the book keeps going and the analysis reports it
+	err error
+}
+
+func newLotBook(opts AnalyzeOptions) *lotBook {
+	method := opts.LotMethod
+	if method == "" {
+		method = types.LotMethodFIFO
+	}
+
+	return &lotBook{
+		method:     method,
+		selections: opts.LotSelections,
+		open:       make(map[lotKey][]*types.Lot),
+		closed:     make([]types.ClosedLot, 0),
+
+		transfersOut: make(map[string][]types.Lot),
+		transfersIn:  make(map[string]types.Transaction),
+	}
+}
+
+// apply records t in the lots, paired tells whether both rows of a transfer are in the book. The analysis and new
+// transfers both go through it so they see the same lots.
+func (b *lotBook) apply(t types.Transaction, paired bool) {
+	switch t.Type {
+	case types.TransactionTypeBuy:
+		b.buy(t)
+	case types.TransactionTypeSell:
+		b.sell(t)
+	case types.TransactionTypeTransfer:
+		b.transfer(t, paired)
+	case types.TransactionTypeSplit:
+		b.split(t.Symbol, t.Pps)
+	case types.TransactionTypeCorporateAction:
+		b.corporateAction(*t.Action, t.Id)
+	}
+}
+
+func (b *lotBook) buy(t types.Transaction) {
+	if t.Quantity <= 0 {
+		return
+	}
+
+	key := lotKey{accountId: t.AccountId, symbol: strings.ToLower(t.Symbol)}
+	b.open[key] = append(b.open[key], &types.Lot{
+		Id:        t.Id,
+		AccountId: t.AccountId,
+		Symbol:    t.Symbol,
+		OpenDate:  t.Date,
+		Quantity:  t.Quantity,
+		CostBasis: b.money(t.Cost()),
+	})
+}
+
+// money returns v and keeps err when it is the first overflow of the book
+func (b *lotBook) money(v types.Money, err error) types.Money {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return v
+}
+
+func (b *lotBook) sell(t types.Transaction) {
+	b.consume(t, t.Quantity, func(lot *types.Lot, quantity types.Quantity) types.Quantity {
+		return b.closeLot(lot, quantity, t)
+	})
+}
+
+// consume takes quantity shares out of the open lots of the account and symbol of t, selected lots first and then
+// in the order of the lot method. take removes up to the given quantity from a lot and returns how many it took.
+func (b *lotBook) consume(t types.Transaction, quantity types.Quantity, take func(lot *types.Lot, quantity types.Quantity) types.Quantity) {
+	key := lotKey{accountId: t.AccountId, symbol: strings.ToLower(t.Symbol)}
+	lots := b.open[key]
+	remaining := quantity
+
+	if selections, ok := b.selections[t.Id]; ok {
+		for _, sel := range selections {
+			idx := slices.IndexFunc(lots, func(l *types.Lot) bool { return l.Id == sel.LotId })
+			if idx < 0 || remaining <= 0 {
+				continue
+			}
+			remaining -= take(lots[idx], min(sel.Quantity, remaining))
+		}
+	}
+
+	for _, lot := range b.matchOrder(lots) {
+		if remaining <= 0 {
+			break
+		}
+		remaining -= take(lot, remaining)
+	}
+
+	b.open[key] = slices.DeleteFunc(lots, func(l *types.Lot) bool { return l.Quantity <= 0 })
+}
+
+// transfer moves lots between accounts. When both rows of the transfer are analyzed the lots keep their open date
+// and cost basis, otherwise the incoming row opens a lot at the cost basis it carries and the outgoing row just
+// removes shares. Neither closes a lot so a transfer has no realized gain.
+func (b *lotBook) transfer(t types.Transaction, paired bool) {
+	if !paired {
+		if t.Quantity > 0 {
+			b.buy(t)
+		} else {
+			b.takeLots(t)
+		}
+		return
+	}
+
+	if t.Quantity < 0 {
+		moved := b.takeLots(t)
+		if in, ok := b.transfersIn[t.TransferId]; ok {
+			delete(b.transfersIn, t.TransferId)
+			b.placeLots(in, moved)
+		} else {
+			b.transfersOut[t.TransferId] = moved
+		}
+		return
+	}
+
+	if moved, ok := b.transfersOut[t.TransferId]; ok {
+		delete(b.transfersOut, t.TransferId)
+		b.placeLots(t, moved)
+	} else {
+		b.transfersIn[t.TransferId] = t
+	}
+}
+
+// takeLots removes the shares of an outgoing transfer from its account and returns them as lots
+func (b *lotBook) takeLots(t types.Transaction) []types.Lot {
+	var moved []types.Lot
+	b.consume(t, -t.Quantity, func(lot *types.Lot, quantity types.Quantity) types.Quantity {
+		take := min(quantity, lot.Quantity)
+		if take <= 0 {
+			return 0
+		}
+
+		basis := b.money(lotBasis(lot, take))
+		moved = append(moved, types.Lot{
+			Id:        lot.Id,
+			Symbol:    lot.Symbol,
+			OpenDate:  lot.OpenDate,
+			Quantity:  take,
+			CostBasis: basis,
+		})
+
+		lot.Quantity -= take
+		lot.CostBasis -= basis
+		return take
+	})
+	return moved
+}
+
+// placeLots adds lots moved by a transfer to the account of the incoming row t, ordered by their open date
+func (b *lotBook) placeLots(t types.Transaction, moved []types.Lot) {
+	key := lotKey{accountId: t.AccountId, symbol: strings.ToLower(t.Symbol)}
+	for _, lot := range moved {
+		lot.AccountId = t.AccountId
+		b.open[key] = append(b.open[key], &lot)
+	}
+
+	slices.SortStableFunc(b.open[key], func(a, b *types.Lot) int {
+		return a.OpenDate.Compare(b.OpenDate)
+	})
+}
+
+// matchOrder returns the lots in the order the default method consumes them
+func (b *lotBook) matchOrder(lots []*types.Lot) []*types.Lot {
+	ordered := slices.Clone(lots)
+
+	switch b.method {
+	case types.LotMethodLIFO:
+		slices.Reverse(ordered)
+	case types.LotMethodHighestCost:
+		slices.SortStableFunc(ordered, func(a, b *types.Lot) int {
+			ac := a.AverageCost()
+			bc := b.AverageCost()
+			if ac > bc {
+				return -1
+			}
+			if ac < bc {
+				return 1
+			}
+			return 0
+		})
+	}
+
+	return ordered
+}
+
+// closeLot moves up to quantity shares of lot into the closed lots and returns how many were closed
+func (b *lotBook) closeLot(lot *types.Lot, quantity types.Quantity, sell types.Transaction) types.Quantity {
+	take := min(quantity, lot.Quantity)
+	if take <= 0 {
+		return 0
+	}
+
+	basis := b.money(lotBasis(lot, take))
+
+	b.closed = append(b.closed, types.ClosedLot{
+		LotId:     lot.Id,
+		SellId:    sell.Id,
+		AccountId: lot.AccountId,
+		Symbol:    lot.Symbol,
+		OpenDate:  lot.OpenDate,
+		CloseDate: sell.Date,
+		Quantity:  take,
+		CostBasis: basis,
+		Proceeds:  b.money(proceedsOf(sell, take)),
+	})
+
+	lot.Quantity -= take
+	lot.CostBasis -= basis
+
+	return take
+}
+
+// lotBasis returns the share of the cost basis of lot that take shares carry
+func lotBasis(lot *types.Lot, take types.Quantity) (types.Money, error) {
+	if take >= lot.Quantity {
+		return lot.CostBasis, nil
+	}
+	return lot.CostBasis.MulRatio(int64(take), int64(lot.Quantity))
+}
+
+// proceedsOf returns the proceeds of take shares of sell, the fee is shared between the lots it closes by quantity
+func proceedsOf(sell types.Transaction, take types.Quantity) (types.Money, error) {
+	proceeds, err := sell.Pps.MulQuantity(take)
+	if err != nil || sell.Fee == 0 || sell.Quantity <= 0 {
+		return proceeds, err
+	}
+
+	fee, err := sell.Fee.MulRatio(int64(take), int64(sell.Quantity))
+	if err != nil {
+		return 0, err
+	}
+	return proceeds.Sub(fee)
+}
+
+// split adjusts the quantity of every open lot of symbol by pps/100, the cost basis is unchanged
+func (b *lotBook) split(symbol string, pps types.Money) {
+	symbol = strings.ToLower(symbol)
+	for key, lots := range b.open {
+		if key.symbol != symbol {
+			continue
+		}
+		for _, lot := range lots {
+			lot.Quantity = lot.Quantity.MulRatio(int64(pps), 100)
+		}
+	}
+}
+
+func (b *lotBook) openLots() []types.Lot {
+	lots := make([]types.Lot, 0)
+	for _, keyLots := range b.open {
+		for _, lot := range keyLots {
+			if lot.Quantity > 0 {
+				lots = append(lots, *lot)
+			}
+		}
+	}
+
+	slices.SortStableFunc(lots, func(a, b types.Lot) int {
+		if c := a.OpenDate.Compare(b.OpenDate); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Symbol, b.Symbol); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return lots
+}
@@ -0,0 +1,180 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func lotsTestTransactions() []types.Transaction {
+	return []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Id: "b2", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 300, Date: utils.StringToDate("2023-02-01")},
+		{Id: "b3", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 200, Date: utils.StringToDate("2023-03-01")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(15), Pps: 400, Date: utils.StringToDate("2024-06-01")},
+	}
+}
+
+func TestLotMethods(t *testing.T) {
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 400},
+	}
+
+	testCases := []struct {
+		method       types.LotMethod
+		closedBasis  types.Money
+		openLotIds   []string
+		openQuantity []types.Quantity
+	}{
+		{method: types.LotMethodFIFO, closedBasis: 10*100 + 5*300, openLotIds: []string{"b2", "b3"}, openQuantity: []types.Quantity{types.NewQuantity(5), types.NewQuantity(10)}},
+		{method: types.LotMethodLIFO, closedBasis: 10*200 + 5*300, openLotIds: []string{"b1", "b2"}, openQuantity: []types.Quantity{types.NewQuantity(10), types.NewQuantity(5)}},
+		{method: types.LotMethodHighestCost, closedBasis: 10*300 + 5*200, openLotIds: []string{"b1", "b3"}, openQuantity: []types.Quantity{types.NewQuantity(10), types.NewQuantity(5)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.method), func(t *testing.T) {
+			portfolio, err := AnalyzeTransactionsWithOptions(lotsTestTransactions(), priceTable, AnalyzeOptions{LotMethod: tc.method})
+			if err != nil {
+				t.Fatalf("Error wasn't nil: %v\n", err)
+			}
+
+			var closedBasis, proceeds types.Money
+			for _, cl := range portfolio.ClosedLots {
+				closedBasis += cl.CostBasis
+				proceeds += cl.Proceeds
+			}
+
+			if closedBasis != tc.closedBasis {
+				t.Fatalf("Expected closed cost basis to be %d but got %d\n", tc.closedBasis, closedBasis)
+			}
+
+			if proceeds != 15*400 {
+				t.Fatalf("Expected proceeds to be %d but got %d\n", 15*400, proceeds)
+			}
+
+			if len(portfolio.OpenLots) != len(tc.openLotIds) {
+				t.Fatalf("Expected %d open lots but got %d\n", len(tc.openLotIds), len(portfolio.OpenLots))
+			}
+
+			for i, lot := range portfolio.OpenLots {
+				if lot.Id != tc.openLotIds[i] || lot.Quantity != tc.openQuantity[i] {
+					t.Fatalf("Expected open lot %s(%s) but got %s(%s)\n", tc.openLotIds[i], tc.openQuantity[i], lot.Id, lot.Quantity)
+				}
+			}
+
+			if portfolio.CostBasis("AAPL")+closedBasis != 10*100+10*300+10*200 {
+				t.Fatalf("Expected open and closed cost basis to add up to the total invested but got %d\n", portfolio.CostBasis("AAPL")+closedBasis)
+			}
+		})
+	}
+}
+
+func TestSpecificLotSelection(t *testing.T) {
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 400},
+	}
+
+	opts := DefaultAnalyzeOptions()
+	opts.LotSelections = map[string][]types.LotSelection{
+		"s1": {{LotId: "b3", Quantity: types.NewQuantity(10)}, {LotId: "b1", Quantity: types.NewQuantity(2)}},
+	}
+
+	portfolio, err := AnalyzeTransactionsWithOptions(lotsTestTransactions(), priceTable, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// 12 shares come from the selected lots, the remaining 3 fall back to FIFO on b1
+	expected := map[string]types.Quantity{"b3": types.NewQuantity(10), "b1": types.NewQuantity(5)}
+	closed := make(map[string]types.Quantity)
+	for _, cl := range portfolio.ClosedLots {
+		closed[cl.LotId] += cl.Quantity
+	}
+
+	for id, qty := range expected {
+		if closed[id] != qty {
+			t.Fatalf("Expected %s shares closed from lot %s but got %s\n", qty, id, closed[id])
+		}
+	}
+
+	if len(closed) != len(expected) {
+		t.Fatalf("Expected only lots b1 and b3 to be closed but got %v\n", closed)
+	}
+}
+
+func TestLotsSplitAndHoldingPeriod(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 400, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeSplit, Pps: 400, Date: utils.StringToDate("2023-06-01")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(20), Pps: 150, Date: utils.StringToDate("2024-03-01")},
+	}
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 150},
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, priceTable)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if len(portfolio.OpenLots) != 1 || portfolio.OpenLots[0].Quantity != types.NewQuantity(20) {
+		t.Fatalf("Expected one open lot of 20 shares after the split but got %+v\n", portfolio.OpenLots)
+	}
+
+	if portfolio.AverageCost("aapl") != 100 {
+		t.Fatalf("Expected average cost to be 100 but got %f\n", portfolio.AverageCost("aapl"))
+	}
+
+	if len(portfolio.ClosedLots) != 1 {
+		t.Fatalf("Expected one closed lot but got %d\n", len(portfolio.ClosedLots))
+	}
+
+	cl := portfolio.ClosedLots[0]
+	gain, err := cl.Gain()
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if cl.CostBasis != 2000 || gain != 1000 {
+		t.Fatalf("Expected closed lot cost basis 2000 and gain 1000 but got %d and %d\n", cl.CostBasis, gain)
+	}
+
+	if _, err := (types.ClosedLot{Proceeds: math.MaxInt64, CostBasis: -1}).Gain(); !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected a gain larger than an int64 to overflow but got %v\n", err)
+	}
+
+	if !cl.LongTerm() {
+		t.Fatalf("Expected closed lot held for %d days to be long term\n", cl.HoldingDays())
+	}
+}
+
+func TestSameDaySellBeforeBuy(t *testing.T) {
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 400},
+	}
+
+	// the sell is stored before the buy that funds it on the same day
+	transactions := []types.Transaction{
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 1, Date: utils.StringToDate("2024-06-01")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 300, Date: utils.StringToDate("2024-06-01")},
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-06-01")},
+	}
+	sortTransactions(transactions)
+
+	if transactions[0].Id != "b1" || transactions[1].Id != "s1" || transactions[2].Type != types.TransactionTypeDividend {
+		t.Fatalf("Expected the buy, the sell and then the dividend but got %v\n", transactions)
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, priceTable)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if len(portfolio.ClosedLots) != 1 || portfolio.RealizedGain != 5*(300-100) {
+		t.Fatalf("Expected a realized gain of %d but got %d\n", 5*(300-100), portfolio.RealizedGain)
+	}
+	if len(portfolio.OpenLots) != 1 || portfolio.OpenLots[0].Quantity != types.NewQuantity(5) {
+		t.Fatalf("Expected 5 shares left open but got %v\n", portfolio.OpenLots)
+	}
+}
@@ -0,0 +1,254 @@
+package portfolio
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strings"
+	"tracker/loaders"
+	"tracker/types"
+)
+
+type RebalanceOptions struct {
+	// Tolerance is the drift left alone for targets without a tolerance of their own
+	Tolerance float64
+	// CashOnly only buys underweight targets with the cash, nothing is sold
+	CashOnly bool
+	// NewCash is cash about to be added, it is invested together with the cash balance
+	NewCash types.Money
+}
+
+// weightScale turns target weights into the ratio they take of a total
+const weightScale = 1_000_000
+
+// rebalanceGroup is a target, or a held symbol without one, with the holdings that count toward it
+type rebalanceGroup struct {
+	line     types.RebalanceLine
+	holdings []HoldingRow
+	price    types.Money
+}
+
+// LoadRebalancePlan plans the rebalancing of the accounts, together, toward the targets of tag. Values are in
+// currency.
+func LoadRebalancePlan(db *sql.DB, accountIds []string, tag string, currency string, opts RebalanceOptions) (types.RebalancePlan, error) {
+	targets, err := loaders.TargetAllocations(db, tag)
+	if err != nil {
+		return types.RebalancePlan{}, err
+	}
+
+	p, err := LoadAndAnalyzeAccountsIn(db, accountIds, currency)
+	if err != nil {
+		return types.RebalancePlan{}, err
+	}
+
+	prices := loaders.AllPrices(db)
+	symbols, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		symbols = map[string]types.SymbolInfo{}
+	}
+
+	// symbols with a target that aren't held yet are bought at the price in the currency of the portfolio
+	for key, price := range p.Prices {
+		prices[key] = price
+	}
+
+	holdings, err := BuildHoldingRows(p, prices, symbols)
+	if err != nil {
+		return types.RebalancePlan{}, err
+	}
+	plan, err := PlanRebalance(holdings, prices, p.CashBalance, targets, opts)
+	if err != nil {
+		return types.RebalancePlan{}, err
+	}
+	plan.Tag = tag
+	return plan, nil
+}
+
+// PlanRebalance compares the holdings and cash with the targets and suggests the trades that bring the targets that
+// drifted out of their tolerance back to their weight. Sells pay for the buys, and when there isn't enough to buy
+// everything the buys are scaled down evenly. With CashOnly nothing is sold, the cash is split between the
+// underweight targets by how far they are below their weight. Trades are in whole shares, prices are looked up in
+// prices for targets that aren't held.
+func PlanRebalance(holdings []HoldingRow, prices map[string]types.SymbolPrice, cash types.Money, targets []types.TargetAllocation, opts RebalanceOptions) (types.RebalancePlan, error) {
+	var acc moneyAccumulator
+	cash = max(cash, 0)
+	acc.add(&cash, max(opts.NewCash, 0))
+	plan := types.RebalancePlan{CashOnly: opts.CashOnly, Cash: cash, Total: cash}
+
+	groups := make([]*rebalanceGroup, 0, len(targets))
+	bySymbol := make(map[string]*rebalanceGroup)
+	byClass := make(map[string]*rebalanceGroup)
+	for _, t := range targets {
+		tolerance := t.Tolerance
+		if tolerance <= 0 {
+			tolerance = float32(opts.Tolerance)
+		}
+		g := &rebalanceGroup{line: types.RebalanceLine{
+			Kind:         t.Kind,
+			Key:          t.Key,
+			Targeted:     true,
+			TargetWeight: t.Weight,
+			Tolerance:    tolerance,
+		}}
+		if t.Kind == types.TargetSymbol {
+			g.line.Key = strings.ToUpper(t.Key)
+			g.price = prices[strings.ToLower(t.Key)].AdjPrice
+			bySymbol[strings.ToLower(t.Key)] = g
+		} else {
+			byClass[strings.ToLower(t.Key)] = g
+		}
+		groups = append(groups, g)
+		plan.TargetTotal += t.Weight
+	}
+
+	for _, h := range holdings {
+		if h.MarketValue <= 0 {
+			continue
+		}
+		acc.add(&plan.Total, h.MarketValue)
+
+		g, ok := bySymbol[strings.ToLower(h.Symbol)]
+		if !ok {
+			g, ok = byClass[strings.ToLower(string(h.Info.AssetClass))]
+		}
+		if !ok {
+			g = &rebalanceGroup{line: types.RebalanceLine{Kind: types.TargetSymbol, Key: h.Symbol}}
+			groups = append(groups, g)
+		}
+		acc.add(&g.line.Value, h.MarketValue)
+		g.holdings = append(g.holdings, h)
+		if g.line.Kind == types.TargetSymbol {
+			g.price = h.Price
+		}
+	}
+
+	if acc.err != nil {
+		return plan, acc.err
+	}
+
+	deltas := make(map[*rebalanceGroup]types.Money, len(groups))
+	for _, g := range groups {
+		if plan.Total != 0 {
+			g.line.Weight = float32(float64(g.line.Value) / float64(plan.Total))
+		}
+		if !g.line.Targeted {
+			continue
+		}
+		g.line.Drift = g.line.Weight - g.line.TargetWeight
+		g.line.OutOfBand = math.Abs(float64(g.line.Drift)) > float64(g.line.Tolerance)+1e-9
+
+		delta := acc.ratio(plan.Total, int64(math.Round(float64(g.line.TargetWeight)*weightScale)), weightScale)
+		acc.add(&delta, -g.line.Value)
+		if opts.CashOnly {
+			if delta > 0 {
+				deltas[g] = delta
+			}
+		} else if g.line.OutOfBand {
+			deltas[g] = delta
+		}
+	}
+
+	// sells first, what they actually raise in whole shares pays for the buys
+	plan.CashLeft = cash
+	var buys types.Money
+	for _, g := range groups {
+		delta := deltas[g]
+		if delta >= 0 {
+			acc.add(&buys, delta)
+			continue
+		}
+		for _, trade := range g.trades(delta, &acc) {
+			acc.add(&plan.CashLeft, trade.Amount)
+			plan.Trades = append(plan.Trades, trade)
+		}
+	}
+
+	available := plan.CashLeft
+	for _, g := range groups {
+		delta := deltas[g]
+		if delta <= 0 {
+			continue
+		}
+		if buys > available {
+			delta = acc.ratio(delta, int64(available), int64(buys))
+		}
+		if delta <= 0 {
+			continue
+		}
+		for _, trade := range g.trades(delta, &acc) {
+			acc.add(&plan.CashLeft, -trade.Amount)
+			plan.Trades = append(plan.Trades, trade)
+		}
+	}
+	if acc.err != nil {
+		return plan, acc.err
+	}
+
+	for _, g := range groups {
+		plan.Lines = append(plan.Lines, g.line)
+	}
+	sort.SliceStable(plan.Lines, func(i, j int) bool {
+		if plan.Lines[i].Targeted != plan.Lines[j].Targeted {
+			return plan.Lines[i].Targeted
+		}
+		return plan.Lines[i].Value > plan.Lines[j].Value
+	})
+	sort.SliceStable(plan.Trades, func(i, j int) bool {
+		if plan.Trades[i].Type != plan.Trades[j].Type {
+			return plan.Trades[i].Type == types.TransactionTypeSell
+		}
+		return plan.Trades[i].Amount > plan.Trades[j].Amount
+	})
+
+	return plan, nil
+}
+
+// trades splits amount, positive to buy and negative to sell, between the holdings of the group by their value.
+// An asset class without holdings gets a single trade without a symbol.
+func (g *rebalanceGroup) trades(amount types.Money, acc *moneyAccumulator) []types.RebalanceTrade {
+	tradeType := types.TransactionTypeBuy
+	if amount < 0 {
+		tradeType = types.TransactionTypeSell
+		amount = -amount
+	}
+
+	if g.line.Kind == types.TargetSymbol {
+		return wholeShareTrade(g.line.Key, g.line.Key, tradeType, g.price, amount, acc)
+	}
+
+	if len(g.holdings) == 0 || g.line.Value == 0 {
+		if tradeType == types.TransactionTypeSell {
+			return nil
+		}
+		return []types.RebalanceTrade{{Group: g.line.Key, Type: tradeType, Amount: amount}}
+	}
+
+	trades := make([]types.RebalanceTrade, 0, len(g.holdings))
+	for _, h := range g.holdings {
+		share := acc.ratio(amount, int64(h.MarketValue), int64(g.line.Value))
+		trades = append(trades, wholeShareTrade(h.Symbol, g.line.Key, tradeType, h.Price, share, acc)...)
+	}
+	return trades
+}
+
+// wholeShareTrade returns the trade of the whole shares amount buys or sells at price, none when it's less than one
+func wholeShareTrade(symbol, group string, tradeType types.TransactionType, price types.Money, amount types.Money, acc *moneyAccumulator) []types.RebalanceTrade {
+	if price <= 0 {
+		return nil
+	}
+
+	shares := int64(amount / price)
+	if shares == 0 {
+		return nil
+	}
+
+	quantity := types.NewQuantity(shares)
+	return []types.RebalanceTrade{{
+		Symbol:   symbol,
+		Group:    group,
+		Type:     tradeType,
+		Quantity: quantity,
+		Price:    price,
+		Amount:   acc.value(quantity, price),
+	}}
+}
@@ -0,0 +1,123 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+)
+
+func rebalanceHoldings() []HoldingRow {
+	return []HoldingRow{
+		{Symbol: "VTI", Info: types.SymbolInfo{Symbol: "VTI", AssetClass: types.AssetClassETF}, Quantity: types.NewQuantity(70), Price: 100, MarketValue: 7000},
+		{Symbol: "BND", Info: types.SymbolInfo{Symbol: "BND", AssetClass: types.AssetClassBond}, Quantity: types.NewQuantity(20), Price: 100, MarketValue: 2000},
+	}
+}
+
+func rebalanceTargets() []types.TargetAllocation {
+	return []types.TargetAllocation{
+		{Tag: "All", Kind: types.TargetSymbol, Key: "vti", Weight: 0.6},
+		{Tag: "All", Kind: types.TargetAssetClass, Key: "Bond", Weight: 0.4},
+	}
+}
+
+func TestPlanRebalance(t *testing.T) {
+	plan, err := PlanRebalance(rebalanceHoldings(), nil, 1000, rebalanceTargets(), RebalanceOptions{Tolerance: 0.05})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if plan.Total != 10000 || plan.Cash != 1000 {
+		t.Fatalf("Expected total/cash 10000/1000 but got %d/%d\n", plan.Total, plan.Cash)
+	}
+
+	if len(plan.Lines) != 2 || plan.Lines[0].Key != "VTI" || !plan.Lines[0].OutOfBand || !plan.Lines[1].OutOfBand {
+		t.Fatalf("Expected VTI and Bond to be out of band but got %v\n", plan.Lines)
+	}
+
+	// selling VTI back to 60% pays for the bonds together with the cash
+	if len(plan.Trades) != 2 {
+		t.Fatalf("Expected 2 trades but got %v\n", plan.Trades)
+	}
+	sell, buy := plan.Trades[0], plan.Trades[1]
+	if sell.Type != types.TransactionTypeSell || sell.Symbol != "VTI" || sell.Quantity != types.NewQuantity(10) {
+		t.Fatalf("Expected to sell 10 VTI but got %v\n", sell)
+	}
+	if buy.Type != types.TransactionTypeBuy || buy.Symbol != "BND" || buy.Group != "Bond" || buy.Quantity != types.NewQuantity(20) {
+		t.Fatalf("Expected to buy 20 BND but got %v\n", buy)
+	}
+	if plan.CashLeft != 0 {
+		t.Fatalf("Expected no cash left but got %d\n", plan.CashLeft)
+	}
+}
+
+func TestPlanRebalanceCashOnly(t *testing.T) {
+	plan, err := PlanRebalance(rebalanceHoldings(), nil, 500, rebalanceTargets(), RebalanceOptions{Tolerance: 0.05, CashOnly: true, NewCash: 500})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// nothing is sold, the cash only goes as far as it can toward the bonds
+	if len(plan.Trades) != 1 {
+		t.Fatalf("Expected 1 trade but got %v\n", plan.Trades)
+	}
+	if buy := plan.Trades[0]; buy.Type != types.TransactionTypeBuy || buy.Symbol != "BND" || buy.Quantity != types.NewQuantity(10) {
+		t.Fatalf("Expected to buy 10 BND but got %v\n", buy)
+	}
+	if plan.CashLeft != 0 {
+		t.Fatalf("Expected no cash left but got %d\n", plan.CashLeft)
+	}
+}
+
+func TestPlanRebalanceWithinTolerance(t *testing.T) {
+	targets := rebalanceTargets()
+	targets[0].Tolerance = 0.15
+	targets = append(targets, types.TargetAllocation{Tag: "All", Kind: types.TargetAssetClass, Key: "Crypto", Weight: 0.05})
+	holdings := append(rebalanceHoldings(), HoldingRow{Symbol: "GLD", Info: types.SymbolInfo{Symbol: "GLD"}, Price: 100, MarketValue: 1000})
+
+	plan, err := PlanRebalance(holdings, nil, 0, targets, RebalanceOptions{Tolerance: 0.1})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// VTI drifted 10% which is within its own band, the bonds drifted out of the default one
+	for _, line := range plan.Lines {
+		if line.Key == "VTI" && line.OutOfBand {
+			t.Fatalf("Expected VTI to be within its tolerance but got %v\n", line)
+		}
+		if line.Key == "GLD" && line.Targeted {
+			t.Fatalf("Expected GLD to have no target but got %v\n", line)
+		}
+	}
+
+	// there is no cash and nothing to sell, so nothing is bought
+	if len(plan.Trades) != 0 {
+		t.Fatalf("Expected no trades but got %v\n", plan.Trades)
+	}
+
+	plan, err = PlanRebalance(holdings, nil, 1000, targets, RebalanceOptions{Tolerance: 0.1, CashOnly: true})
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// the cash is split between the bonds and crypto, which isn't held and is left to pick
+	var crypto types.Money
+	for _, trade := range plan.Trades {
+		if trade.Group == "Crypto" {
+			if trade.Symbol != "" {
+				t.Fatalf("Expected crypto trade without a symbol but got %v\n", trade)
+			}
+			crypto = trade.Amount
+		}
+	}
+	if crypto == 0 || plan.CashLeft < 0 {
+		t.Fatalf("Expected cash to be split with crypto but got %v\n", plan)
+	}
+}
+
+func TestPlanRebalanceOverflow(t *testing.T) {
+	_, err := PlanRebalance(rebalanceHoldings(), nil, math.MaxInt64, rebalanceTargets(), RebalanceOptions{})
+	if !errors.Is(err, types.ErrMoneyOverflow) {
+		t.Fatalf("Expected the total to overflow but got %v\n", err)
+	}
+}
@@ -0,0 +1,108 @@
+package portfolio
+
+import (
+	"math"
+	"time"
+	"tracker/types"
+)
+
+// riskPeriodsPerYear annualizes the returns of trading days. The series has a snapshot for every calendar day, the
+// returns of weekends are compounded into the next weekday so they don't add flat days that shrink the deviations.
+// Holidays still count as flat trading days.
+const riskPeriodsPerYear = 252
+
+// RiskMetricsFromSeries computes volatility, drawdowns and Sharpe and Sortino ratios from the daily returns of
+// series. Drawdowns are measured on the chained daily returns so deposits and withdrawals don't count as gains or
+// losses. riskFreeRate is annual.
+func RiskMetricsFromSeries(series []types.PortfolioSnapshot, riskFreeRate float64) types.RiskMetrics {
+	risk := types.RiskMetrics{RiskFreeRate: float32(riskFreeRate)}
+
+	returns := DailyReturns(series)
+	first := 0
+	for first < len(series) && series[first].Value == 0 {
+		first++
+	}
+	if len(returns)-first < 2 {
+		return risk
+	}
+	returns = returns[first:]
+	series = series[first:]
+	trading := tradingDayReturns(series, returns)
+	if len(trading) < 2 {
+		return risk
+	}
+
+	dailyRiskFree := math.Pow(1+riskFreeRate, 1.0/riskPeriodsPerYear) - 1
+
+	var sum float64
+	for _, r := range trading {
+		sum += r
+	}
+	mean := sum / float64(len(trading))
+
+	var variance, downside float64
+	for _, r := range trading {
+		variance += (r - mean) * (r - mean)
+		if r < dailyRiskFree {
+			downside += (r - dailyRiskFree) * (r - dailyRiskFree)
+		}
+	}
+	variance /= float64(len(trading) - 1)
+	downside /= float64(len(trading))
+
+	annualization := math.Sqrt(riskPeriodsPerYear)
+	volatility := math.Sqrt(variance) * annualization
+	downsideDeviation := math.Sqrt(downside) * annualization
+	excess := (mean - dailyRiskFree) * riskPeriodsPerYear
+
+	risk.Available = true
+	risk.Volatility = float32(volatility)
+	if volatility > 0 {
+		risk.Sharpe = float32(excess / volatility)
+	}
+	if downsideDeviation > 0 {
+		risk.Sortino = float32(excess / downsideDeviation)
+	}
+
+	wealth, peak, maxDrawdown := 1.0, 1.0, 0.0
+	peakDate := series[0].Date.AddDate(0, 0, -1)
+	for i, r := range returns {
+		wealth *= 1 + r
+		if wealth > peak {
+			peak = wealth
+			peakDate = series[i].Date
+			continue
+		}
+
+		drawdown := wealth/peak - 1
+		if drawdown < maxDrawdown {
+			maxDrawdown = drawdown
+			risk.PeakDate = peakDate
+			risk.TroughDate = series[i].Date
+		}
+	}
+	risk.MaxDrawdown = float32(maxDrawdown)
+	risk.CurrentDrawdown = float32(wealth/peak - 1)
+
+	return risk
+}
+
+// tradingDayReturns compounds the returns of Saturdays and Sundays into the return of the following weekday. A
+// weekend at the end of series is kept as a day of its own when it moved.
+func tradingDayReturns(series []types.PortfolioSnapshot, returns []float64) []float64 {
+	trading := make([]float64, 0, len(returns))
+	pending := 1.0
+	for i, r := range returns {
+		pending *= 1 + r
+		if day := series[i].Date.Weekday(); day == time.Saturday || day == time.Sunday {
+			continue
+		}
+		trading = append(trading, pending-1)
+		pending = 1
+	}
+	if pending != 1 {
+		trading = append(trading, pending-1)
+	}
+
+	return trading
+}
@@ -0,0 +1,107 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func riskSeries(values ...types.Money) []types.PortfolioSnapshot {
+	start := utils.StringToDate("2024-01-01")
+	series := make([]types.PortfolioSnapshot, len(values))
+	for i, v := range values {
+		series[i] = types.PortfolioSnapshot{Date: start.AddDate(0, 0, i), Value: v, NetContributions: 1000}
+	}
+	return series
+}
+
+func TestRiskMetricsDrawdown(t *testing.T) {
+	series := riskSeries(1000, 1200, 900, 600, 900, 1100)
+	// a deposit must not end the drawdown
+	series[4].NetContributions = 1300
+	series[5].NetContributions = 1300
+
+	risk := RiskMetricsFromSeries(series, 0)
+	if !risk.Available {
+		t.Fatalf("Expected risk metrics to be available\n")
+	}
+
+	if math.Abs(float64(risk.MaxDrawdown)+0.5) > 1e-6 {
+		t.Fatalf("Expected max drawdown to be -0.5 but got %f\n", risk.MaxDrawdown)
+	}
+
+	if !risk.PeakDate.Equal(utils.StringToDate("2024-01-02")) || !risk.TroughDate.Equal(utils.StringToDate("2024-01-04")) {
+		t.Fatalf("Expected drawdown from 2024-01-02 to 2024-01-04 but got %v to %v\n", risk.PeakDate, risk.TroughDate)
+	}
+
+	// the trough is half of the peak, the deposit of 300 is flat and 900 -> 1100 is the only gain after it
+	expected := 0.5*(1100.0/900.0) - 1
+	if math.Abs(float64(risk.CurrentDrawdown)-expected) > 1e-6 {
+		t.Fatalf("Expected current drawdown to be %f but got %f\n", expected, risk.CurrentDrawdown)
+	}
+}
+
+func TestRiskMetricsRatios(t *testing.T) {
+	series := riskSeries(1000, 1100, 1045, 1149, 1100, 1210)
+	returns := DailyReturns(series)
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	// the series ends on a Saturday that moved, every day is a trading day of its own
+	volatility := math.Sqrt(variance/float64(len(returns)-1)) * math.Sqrt(252)
+	downsideDeviation := math.Sqrt(downside/float64(len(returns))) * math.Sqrt(252)
+
+	risk := RiskMetricsFromSeries(series, 0)
+	if math.Abs(float64(risk.Volatility)-volatility) > 1e-4 {
+		t.Fatalf("Expected volatility to be %f but got %f\n", volatility, risk.Volatility)
+	}
+
+	if math.Abs(float64(risk.Sharpe)-mean*252/volatility) > 1e-3 {
+		t.Fatalf("Expected sharpe to be %f but got %f\n", mean*252/volatility, risk.Sharpe)
+	}
+
+	if math.Abs(float64(risk.Sortino)-mean*252/downsideDeviation) > 1e-3 {
+		t.Fatalf("Expected sortino to be %f but got %f\n", mean*252/downsideDeviation, risk.Sortino)
+	}
+
+	withRiskFree := RiskMetricsFromSeries(series, 0.05)
+	if withRiskFree.Sharpe >= risk.Sharpe {
+		t.Fatalf("Expected a risk free rate to lower sharpe but got %f and %f\n", withRiskFree.Sharpe, risk.Sharpe)
+	}
+}
+
+func TestRiskMetricsWeekends(t *testing.T) {
+	// Monday 2024-01-01 to Tuesday 2024-01-09, the weekend moves 10% a day and the following Monday another 10%
+	series := riskSeries(1000, 1000, 1000, 1000, 1000, 1100, 1210, 1331, 1331)
+
+	risk := RiskMetricsFromSeries(series, 0)
+	// the weekend is part of the return of Monday, 6 flat weekdays and one of 33.1%
+	trading := []float64{0, 0, 0, 0, 0, 0.331, 0}
+	mean := 0.331 / 7
+	var variance float64
+	for _, r := range trading {
+		variance += (r - mean) * (r - mean)
+	}
+	volatility := math.Sqrt(variance/6) * math.Sqrt(252)
+	if math.Abs(float64(risk.Volatility)-volatility) > 1e-4 {
+		t.Fatalf("Expected volatility to be %f but got %f\n", volatility, risk.Volatility)
+	}
+}
+
+func TestRiskMetricsNotEnoughHistory(t *testing.T) {
+	if RiskMetricsFromSeries(riskSeries(0, 0, 1000), 0).Available {
+		t.Fatalf("Expected risk metrics to be unavailable for a single day of history\n")
+	}
+}
@@ -0,0 +1,227 @@
+package portfolio
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"tracker/config"
+	"tracker/loaders"
+	"tracker/types"
+)
+
+type SimulationOptions struct {
+	Analyze             AnalyzeOptions
+	CapitalGainsTaxRate float64
+	DividendTaxes       config.DividendTaxConfig
+	// Now is the day the dividends of the last 12 months are counted back from
+	Now time.Time
+}
+
+// LoadSimulation simulates the trades on the stored transactions of account, with every amount in currency. Nothing
+// is written to the database.
+func LoadSimulation(db *sql.DB, account types.Account, trades []types.Transaction, currency string, opts SimulationOptions) (types.TradeSimulation, error) {
+	if len(trades) == 0 {
+		return types.TradeSimulation{}, fmt.Errorf("no trades to simulate")
+	}
+
+	transactions, err := loaders.AccountTransactions(db, account.Id)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+
+	// the market events are loaded for the traded symbols as well, their dividends project the income of new holdings
+	combined := append(slices.Clone(*transactions), trades...)
+	allTransactions := withMarketEvents(db, &combined)
+	stored := slices.DeleteFunc(allTransactions, func(t types.Transaction) bool {
+		return t.Id == "" && (t.Type == types.TransactionTypeBuy || t.Type == types.TransactionTypeSell)
+	})
+
+	symbols, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		symbols = map[string]types.SymbolInfo{}
+	}
+
+	analyze := analyzeOptions(db)
+	analyze.ReportingCurrency = currency
+	opts.Analyze = analyze
+
+	return SimulateTrades(account, stored, trades, loaders.AllPrices(db), symbols, opts)
+}
+
+// SimulateTrades applies the hypothetical Buy and Sell trades to a copy of transactions, sorted by date, and analyzes
+// the account again. Trades are placed after the transactions of their day and can't sell more shares than are held.
+func SimulateTrades(account types.Account, transactions []types.Transaction, trades []types.Transaction, prices map[string]types.SymbolPrice, symbols map[string]types.SymbolInfo, opts SimulationOptions) (types.TradeSimulation, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	before, err := AnalyzeTransactionsWithOptions(transactions, prices, opts.Analyze)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+
+	held := make(map[string]types.Quantity, len(before.SymbolsCount))
+	for symbol, count := range before.SymbolsCount {
+		held[strings.ToLower(symbol)] = count
+	}
+	for _, t := range trades {
+		if t.Type != types.TransactionTypeBuy && t.Type != types.TransactionTypeSell {
+			return types.TradeSimulation{}, fmt.Errorf("only buys and sells can be simulated, got %s", t.Type)
+		}
+		if err := t.Validate(); err != nil {
+			return types.TradeSimulation{}, err
+		}
+
+		key := strings.ToLower(t.Symbol)
+		if t.Type == types.TransactionTypeSell {
+			if t.Quantity > held[key] {
+				return types.TradeSimulation{}, fmt.Errorf("can't sell %s shares of %s, %s held", t.Quantity, strings.ToUpper(t.Symbol), held[key])
+			}
+			held[key] -= t.Quantity
+		} else {
+			held[key] += t.Quantity
+		}
+	}
+
+	simulated := append(slices.Clone(transactions), trades...)
+	sortTransactions(simulated)
+
+	after, err := AnalyzeTransactionsWithOptions(simulated, prices, opts.Analyze)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+
+	perShare, err := trailingDividendsPerShare(simulated, opts.Now)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+	sim := types.TradeSimulation{Trades: trades}
+	if sim.Before, err = simulationState(account, before, prices, symbols, perShare); err != nil {
+		return types.TradeSimulation{}, err
+	}
+	if sim.After, err = simulationState(account, after, prices, symbols, perShare); err != nil {
+		return types.TradeSimulation{}, err
+	}
+
+	// RealizedGain and DividendIncome are the differences of the states, they have to fit. A realized loss owes no
+	// tax, whatever it offsets elsewhere is left out.
+	var acc moneyAccumulator
+	if gain := acc.money(sim.After.RealizedGain.Sub(sim.Before.RealizedGain)); gain > 0 {
+		sim.Tax = acc.money(types.RoundMoney(float64(gain) * opts.CapitalGainsTaxRate))
+	}
+	_, err = sim.After.DividendIncome.Sub(sim.Before.DividendIncome)
+	acc.keep(err)
+
+	// the change in income of every symbol is taxed at the rate of its country in the account
+	tax := DividendTax{Rates: opts.DividendTaxes, Symbols: symbols}
+	income := make(taxedDividends)
+	afterIncome, err := dividendIncome(after, prices, perShare)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+	beforeIncome, err := dividendIncome(before, prices, perShare)
+	if err != nil {
+		return types.TradeSimulation{}, err
+	}
+	for key, amount := range afterIncome {
+		income.add(&acc, tax.Rate(account.Id, key), amount)
+	}
+	for key, amount := range beforeIncome {
+		income.add(&acc, tax.Rate(account.Id, key), -amount)
+	}
+	sim.NetDividendIncome = income.net(&acc)
+	if acc.err != nil {
+		return types.TradeSimulation{}, acc.err
+	}
+
+	return sim, nil
+}
+
+// simulationState summarizes p, the dividend income is the dividendIncome of its holdings
+func simulationState(account types.Account, p types.AnalyzedPortfolio, prices map[string]types.SymbolPrice, symbols map[string]types.SymbolInfo, perShare map[string]types.Money) (types.SimulationState, error) {
+	allocation, err := BuildAllocation([]types.Account{account}, map[string]types.AnalyzedPortfolio{account.Id: p}, prices, symbols)
+	if err != nil {
+		return types.SimulationState{}, err
+	}
+	state := types.SimulationState{
+		Value:          p.Value,
+		CashBalance:    p.CashBalance,
+		RealizedGain:   p.RealizedGain,
+		UnrealizedGain: p.UnrealizedGain,
+		Allocation:     allocation,
+	}
+
+	holdings, err := BuildHoldingRows(p, prices, symbols)
+	if err != nil {
+		return types.SimulationState{}, err
+	}
+	var acc moneyAccumulator
+	values := make(map[string]types.Money)
+	for _, h := range holdings {
+		if h.MarketValue > 0 {
+			value := values[h.Symbol]
+			acc.add(&value, h.MarketValue)
+			values[h.Symbol] = value
+		}
+	}
+	if acc.err != nil {
+		return types.SimulationState{}, acc.err
+	}
+	incomes, err := dividendIncome(p, prices, perShare)
+	if err != nil {
+		return types.SimulationState{}, err
+	}
+	for _, income := range incomes {
+		acc.add(&state.DividendIncome, income)
+	}
+	if acc.err != nil {
+		return types.SimulationState{}, acc.err
+	}
+	state.Holdings = allocationSlices(values, state.Allocation.Total)
+
+	return state, nil
+}
+
+// dividendIncome is the yield of the dividends per share on the market value of every holding of p, keyed by
+// lowercase symbol. The yield uses the stored price, in the currency the dividends are paid in.
+func dividendIncome(p types.AnalyzedPortfolio, prices map[string]types.SymbolPrice, perShare map[string]types.Money) (map[string]types.Money, error) {
+	holdings, err := BuildHoldingRows(p, prices, nil)
+	if err != nil {
+		return nil, err
+	}
+	var acc moneyAccumulator
+	income := make(map[string]types.Money)
+	for _, h := range holdings {
+		key := strings.ToLower(h.Symbol)
+		if price := prices[key].AdjPrice; h.MarketValue > 0 && price > 0 && perShare[key] > 0 {
+			sum := income[key]
+			acc.add(&sum, acc.ratio(h.MarketValue, int64(perShare[key]), int64(price)))
+			income[key] = sum
+		}
+	}
+
+	return income, acc.err
+}
+
+// trailingDividendsPerShare sums the dividends per share paid in the 12 months up to now, keyed by lowercase symbol
+func trailingDividendsPerShare(transactions []types.Transaction, now time.Time) (map[string]types.Money, error) {
+	var acc moneyAccumulator
+	from := now.AddDate(-1, 0, 0)
+	perShare := make(map[string]types.Money)
+	for _, t := range transactions {
+		if t.Type != types.TransactionTypeDividend {
+			continue
+		}
+		date := t.AsDate()
+		if date.After(from) && !date.After(now) {
+			key := strings.ToLower(t.Symbol)
+			sum := perShare[key]
+			acc.add(&sum, t.Pps)
+			perShare[key] = sum
+		}
+	}
+
+	return perShare, acc.err
+}
@@ -0,0 +1,146 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func simulationTransactions() []types.Transaction {
+	return []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 5, Date: utils.StringToDate("2024-06-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 1, Date: utils.StringToDate("2025-12-01")},
+		{Symbol: "MSFT", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 3, Date: utils.StringToDate("2026-03-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 1, Date: utils.StringToDate("2026-06-01")},
+	}
+}
+
+func TestSimulateTrades(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 200},
+		"msft": {Symbol: "MSFT", AdjPrice: 100},
+	}
+	trades := []types.Transaction{
+		{AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 200, Date: utils.StringToDate("2026-10-01")},
+		{AccountId: "1", Symbol: "MSFT", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2026-10-01")},
+	}
+	opts := SimulationOptions{
+		Analyze:             DefaultAnalyzeOptions(),
+		CapitalGainsTaxRate: 0.25,
+		DividendTaxes:       config.DividendTaxConfig{Default: 0.25},
+		Now:                 utils.StringToDate("2026-10-01"),
+	}
+
+	transactions := simulationTransactions()
+	sim, err := SimulateTrades(types.Account{Id: "1"}, transactions, trades, prices, nil, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if len(transactions) != 5 {
+		t.Fatalf("Expected the transactions to be left alone but got %d\n", len(transactions))
+	}
+
+	// selling half the AAPL realizes 5 * (200 - 100)
+	if sim.RealizedGain() != 500 || sim.Tax != 125 {
+		t.Fatalf("Expected realized gain/tax 500/125 but got %d/%d\n", sim.RealizedGain(), sim.Tax)
+	}
+
+	// AAPL paid 2 in the last 12 months on a price of 200 and MSFT 3 on 100
+	if sim.Before.DividendIncome != 20 || sim.After.DividendIncome != 40 {
+		t.Fatalf("Expected dividend income 20/40 but got %d/%d\n", sim.Before.DividendIncome, sim.After.DividendIncome)
+	}
+	if sim.DividendIncome() != 20 || sim.NetDividendIncome != 15 {
+		t.Fatalf("Expected dividend income change 20/15 but got %d/%d\n", sim.DividendIncome(), sim.NetDividendIncome)
+	}
+
+	// MSFT pays from a country without dividend tax, only the AAPL income given up is taxed
+	countryOpts := opts
+	countryOpts.DividendTaxes.Countries = map[string]float64{"IE": 0}
+	symbols := map[string]types.SymbolInfo{"msft": {Symbol: "MSFT", Country: "IE"}}
+	countrySim, err := SimulateTrades(types.Account{Id: "1"}, transactions, trades, prices, symbols, countryOpts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if countrySim.NetDividendIncome != 22 {
+		t.Fatalf("Expected dividend income change after tax 22 but got %d\n", countrySim.NetDividendIncome)
+	}
+
+	if len(sim.Before.Holdings) != 1 || sim.Before.Holdings[0].Percent != 1 {
+		t.Fatalf("Expected to hold only AAPL before but got %v\n", sim.Before.Holdings)
+	}
+	expected := []types.AllocationSlice{{Label: "AAPL", Value: 1000, Percent: 0.5}, {Label: "MSFT", Value: 1000, Percent: 0.5}}
+	if len(sim.After.Holdings) != 2 || sim.After.Holdings[0] != expected[0] || sim.After.Holdings[1] != expected[1] {
+		t.Fatalf("Expected holdings %v but got %v\n", expected, sim.After.Holdings)
+	}
+	if changes := sim.HoldingChanges(); len(changes) != 2 || changes[0].Change() != -0.5 || changes[1].Before != 0 {
+		t.Fatalf("Expected AAPL to drop by half and MSFT to be new but got %v\n", changes)
+	}
+	if sim.After.Allocation.Total != 2000 {
+		t.Fatalf("Expected allocation total to be 2000 but got %d\n", sim.After.Allocation.Total)
+	}
+}
+
+func TestSimulateTradesOversell(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 200},
+		"msft": {Symbol: "MSFT", AdjPrice: 100},
+	}
+	trades := []types.Transaction{
+		{AccountId: "1", Symbol: "aapl", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(11), Pps: 200, Date: utils.StringToDate("2026-10-01")},
+	}
+
+	_, err := SimulateTrades(types.Account{Id: "1"}, simulationTransactions(), trades, prices, nil, SimulationOptions{Analyze: DefaultAnalyzeOptions()})
+	if err == nil {
+		t.Fatalf("Expected selling more than is held to fail\n")
+	}
+}
+
+func TestSimulateTradesLoss(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 50},
+		"msft": {Symbol: "MSFT", AdjPrice: 100},
+	}
+	trades := []types.Transaction{
+		{AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 50, Date: utils.StringToDate("2026-10-01")},
+	}
+	opts := SimulationOptions{
+		Analyze:             DefaultAnalyzeOptions(),
+		CapitalGainsTaxRate: 0.25,
+		Now:                 utils.StringToDate("2026-10-01"),
+	}
+
+	sim, err := SimulateTrades(types.Account{Id: "1"}, simulationTransactions(), trades, prices, nil, opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// selling at a loss realizes 5 * (50 - 100) and owes no tax
+	if sim.RealizedGain() != -250 || sim.Tax != 0 {
+		t.Fatalf("Expected realized gain/tax -250/0 but got %d/%d\n", sim.RealizedGain(), sim.Tax)
+	}
+}
+
+func TestSimulateTradesOverflow(t *testing.T) {
+	prices := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 1 << 62},
+	}
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: 1, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 1 << 62, Date: utils.StringToDate("2026-06-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Quantity: 1, Pps: 1 << 62, Date: utils.StringToDate("2026-07-01")},
+	}
+	trades := []types.Transaction{
+		{AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(1), Pps: 1, Date: utils.StringToDate("2026-10-01")},
+	}
+	opts := SimulationOptions{
+		Analyze: DefaultAnalyzeOptions(),
+		Now:     utils.StringToDate("2026-10-01"),
+	}
+
+	if _, err := SimulateTrades(types.Account{Id: "1"}, transactions, trades, prices, nil, opts); err == nil {
+		t.Fatalf("Expected a simulation that doesn't fit to fail\n")
+	}
+}
@@ -0,0 +1,185 @@
+package portfolio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"tracker/types"
+)
+
+// BuildTimeSeries returns a snapshot for every calendar day from the first transaction until to.
+// transactions must be sorted by date, history is keyed by lowercase symbol and sorted by date.
+// Days without a price carry the last known price forward, trades count as a known price for their day.
+// Accounts that keep a cash ledger contribute their deposits and withdrawals and their cash is part of the value.
+// Merger cash paid out of the portfolio lowers the net contributions like a sell.
+func BuildTimeSeries(transactions []types.Transaction, history map[string][]types.SymbolPrice, to time.Time) ([]types.PortfolioSnapshot, error) {
+	return BuildTimeSeriesWithOptions(transactions, history, to, DefaultAnalyzeOptions())
+}
+
+// BuildTimeSeriesWithOptions builds the series in opts.ReportingCurrency, flows are converted at the rate of their
+// day and every snapshot values the holdings and cash at the rate of its own day
+func BuildTimeSeriesWithOptions(transactions []types.Transaction, history map[string][]types.SymbolPrice, to time.Time, opts AnalyzeOptions) ([]types.PortfolioSnapshot, error) {
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+
+	start := truncateDay(transactions[0].AsDate())
+	end := truncateDay(to)
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	series := make([]types.PortfolioSnapshot, 0, int(end.Sub(start).Hours()/24)+1)
+	holdings := make(map[string]types.Quantity)
+	lastPrice := make(map[string]types.Money)
+	lastPriceDate := make(map[string]time.Time)
+	historyIdx := make(map[string]int, len(history))
+
+	var acc moneyAccumulator
+	var netContributions types.Money
+	var dividends types.Money
+	ledger := newCashLedger(transactions)
+	currencies := newCurrencyBook(transactions, nil, opts)
+	paired := pairedTransfers(transactions)
+	txIdx := 0
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		for ; txIdx < len(transactions) && !truncateDay(transactions[txIdx].AsDate()).After(day); txIdx++ {
+			local := transactions[txIdx]
+			t := currencies.convert(local)
+			currency := currencies.currencyOf(local)
+			symbol := strings.ToLower(t.Symbol)
+
+			if t.Type.IsCash() {
+				currencies.addCash(currency, cashDelta(local))
+				switch t.Type {
+				case types.TransactionTypeDeposit:
+					acc.add(&netContributions, t.Pps)
+				case types.TransactionTypeWithdrawal:
+					acc.add(&netContributions, -t.Pps)
+				}
+				continue
+			}
+
+			switch t.Type {
+			case types.TransactionTypeBuy:
+				holdings[symbol] += t.Quantity
+				if ledger.tracks(t) {
+					currencies.addCash(currency, -acc.cost(local))
+				} else {
+					acc.add(&netContributions, acc.cost(t))
+				}
+				lastPrice[symbol] = local.Pps
+				lastPriceDate[symbol] = day
+
+			case types.TransactionTypeSell:
+				holdings[symbol] -= t.Quantity
+				if ledger.tracks(t) {
+					currencies.addCash(currency, acc.cost(local))
+				} else {
+					acc.add(&netContributions, -acc.cost(t))
+				}
+				lastPrice[symbol] = local.Pps
+				lastPriceDate[symbol] = day
+
+			case types.TransactionTypeTransfer:
+				holdings[symbol] += t.Quantity
+				ledger.tracks(t)
+				if !paired[t.TransferId] {
+					acc.add(&netContributions, acc.value(t.Quantity, t.Pps))
+				}
+
+			case types.TransactionTypeDividend:
+				reinvested := acc.value(ledger.shares[symbol], t.Pps)
+				currencies.addCash(currency, acc.value(ledger.shares[symbol], local.Pps))
+				acc.add(&dividends, acc.value(holdings[symbol], t.Pps)-reinvested)
+
+			case types.TransactionTypeCorporateAction:
+				a := *t.Action
+				newSymbol := strings.ToLower(a.NewSymbol)
+				count := holdings[symbol]
+				ledgerShares := ledger.corporateAction(a)
+				currencies.addCash(currency, acc.value(ledgerShares, local.Action.CashPerShare))
+				acc.add(&netContributions, -acc.value(count-ledgerShares, a.CashPerShare))
+				holdings[newSymbol] += a.NewShares(count)
+				if a.Type == types.CorporateActionRename {
+					// the new ticker may have no history before the rename
+					if _, ok := lastPrice[newSymbol]; !ok {
+						lastPrice[newSymbol] = lastPrice[symbol]
+						lastPriceDate[newSymbol] = lastPriceDate[symbol]
+					}
+				}
+				if a.Replaces() && newSymbol != symbol {
+					delete(holdings, symbol)
+				}
+
+			case types.TransactionTypeSplit:
+				holdings[symbol] = holdings[symbol].MulRatio(int64(t.Pps), 100)
+				ledger.split(symbol, t.Pps)
+				if price, err := lastPrice[symbol].MulRatio(100, int64(t.Pps)); err == nil {
+					lastPrice[symbol] = price
+				}
+			}
+
+			acc.keep(currencies.acc.err)
+			if acc.err != nil {
+				return nil, fmt.Errorf("building time series at transaction %s: %w", t.Id, acc.err)
+			}
+		}
+
+		value := currencies.cashValue(day)
+		snapshotHoldings := make(map[string]types.Quantity, len(holdings))
+		for symbol, count := range holdings {
+			if count == 0 {
+				continue
+			}
+
+			prices := history[symbol]
+			i := historyIdx[symbol]
+			for ; i < len(prices) && !truncateDay(prices[i].Date).After(day); i++ {
+				if truncateDay(prices[i].Date).Before(lastPriceDate[symbol]) {
+					continue
+				}
+				lastPrice[symbol] = prices[i].AdjPrice
+				lastPriceDate[symbol] = truncateDay(prices[i].Date)
+			}
+			historyIdx[symbol] = i
+
+			snapshotHoldings[symbol] = count
+			acc.add(&value, currencies.toReporting(acc.value(count, lastPrice[symbol]), currencies.symbolCurrency(symbol), day))
+		}
+		acc.keep(currencies.acc.err)
+		if acc.err != nil {
+			return nil, fmt.Errorf("building time series on %s: %w", day.Format(time.DateOnly), acc.err)
+		}
+
+		series = append(series, types.PortfolioSnapshot{
+			Date:             day,
+			Value:            value,
+			NetContributions: netContributions,
+			Dividends:        dividends,
+			Holdings:         snapshotHoldings,
+		})
+	}
+
+	return series, nil
+}
+
+// SnapshotAt returns the snapshot of the given day, false if the day is outside the series
+func SnapshotAt(series []types.PortfolioSnapshot, date time.Time) (types.PortfolioSnapshot, bool) {
+	day := truncateDay(date)
+	i := sort.Search(len(series), func(i int) bool {
+		return !series[i].Date.Before(day)
+	})
+
+	if i == len(series) || !series[i].Date.Equal(day) {
+		return types.PortfolioSnapshot{}, false
+	}
+
+	return series[i], true
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,63 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestBuildTimeSeries(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2023-01-01")},
+		{Symbol: "AAPL", Type: types.TransactionTypeDividend, Pps: 5, Date: utils.StringToDate("2023-01-03")},
+		{Symbol: "AAPL", Type: types.TransactionTypeSplit, Pps: 200, Date: utils.StringToDate("2023-01-04")},
+		{Id: "s1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(5), Pps: 60, Date: utils.StringToDate("2023-01-05")},
+	}
+	history := map[string][]types.SymbolPrice{
+		"aapl": {
+			{Symbol: "AAPL", AdjPrice: 110, Date: utils.StringToDate("2023-01-02")},
+			{Symbol: "AAPL", AdjPrice: 70, Date: utils.StringToDate("2023-01-06")},
+		},
+	}
+
+	series, err := BuildTimeSeries(transactions, history, utils.StringToDate("2023-01-07"))
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if len(series) != 7 {
+		t.Fatalf("Expected 7 daily snapshots but got %d\n", len(series))
+	}
+
+	testCases := []struct {
+		date          string
+		value         types.Money
+		contributions types.Money
+		dividends     types.Money
+		shares        int64
+	}{
+		{date: "2023-01-01", value: 1000, contributions: 1000, dividends: 0, shares: 10},
+		{date: "2023-01-02", value: 1100, contributions: 1000, dividends: 0, shares: 10},
+		// no price on the 3rd, the price of the 2nd is carried forward
+		{date: "2023-01-03", value: 1100, contributions: 1000, dividends: 50, shares: 10},
+		// the carried price is halved by the 2:1 split
+		{date: "2023-01-04", value: 1100, contributions: 1000, dividends: 50, shares: 20},
+		{date: "2023-01-05", value: 900, contributions: 700, dividends: 50, shares: 15},
+		{date: "2023-01-07", value: 1050, contributions: 700, dividends: 50, shares: 15},
+	}
+
+	for _, tc := range testCases {
+		s, ok := SnapshotAt(series, utils.StringToDate(tc.date))
+		if !ok {
+			t.Fatalf("Expected a snapshot for %s\n", tc.date)
+		}
+
+		if s.Value != tc.value || s.NetContributions != tc.contributions || s.Dividends != tc.dividends || s.Holdings["aapl"] != types.NewQuantity(tc.shares) {
+			t.Fatalf("Expected %s value/contributions/dividends/shares %d/%d/%d/%d but got %d/%d/%d/%s\n", tc.date,
+				tc.value, tc.contributions, tc.dividends, tc.shares, s.Value, s.NetContributions, s.Dividends, s.Holdings["aapl"])
+		}
+	}
+
+	if _, ok := SnapshotAt(series, utils.StringToDate("2022-12-31")); ok {
+		t.Fatalf("Expected no snapshot before the first transaction\n")
+	}
+}
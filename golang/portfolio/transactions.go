@@ -8,31 +8,51 @@ import (
 
 type TransactionRow struct {
 	Transaction types.Transaction
-	Quantity    int32
+	Quantity    types.Quantity
 	Total       int64
 }
 
+// BuildTransactionRows returns the rows of the transactions table newest first. Totals are net of fees, a buy
+// costs its fee on top and a sell's proceeds are after its fee. Corporate actions show the shares they applied to
+// and the cash they paid, they are hidden along with dividends and splits.
 func BuildTransactionRows(transactions []types.Transaction, showDividends bool) []TransactionRow {
 	rows := make([]TransactionRow, 0, len(transactions))
-	symbolsCount := make(map[string]int32)
+	symbolsCount := make(map[string]types.Quantity)
 
 	for _, tx := range transactions {
 		symbol := strings.ToLower(tx.Symbol)
 		quantity := tx.Quantity
-		total := int64(tx.Quantity) * int64(tx.Pps)
+		total := tx.Quantity.MulPrice(int64(tx.Pps))
+
+		if tx.Type.IsCash() {
+			total = int64(tx.Pps)
+		}
 
 		switch tx.Type {
-		case types.TransactionTypeBuy:
+		case types.TransactionTypeBuy, types.TransactionTypeSell:
+			if tx.Type == types.TransactionTypeBuy {
+				symbolsCount[symbol] += tx.Quantity
+			} else {
+				symbolsCount[symbol] -= tx.Quantity
+			}
+			// an amount too large for the fee to be applied keeps the saturated value
+			if cost, err := tx.Cost(); err == nil {
+				total = int64(cost)
+			}
+		case types.TransactionTypeTransfer:
 			symbolsCount[symbol] += tx.Quantity
-		case types.TransactionTypeSell:
-			symbolsCount[symbol] -= tx.Quantity
 		case types.TransactionTypeDividend:
 			quantity = symbolsCount[symbol]
-			total = int64(quantity) * int64(tx.Pps)
+			total = quantity.MulPrice(int64(tx.Pps))
 		case types.TransactionTypeSplit:
-			count := symbolsCount[symbol]
-			ratio := float32(tx.Pps) / 100
-			symbolsCount[symbol] = int32(float32(count) * ratio)
+			symbolsCount[symbol] = symbolsCount[symbol].MulRatio(int64(tx.Pps), 100)
+		case types.TransactionTypeCorporateAction:
+			quantity = symbolsCount[symbol]
+			total = quantity.MulPrice(int64(tx.Action.CashPerShare))
+			symbolsCount[strings.ToLower(tx.Action.NewSymbol)] += tx.Action.NewShares(quantity)
+			if tx.Action.Replaces() && !strings.EqualFold(tx.Action.NewSymbol, tx.Symbol) {
+				delete(symbolsCount, symbol)
+			}
 		}
 
 		rows = append(rows, TransactionRow{Transaction: tx, Quantity: quantity, Total: total})
@@ -48,7 +68,9 @@ func BuildTransactionRows(transactions []types.Transaction, showDividends bool)
 
 	filtered := make([]TransactionRow, 0, len(rows))
 	for _, row := range rows {
-		if row.Transaction.Type != types.TransactionTypeDividend && row.Transaction.Type != types.TransactionTypeSplit {
+		switch row.Transaction.Type {
+		case types.TransactionTypeDividend, types.TransactionTypeSplit, types.TransactionTypeCorporateAction:
+		default:
 			filtered = append(filtered, row)
 		}
 	}
@@ -0,0 +1,96 @@
+package portfolio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"tracker/types"
+	"tracker/utils"
+)
+
+// pairedTransfers returns the transfer ids that have both their outgoing and incoming row in transactions.
+// A paired transfer moves shares inside the analyzed accounts, an unpaired one moves them in or out at cost.
+func pairedTransfers(transactions []types.Transaction) map[string]bool {
+	out := make(map[string]bool)
+	in := make(map[string]bool)
+	for _, t := range transactions {
+		if t.Type != types.TransactionTypeTransfer || t.TransferId == "" {
+			continue
+		}
+		if t.Quantity < 0 {
+			out[t.TransferId] = true
+		} else {
+			in[t.TransferId] = true
+		}
+	}
+
+	paired := make(map[string]bool, len(out))
+	for id := range out {
+		if in[id] {
+			paired[id] = true
+		}
+	}
+	return paired
+}
+
+// NewTransfer returns the outgoing and incoming rows moving quantity shares of symbol between two accounts on date.
+// source are the transactions of the sending account with their market events sorted by date, they are replayed into
+// lots like the analysis does with opts so each side carries the cost basis of the moved shares.
+func NewTransfer(source []types.Transaction, fromAccountId, toAccountId, symbol string, quantity types.Quantity, date time.Time, opts AnalyzeOptions) (types.Transaction, types.Transaction, error) {
+	if fromAccountId == toAccountId {
+		return types.Transaction{}, types.Transaction{}, fmt.Errorf("can't transfer to the same account")
+	}
+	if quantity <= 0 {
+		return types.Transaction{}, types.Transaction{}, fmt.Errorf("quantity must be positive")
+	}
+
+	lots := newLotBook(opts)
+	paired := pairedTransfers(source)
+	for _, t := range source {
+		if t.AsDate().After(date) {
+			break
+		}
+		lots.apply(t, paired[t.TransferId])
+	}
+
+	out := types.Transaction{
+		Id:         utils.GenerateUUID(),
+		AccountId:  fromAccountId,
+		Symbol:     symbol,
+		Date:       date,
+		Type:       types.TransactionTypeTransfer,
+		Quantity:   -quantity,
+		TransferId: utils.GenerateUUID(),
+		// the cost basis is in the currency the shares were bought in
+		Currency: newCurrencyBook(source, nil, opts).symbolCurrency(symbol),
+	}
+
+	var held types.Quantity
+	var acc moneyAccumulator
+	var basis types.Money
+	for _, lot := range lots.takeLots(out) {
+		held += lot.Quantity
+		acc.add(&basis, lot.CostBasis)
+	}
+	acc.keep(lots.err)
+	if acc.err != nil {
+		return types.Transaction{}, types.Transaction{}, acc.err
+	}
+	if held < quantity {
+		return types.Transaction{}, types.Transaction{}, fmt.Errorf("only %s shares of %s are held, can't transfer %s",
+			held, strings.ToUpper(symbol), quantity)
+	}
+
+	pps, err := basis.MulRatio(int64(types.QuantityScale), int64(quantity))
+	if err != nil {
+		return types.Transaction{}, types.Transaction{}, err
+	}
+	out.Pps = pps
+
+	in := out
+	in.Id = utils.GenerateUUID()
+	in.AccountId = toAccountId
+	in.Quantity = quantity
+
+	return out, in, nil
+}
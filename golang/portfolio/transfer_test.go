@@ -0,0 +1,120 @@
+package portfolio
+
+import (
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestTransferBetweenAccounts(t *testing.T) {
+	source := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		{Id: "b2", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 200, Date: utils.StringToDate("2024-02-01")},
+	}
+
+	out, in, err := NewTransfer(source, "a", "b", "AAPL", types.NewQuantity(15), utils.StringToDate("2024-03-01"), DefaultAnalyzeOptions())
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	// FIFO moves all of the first lot and half of the second, 2000 over 15 shares
+	if out.Pps != 133 || in.Pps != 133 || out.Quantity != -in.Quantity || out.TransferId != in.TransferId {
+		t.Fatalf("Expected a linked pair at 133 per share but got %+v and %+v\n", out, in)
+	}
+
+	sell := types.Transaction{Id: "s1", AccountId: "b", Symbol: "AAPL", Type: types.TransactionTypeSell, Quantity: types.NewQuantity(15), Pps: 300, Date: utils.StringToDate("2024-04-01")}
+	prices := map[string]types.SymbolPrice{"aapl": {AdjPrice: 300}}
+
+	// the incoming row comes first on the transfer day, the lots still move
+	combined, err := AnalyzeTransactions(append(source, in, out, sell), prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if combined.TotalInvested != 3000 || combined.TotalWithdrawn != 4500 {
+		t.Fatalf("Expected invested/withdrawn 3000/4500 but got %d/%d\n", combined.TotalInvested, combined.TotalWithdrawn)
+	}
+
+	if combined.RealizedGain != 2500 {
+		t.Fatalf("Expected RealizedGain to be %d but got %d\n", 2500, combined.RealizedGain)
+	}
+
+	for _, l := range combined.ClosedLots {
+		if l.AccountId != "b" || (l.LotId != "b1" && l.LotId != "b2") {
+			t.Fatalf("Expected lots opened in a to be closed in b but got %+v\n", l)
+		}
+	}
+
+	if combined.OpenLots[0].AccountId != "a" || combined.OpenLots[0].Quantity != types.NewQuantity(5) {
+		t.Fatalf("Expected 5 shares left in a but got %+v\n", combined.OpenLots)
+	}
+
+	accountA, err := AnalyzeTransactions(append(source, out), prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	accountB, err := AnalyzeTransactions([]types.Transaction{in, sell}, prices)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if accountA.RealizedGain != 0 {
+		t.Fatalf("Expected no realized gain on the sending side but got %d\n", accountA.RealizedGain)
+	}
+
+	if accountA.TotalWithdrawn != 1995 || accountB.TotalInvested != 1995 {
+		t.Fatalf("Expected the shares to move at cost but got %d/%d\n", accountA.TotalWithdrawn, accountB.TotalInvested)
+	}
+
+	if accountA.GainValue+accountB.GainValue != combined.GainValue {
+		t.Fatalf("Expected account gains %d + %d to add up to %d\n", accountA.GainValue, accountB.GainValue, combined.GainValue)
+	}
+
+	if accountA.Value+accountB.Value != combined.Value {
+		t.Fatalf("Expected account values %d + %d to add up to %d\n", accountA.Value, accountB.Value, combined.Value)
+	}
+}
+
+func TestTransferMoreThanHeld(t *testing.T) {
+	source := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+	}
+
+	if _, _, err := NewTransfer(source, "a", "b", "AAPL", types.NewQuantity(11), utils.StringToDate("2024-03-01"), DefaultAnalyzeOptions()); err == nil {
+		t.Fatalf("Expected an error transferring more shares than held\n")
+	}
+
+	if _, _, err := NewTransfer(source, "a", "a", "AAPL", types.NewQuantity(1), utils.StringToDate("2024-03-01"), DefaultAnalyzeOptions()); err == nil {
+		t.Fatalf("Expected an error transferring to the same account\n")
+	}
+}
+
+func TestTransferAfterRename(t *testing.T) {
+	rename := types.CorporateAction{Id: "ca1", Date: utils.StringToDate("2024-02-01"), Type: types.CorporateActionRename, Symbol: "FB", NewSymbol: "META"}
+	source := []types.Transaction{
+		{Id: "b1", AccountId: "a", Symbol: "FB", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+		{Id: "b2", AccountId: "a", Symbol: "FB", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 200, Date: utils.StringToDate("2024-01-15")},
+		actionRow(rename),
+	}
+
+	// the shares bought as FB are held as META after the rename
+	out, in, err := NewTransfer(source, "a", "b", "META", types.NewQuantity(5), utils.StringToDate("2024-03-01"), DefaultAnalyzeOptions())
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if out.Pps != 100 || in.Symbol != "META" {
+		t.Fatalf("Expected 5 META moving at the FIFO cost of 100 but got %+v\n", in)
+	}
+
+	// the lot method of the caller picks the lots
+	opts := DefaultAnalyzeOptions()
+	opts.LotMethod = types.LotMethodLIFO
+	out, _, err = NewTransfer(source, "a", "b", "META", types.NewQuantity(5), utils.StringToDate("2024-03-01"), opts)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if out.Pps != 200 {
+		t.Fatalf("Expected the LIFO cost of 200 but got %d\n", out.Pps)
+	}
+}
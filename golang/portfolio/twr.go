@@ -0,0 +1,79 @@
+package portfolio
+
+import (
+	"math"
+	"time"
+	"tracker/types"
+)
+
+// DailyReturns returns the return of every day in series. Buys and sells are external cash flows and are
+// assumed to land at the end of the day at the traded price, unless the portfolio was empty the day before.
+// Dividends are income and count towards the return of the day they are paid.
+func DailyReturns(series []types.PortfolioSnapshot) []float64 {
+	returns := make([]float64, len(series))
+
+	var prev types.PortfolioSnapshot
+	for i, s := range series {
+		flow := s.NetContributions - prev.NetContributions
+		income := s.Dividends - prev.Dividends
+
+		if prev.Value > 0 {
+			returns[i] = float64(s.Value+income-flow)/float64(prev.Value) - 1
+		} else if flow > 0 {
+			returns[i] = float64(s.Value+income)/float64(flow) - 1
+		}
+
+		prev = s
+	}
+
+	return returns
+}
+
+// TimeWeightedReturnsFromSeries chains the daily returns of series into YTD, 1Y, 3Y, 5Y and since inception windows
+// ending at asOf. Windows longer than a year are also annualized.
+func TimeWeightedReturnsFromSeries(series []types.PortfolioSnapshot, asOf time.Time) types.TimeWeightedReturns {
+	var twr types.TimeWeightedReturns
+	if len(series) == 0 {
+		return twr
+	}
+
+	end := truncateDay(asOf)
+	returns := DailyReturns(series)
+	inception := series[0].Date
+
+	window := func(label string, start time.Time, partial bool) types.ReturnWindow {
+		w := types.ReturnWindow{Label: label, Start: start}
+		if start.Before(inception.AddDate(0, 0, -1)) {
+			if !partial {
+				return w
+			}
+			start = inception.AddDate(0, 0, -1)
+		}
+
+		growth := 1.0
+		for i, s := range series {
+			if s.Date.After(start) && !s.Date.After(end) {
+				growth *= 1 + returns[i]
+			}
+		}
+
+		w.Available = true
+		w.Return = float32(growth - 1)
+		w.Annualized = w.Return
+
+		years := end.Sub(start).Hours() / 24 / 365
+		if years > 1 && growth > 0 {
+			w.Annualized = float32(math.Pow(growth, 1/years) - 1)
+		}
+
+		return w
+	}
+
+	twr.YTD = window("YTD", time.Date(end.Year()-1, 12, 31, 0, 0, 0, 0, time.UTC), true)
+	twr.OneYear = window("1Y", end.AddDate(-1, 0, 0), false)
+	twr.ThreeYears = window("3Y", end.AddDate(-3, 0, 0), false)
+	twr.FiveYears = window("5Y", end.AddDate(-5, 0, 0), false)
+	twr.Inception = window("Inception", inception.AddDate(0, 0, -1), true)
+
+	return twr
+}
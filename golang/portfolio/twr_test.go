@@ -0,0 +1,71 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestDailyReturnsIgnoreCashFlows(t *testing.T) {
+	// a deposit doubling the portfolio mid period must not show up as a gain
+	series := []types.PortfolioSnapshot{
+		{Date: utils.StringToDate("2024-01-01"), Value: 1000, NetContributions: 1000},
+		{Date: utils.StringToDate("2024-01-02"), Value: 1100, NetContributions: 1000},
+		{Date: utils.StringToDate("2024-01-03"), Value: 11000, NetContributions: 11000},
+		{Date: utils.StringToDate("2024-01-04"), Value: 10450, NetContributions: 11000, Dividends: 0},
+		{Date: utils.StringToDate("2024-01-05"), Value: 10450, NetContributions: 11000, Dividends: 209},
+	}
+
+	expected := []float64{0, 0.1, -0.1 / 1.1, -0.05, 0.02}
+	returns := DailyReturns(series)
+	for i := range expected {
+		if math.Abs(returns[i]-expected[i]) > 1e-9 {
+			t.Fatalf("Expected return of day %d to be %f but got %f\n", i, expected[i], returns[i])
+		}
+	}
+}
+
+func TestTimeWeightedReturnsFromSeries(t *testing.T) {
+	transactions := []types.Transaction{
+		{Id: "b1", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2022-01-03")},
+		{Id: "b2", AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(100), Pps: 200, Date: utils.StringToDate("2023-06-01")},
+	}
+	history := map[string][]types.SymbolPrice{
+		"aapl": {
+			{Symbol: "AAPL", AdjPrice: 200, Date: utils.StringToDate("2023-06-01")},
+			{Symbol: "AAPL", AdjPrice: 250, Date: utils.StringToDate("2023-12-31")},
+			{Symbol: "AAPL", AdjPrice: 300, Date: utils.StringToDate("2024-06-01")},
+		},
+	}
+
+	asOf := utils.StringToDate("2024-06-01")
+	series, err := BuildTimeSeries(transactions, history, asOf)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	twr := TimeWeightedReturnsFromSeries(series, asOf)
+
+	// 100 -> 200 -> 300, the large buy in the middle does not change the time weighted return
+	if math.Abs(float64(twr.Inception.Return)-2) > 1e-6 {
+		t.Fatalf("Expected since inception return to be 2 but got %f\n", twr.Inception.Return)
+	}
+
+	if math.Abs(float64(twr.YTD.Return)-0.2) > 1e-6 {
+		t.Fatalf("Expected YTD return to be 0.2 but got %f\n", twr.YTD.Return)
+	}
+
+	if math.Abs(float64(twr.OneYear.Return)-0.5) > 1e-6 || !twr.OneYear.Available {
+		t.Fatalf("Expected 1Y return to be 0.5 but got %f\n", twr.OneYear.Return)
+	}
+
+	if twr.ThreeYears.Available || twr.FiveYears.Available {
+		t.Fatalf("Expected 3Y and 5Y windows to be unavailable for a portfolio opened in 2022\n")
+	}
+
+	years := asOf.Sub(utils.StringToDate("2022-01-02")).Hours() / 24 / 365
+	expectedAnnualized := math.Pow(3, 1/years) - 1
+	if math.Abs(float64(twr.Inception.Annualized)-expectedAnnualized) > 1e-6 {
+		t.Fatalf("Expected annualized since inception return to be %f but got %f\n", expectedAnnualized, twr.Inception.Annualized)
+	}
+}
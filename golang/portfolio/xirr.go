@@ -0,0 +1,124 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrXIRRNoConvergence is returned when neither Newton's method nor bisection find a rate
+	ErrXIRRNoConvergence = errors.New("xirr: failed to converge")
+	// ErrXIRRInvalidCashFlows is returned when the cash flows don't have both a positive and a negative amount
+	ErrXIRRInvalidCashFlows = errors.New("xirr: cash flows must contain at least one positive and one negative amount")
+)
+
+const (
+	xirrTolerance     = 1e-9
+	xirrMaxIterations = 100
+)
+
+// CashFlow is a dated amount from the investor's point of view, money put in is negative and money taken out
+// (or still held at the end) is positive
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// XIRR returns the annual rate at which the net present value of flows is zero, using actual/365 day counting
+// like spreadsheet XIRR. Newton's method is tried first, bisection is the fallback when it diverges.
+func XIRR(flows []CashFlow) (float64, error) {
+	var hasPositive, hasNegative bool
+	for _, f := range flows {
+		if f.Amount > 0 {
+			hasPositive = true
+		} else if f.Amount < 0 {
+			hasNegative = true
+		}
+	}
+	if !hasPositive || !hasNegative {
+		return 0, ErrXIRRInvalidCashFlows
+	}
+
+	start := flows[0].Date
+	for _, f := range flows {
+		if f.Date.Before(start) {
+			start = f.Date
+		}
+	}
+
+	years := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.Date.Sub(start).Hours() / 24 / 365
+	}
+
+	npv := func(rate float64) float64 {
+		var v float64
+		for i, f := range flows {
+			v += f.Amount / math.Pow(1+rate, years[i])
+		}
+		return v
+	}
+
+	if rate, ok := xirrNewton(flows, years, npv); ok {
+		return rate, nil
+	}
+
+	return xirrBisection(npv)
+}
+
+func xirrNewton(flows []CashFlow, years []float64, npv func(float64) float64) (float64, bool) {
+	derivative := func(rate float64) float64 {
+		var d float64
+		for i, f := range flows {
+			d -= years[i] * f.Amount / math.Pow(1+rate, years[i]+1)
+		}
+		return d
+	}
+
+	rate := 0.1
+	for range xirrMaxIterations {
+		d := derivative(rate)
+		if d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+			return 0, false
+		}
+
+		next := rate - npv(rate)/d
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			return 0, false
+		}
+
+		if math.Abs(next-rate) < xirrTolerance {
+			return next, true
+		}
+		rate = next
+	}
+
+	return 0, false
+}
+
+func xirrBisection(npv func(float64) float64) (float64, error) {
+	low, high := -0.999999, 1.0
+	for npv(low)*npv(high) > 0 {
+		high *= 2
+		if high > 1e6 {
+			return 0, ErrXIRRNoConvergence
+		}
+	}
+
+	for range xirrMaxIterations * 2 {
+		mid := (low + high) / 2
+		v := npv(mid)
+		if math.Abs(v) < xirrTolerance || (high-low)/2 < xirrTolerance {
+			return mid, nil
+		}
+
+		if npv(low)*v < 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	return 0, ErrXIRRNoConvergence
+}
@@ -0,0 +1,118 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tracker/types"
+	"tracker/utils"
+)
+
+func TestXIRR(t *testing.T) {
+	testCases := []struct {
+		name     string
+		flows    []CashFlow
+		expected float64
+	}{
+		{
+			// the example from the spreadsheet XIRR documentation
+			name: "spreadsheet example",
+			flows: []CashFlow{
+				{Date: utils.StringToDate("2008-01-01"), Amount: -10000},
+				{Date: utils.StringToDate("2008-03-01"), Amount: 2750},
+				{Date: utils.StringToDate("2008-10-30"), Amount: 4250},
+				{Date: utils.StringToDate("2009-02-15"), Amount: 3250},
+				{Date: utils.StringToDate("2009-04-01"), Amount: 2750},
+			},
+			expected: 0.373362535,
+		},
+		{
+			name: "one year gain over a leap year",
+			flows: []CashFlow{
+				{Date: utils.StringToDate("2020-01-01"), Amount: -1000},
+				{Date: utils.StringToDate("2021-01-01"), Amount: 1100},
+			},
+			expected: 0.099713586,
+		},
+		{
+			name: "loss",
+			flows: []CashFlow{
+				{Date: utils.StringToDate("2021-01-01"), Amount: -1000},
+				{Date: utils.StringToDate("2022-01-01"), Amount: 500},
+			},
+			expected: -0.5,
+		},
+		{
+			name: "unordered flows with a second deposit",
+			flows: []CashFlow{
+				{Date: utils.StringToDate("2022-01-01"), Amount: 2200},
+				{Date: utils.StringToDate("2021-01-01"), Amount: -1000},
+				{Date: utils.StringToDate("2020-01-01"), Amount: -1000},
+			},
+			expected: 0.065184872,
+		},
+		{
+			name: "short period large gain",
+			flows: []CashFlow{
+				{Date: utils.StringToDate("2023-01-01"), Amount: -100},
+				{Date: utils.StringToDate("2023-02-01"), Amount: 150},
+			},
+			expected: 117.394783,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := XIRR(tc.flows)
+			if err != nil {
+				t.Fatalf("Error wasn't nil: %v\n", err)
+			}
+
+			if math.Abs(rate-tc.expected) > 1e-6*math.Max(1, math.Abs(tc.expected)) {
+				t.Fatalf("Expected XIRR to be %f but got %f\n", tc.expected, rate)
+			}
+		})
+	}
+}
+
+func TestXIRRInvalidCashFlows(t *testing.T) {
+	flows := []CashFlow{
+		{Date: utils.StringToDate("2023-01-01"), Amount: -100},
+		{Date: utils.StringToDate("2023-02-01"), Amount: -150},
+	}
+
+	if _, err := XIRR(flows); !errors.Is(err, ErrXIRRInvalidCashFlows) {
+		t.Fatalf("Expected ErrXIRRInvalidCashFlows but got %v\n", err)
+	}
+}
+
+func TestAnalyzerXIRR(t *testing.T) {
+	transactions := []types.Transaction{
+		{AccountId: "1", Symbol: "AAPL", Type: types.TransactionTypeBuy, Quantity: types.NewQuantity(10), Pps: 100, Date: utils.StringToDate("2024-01-01")},
+	}
+	priceTable := map[string]types.SymbolPrice{
+		"aapl": {Symbol: "AAPL", AdjPrice: 100},
+	}
+
+	portfolio, err := AnalyzeTransactions(transactions, priceTable)
+	if err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+
+	if math.Abs(float64(portfolio.XIRR)) > 1e-6 {
+		t.Fatalf("Expected XIRR of an unchanged price to be 0 but got %f\n", portfolio.XIRR)
+	}
+}
+
+func TestXIRRNoConvergence(t *testing.T) {
+	// the net present value stays negative for every rate
+	flows := []CashFlow{
+		{Date: utils.StringToDate("2021-01-01"), Amount: -100},
+		{Date: utils.StringToDate("2022-01-01"), Amount: 250},
+		{Date: utils.StringToDate("2023-01-01"), Amount: -200},
+	}
+
+	if _, err := XIRR(flows); !errors.Is(err, ErrXIRRNoConvergence) {
+		t.Fatalf("Expected ErrXIRRNoConvergence but got %v\n", err)
+	}
+}
@@ -5,13 +5,22 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 	"tracker/config"
+	"tracker/loaders"
 	"tracker/market"
+	"tracker/portfolio"
 	"tracker/storage"
 	"tracker/tui"
+	"tracker/types"
+	"tracker/utils"
 	"tracker/web"
 )
 
@@ -21,6 +30,30 @@ func main() {
 
 	args := flag.Args()
 
+	if len(args) > 0 && args[0] == "rebalance" {
+		if err := runRebalance(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Rebalance failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "lots" {
+		if err := runLots(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Lot selection failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "dividends" {
+		if err := runDividends(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Dividends failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if len(args) == 1 {
 		switch args[0] {
 		case "help":
@@ -29,9 +62,16 @@ func main() {
 		case "update":
 			db, cleanup := storage.OpenDatabase(false)
 			defer cleanup()
-			market.UpdateMarketData(db)
+			market.UpdateMarketData(db, cfg.Currencies.Codes(), cfg.Benchmarks.Symbols()...)
 			fmt.Println("Market data updated successfully")
 			return
+		case "backfill":
+			if err := runBackfill(cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "Backfill failed: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Price history and exchange rates backfilled successfully")
+			return
 		case "server":
 			web.StartServer(cfg)
 			return
@@ -64,13 +104,411 @@ func printHelp() {
 	fmt.Println("Commands:")
 	fmt.Println("  help     Show this help")
 	fmt.Println("  update   Update market data")
+	fmt.Println("  backfill Load daily price history and exchange rates since the first transaction")
 	fmt.Println("  server   Start the web server")
 	fmt.Println("  backup   Backup database to home directory")
+	fmt.Println("  rebalance --tag <tag> [--currency <code>] [--cash-only] [--cash <amount>] [--target <symbol>=<weight>|class:<asset class>=<weight> ...] [--tolerance <weight>]")
+	fmt.Println("           Suggest trades that bring the accounts of tag back to their target weights, --target replaces the targets")
+	fmt.Println("           Amounts are in --currency, the first configured currency by default")
+	fmt.Println("  dividends [--forecast] [--tag <tag>]")
+	fmt.Println("           Report the dividends of the accounts of tag by year, month, symbol and account, --forecast projects")
+	fmt.Println("           them over the next 12 months instead")
+	fmt.Println("  lots --sell <id> [--lot <buy id>=<quantity> ...]")
+	fmt.Println("           Close the given lots first when matching the sell, without --lot the sell closes lots by the")
+	fmt.Println("           configured TRACKER_LOT_METHOD again")
 	fmt.Println("  (none)   Start the portfolio tracker TUI")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  tracker          Start portfolio tracker TUI")
 	fmt.Println("  tracker update   Update market data")
+	fmt.Println("  tracker rebalance --tag Long --target VTI=60 --target class:Bond=40")
+	fmt.Println("  tracker rebalance --tag Long --cash-only --cash 1000 --currency ILS")
+	fmt.Println("  tracker dividends --tag Long")
+	fmt.Println("  tracker dividends --forecast --tag Long")
+	fmt.Println("  tracker lots --sell 7f3c --lot 2a91=10 --lot 88b0=5")
+}
+
+// targetFlags collects repeated flags like --target and --lot
+type targetFlags []string
+
+func (t *targetFlags) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *targetFlags) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+func runRebalance(cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	tag := fs.String("tag", "All", "tag of the accounts to rebalance, All for every account")
+	cashOnly := fs.Bool("cash-only", false, "only buy with the cash, nothing is sold")
+	newCash := fs.Float64("cash", 0, "cash about to be added, in the currency of the plan")
+	currencyCode := fs.String("currency", cfg.Currencies.Base().Code, "currency of the plan, one of the configured currencies")
+	tolerance := fs.Float64("tolerance", 0, "drift left alone for the targets set with --target, 0 for the configured one")
+	var targets targetFlags
+	fs.Var(&targets, "target", "target weight as <symbol>=<weight> or class:<asset class>=<weight>, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	currency := cfg.Currencies.Get(*currencyCode)
+	if currency.Code != types.NormalizeCurrency(*currencyCode) {
+		return fmt.Errorf("unknown currency %q, expected one of %s", *currencyCode, strings.Join(currencyCodes(cfg.Currencies), ", "))
+	}
+	cash, err := types.RoundMoney(*newCash * 100)
+	if err != nil || cash < 0 {
+		return fmt.Errorf("invalid cash amount %v", *newCash)
+	}
+
+	db, cleanup := storage.OpenDatabase(false)
+	defer cleanup()
+
+	if len(targets) > 0 {
+		parsed, err := parseTargets(*tag, targets, *tolerance)
+		if err != nil {
+			return err
+		}
+		if err := loaders.SetTargetAllocations(db, *tag, parsed); err != nil {
+			return fmt.Errorf("failed to save targets: %w", err)
+		}
+	}
+
+	accounts, err := taggedAccounts(db, *tag)
+	if err != nil {
+		return err
+	}
+
+	plan, err := portfolio.LoadRebalancePlan(db, accountIds(accounts), *tag, currency.Code, portfolio.RebalanceOptions{
+		Tolerance: cfg.RebalanceTolerance,
+		CashOnly:  *cashOnly,
+		NewCash:   cash,
+	})
+	if err != nil {
+		return err
+	}
+
+	printRebalancePlan(plan, currency)
+	return nil
+}
+
+// currencyCodes returns the codes of the configured currencies, USD first
+func currencyCodes(currencies config.CurrencyConfig) []string {
+	codes := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		codes = append(codes, c.Code)
+	}
+	return codes
+}
+
+// runLots stores the lots a sell closes, they are picked by the id of the buy that opened them
+func runLots(cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("lots", flag.ContinueOnError)
+	sellId := fs.String("sell", "", "id of the sell transaction")
+	var lots targetFlags
+	fs.Var(&lots, "lot", "lot to close as <buy id>=<quantity>, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*sellId) == "" {
+		return fmt.Errorf("--sell is required")
+	}
+
+	selections, err := parseLotSelections(lots)
+	if err != nil {
+		return err
+	}
+
+	db, cleanup := storage.OpenDatabase(false)
+	defer cleanup()
+
+	if err := loaders.SetLotSelections(db, strings.TrimSpace(*sellId), selections); err != nil {
+		return fmt.Errorf("failed to save lot selections: %w", err)
+	}
+
+	if len(selections) == 0 {
+		fmt.Printf("Sell %s closes lots by %s\n", *sellId, cfg.LotMethod)
+	} else {
+		fmt.Printf("Sell %s closes %d selected lots first and the rest by %s\n", *sellId, len(selections), cfg.LotMethod)
+	}
+	return nil
+}
+
+// parseLotSelections reads <buy id>=<quantity>
+func parseLotSelections(values []string) ([]types.LotSelection, error) {
+	selections := make([]types.LotSelection, 0, len(values))
+	for _, value := range values {
+		lotId, raw, ok := strings.Cut(value, "=")
+		lotId = strings.TrimSpace(lotId)
+		if !ok || lotId == "" {
+			return nil, fmt.Errorf("invalid lot %q, expected <buy id>=<quantity>", value)
+		}
+		quantity, err := types.ParseQuantity(strings.TrimSpace(raw))
+		if err != nil || quantity <= 0 {
+			return nil, fmt.Errorf("invalid quantity in lot %q", value)
+		}
+		selections = append(selections, types.LotSelection{LotId: lotId, Quantity: quantity})
+	}
+
+	return selections, nil
+}
+
+// parseTargets reads <symbol>=<weight> and class:<asset class>=<weight>, weights above 1 are percentages
+func parseTargets(tag string, values []string, tolerance float64) ([]types.TargetAllocation, error) {
+	if math.IsNaN(tolerance) || tolerance < 0 || tolerance > 100 {
+		return nil, fmt.Errorf("invalid tolerance %v", tolerance)
+	}
+	if tolerance > 1 {
+		tolerance = tolerance / 100
+	}
+
+	targets := make([]types.TargetAllocation, 0, len(values))
+	for _, value := range values {
+		key, raw, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid target %q, expected <symbol>=<weight>", value)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || math.IsNaN(weight) || weight < 0 || weight > 100 {
+			return nil, fmt.Errorf("invalid weight in target %q", value)
+		}
+		if weight > 1 {
+			weight = weight / 100
+		}
+
+		t := types.TargetAllocation{Tag: tag, Kind: types.TargetSymbol, Key: strings.TrimSpace(key), Weight: float32(weight), Tolerance: float32(tolerance)}
+		if class, ok := strings.CutPrefix(t.Key, "class:"); ok {
+			t.Kind = types.TargetAssetClass
+			t.Key = strings.TrimSpace(class)
+			if !slices.Contains(types.AssetClasses, types.AssetClass(t.Key)) {
+				return nil, fmt.Errorf("unknown asset class %q", t.Key)
+			}
+		}
+		if t.Key == "" {
+			return nil, fmt.Errorf("invalid target %q, expected <symbol>=<weight>", value)
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// printRebalancePlan prints the plan, its amounts are already in currency
+func printRebalancePlan(plan types.RebalancePlan, currency config.DisplayCurrency) {
+	money := func(val types.Money, precision int) string {
+		return utils.FormatCurrency(val, precision, currency.CurrencyFormat, 1)
+	}
+
+	mode := "sell and buy"
+	if plan.CashOnly {
+		mode = "cash only"
+	}
+	fmt.Printf("Rebalance %s (%s): value %s, cash %s\n", plan.Tag, mode,
+		money(plan.Total, 0), money(plan.Cash, 0))
+	if plan.TargetTotal < 0.999 || plan.TargetTotal > 1.001 {
+		fmt.Printf("Targets add up to %s\n", utils.ToYieldString(plan.TargetTotal))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Target\tValue\tWeight\tTarget\tDrift\tBand\t")
+	for _, line := range plan.Lines {
+		if !line.Targeted {
+			fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t\t\n", line.Key, money(line.Value, 0), utils.ToYieldString(line.Weight))
+			continue
+		}
+		band := "ok"
+		if line.OutOfBand {
+			band = "out"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s ±%s\t\n", line.Key, money(line.Value, 0),
+			utils.ToYieldString(line.Weight), utils.ToYieldString(line.TargetWeight), utils.ToYieldString(line.Drift),
+			band, utils.ToYieldString(line.Tolerance))
+	}
+	w.Flush()
+	fmt.Println()
+
+	if len(plan.Trades) == 0 {
+		fmt.Println("No trades needed")
+		return
+	}
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Trade\tSymbol\tShares\tPrice\tAmount\t")
+	for _, trade := range plan.Trades {
+		symbol := trade.Symbol
+		if symbol == "" {
+			symbol = "any " + trade.Group
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", trade.Type, symbol, trade.Quantity,
+			money(trade.Price, 2), money(trade.Amount, 0))
+	}
+	w.Flush()
+	fmt.Printf("\nCash left: %s\n", money(plan.CashLeft, 0))
+}
+
+func runDividends(cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("dividends", flag.ContinueOnError)
+	tag := fs.String("tag", "All", "tag of the accounts, All for every account")
+	forecast := fs.Bool("forecast", false, "project the dividends of the current holdings over the next 12 months instead of reporting the dividends received")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, cleanup := storage.OpenDatabase(false)
+	defer cleanup()
+
+	accounts, err := taggedAccounts(db, *tag)
+	if err != nil {
+		return err
+	}
+
+	// rates differ by account and country, the tax is printed as the part of the dividends it takes
+	tax := portfolio.DividendTax{Rates: cfg.DividendTaxes}
+	if *forecast {
+		f, err := portfolio.LoadDividendForecast(db, accountIds(accounts), types.CurrencyUSD, cfg.DividendTaxes)
+		if err != nil {
+			return err
+		}
+		printDividendForecast(f, *tag, tax.EffectiveRate(f.Total, f.Net))
+		return nil
+	}
+
+	report, err := portfolio.LoadDividendReport(db, accounts, types.CurrencyUSD, cfg.DividendTaxes)
+	if err != nil {
+		return err
+	}
+	printDividendReport(report, *tag, tax.EffectiveRate(report.Gross, report.Net))
+	return nil
+}
+
+// taggedAccounts returns the accounts with tag, All for every account
+func taggedAccounts(db *sql.DB, tag string) ([]types.Account, error) {
+	all, err := loaders.UserAccounts(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+	var accounts []types.Account
+	for _, ac := range *all {
+		if tag == "All" || slices.Contains(ac.Tags, tag) {
+			accounts = append(accounts, ac)
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no accounts tagged %s", tag)
+	}
+
+	return accounts, nil
+}
+
+func accountIds(accounts []types.Account) []string {
+	ids := make([]string, 0, len(accounts))
+	for _, ac := range accounts {
+		ids = append(ids, ac.Id)
+	}
+	return ids
+}
+
+func printDividendReport(r types.DividendReport, tag string, taxRate float64) {
+	fmt.Printf("Dividends %s up to %s: %s, %s after %s tax\n", tag, r.AsOf.Format("2006-01-02"),
+		utils.ToCurrencyStringUSD(r.Gross, 0), utils.ToCurrencyStringUSD(r.Net, 0), utils.ToYieldString(float32(taxRate)))
+	fmt.Printf("Trailing twelve months: %s, %s after tax\n", utils.ToCurrencyStringUSD(r.TTM, 2), utils.ToCurrencyStringUSD(r.TTMNet, 2))
+	fmt.Println()
+
+	if len(r.Years) == 0 {
+		fmt.Println("No dividends received")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Year\tGross\tNet\tYoY\t")
+	for _, y := range r.Years {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", y.Label, utils.ToCurrencyStringUSD(y.Gross, 2),
+			utils.ToCurrencyStringUSD(y.Net, 2), dividendGrowth(y))
+	}
+	w.Flush()
+	fmt.Println()
+
+	// the last two years of months are enough for the review, the years cover the rest
+	months := r.Months[max(len(r.Months)-24, 0):]
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Month\tGross\tNet\tYoY\t")
+	for _, m := range months {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", m.Label, utils.ToCurrencyStringUSD(m.Gross, 2),
+			utils.ToCurrencyStringUSD(m.Net, 2), dividendGrowth(m))
+	}
+	w.Flush()
+	fmt.Println()
+
+	for _, groups := range []struct {
+		name   string
+		groups []types.DividendGroup
+	}{{"Symbol", r.Symbols}, {"Account", r.Accounts}} {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(w, "%s\tGross\tNet\tTTM\tShare\t\n", groups.name)
+		for _, g := range groups.groups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", g.Label, utils.ToCurrencyStringUSD(g.Gross, 2),
+				utils.ToCurrencyStringUSD(g.Net, 2), utils.ToCurrencyStringUSD(g.TTM, 2), utils.ToYieldString(g.Percent))
+		}
+		w.Flush()
+		fmt.Println()
+	}
+}
+
+func dividendGrowth(p types.DividendPeriod) string {
+	if !p.HasGrowth {
+		return "-"
+	}
+	return utils.ToYieldString(p.Growth)
+}
+
+func printDividendForecast(f types.DividendForecast, tag string, taxRate float64) {
+	fmt.Printf("Dividend forecast %s, %s to %s: %s, %s after %s tax\n", tag, f.From.Format("2006-01-02"),
+		f.From.AddDate(1, 0, -1).Format("2006-01-02"), utils.ToCurrencyStringUSD(f.Total, 0),
+		utils.ToCurrencyStringUSD(f.Net, 0), utils.ToYieldString(float32(taxRate)))
+	fmt.Println()
+
+	if len(f.Holdings) == 0 {
+		fmt.Println("No dividends expected")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Symbol\tShares\tFrequency\tNext\tAnnual\tNet\tYield\tYield on cost\t")
+	for _, h := range f.Holdings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", h.Symbol, h.Quantity, h.Frequency, h.NextDate.Format("2006-01-02"),
+			utils.ToCurrencyStringUSD(h.Annual, 2), utils.ToCurrencyStringUSD(h.Net, 2), utils.ToYieldString(h.Yield),
+			utils.ToYieldString(h.YieldOnCost))
+	}
+	w.Flush()
+	fmt.Println()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Month\tIncome\tNet\t")
+	for _, m := range f.Months {
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", m.Month.Format("Jan 2006"), utils.ToCurrencyStringUSD(m.Amount, 2),
+			utils.ToCurrencyStringUSD(m.Net, 2))
+	}
+	w.Flush()
+	fmt.Println()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Date\tSymbol\tShares\tAmount\tNet\t")
+	for _, p := range f.Payments {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", p.Date.Format("2006-01-02"), p.Symbol, p.Quantity,
+			utils.ToCurrencyStringUSD(p.Amount, 2), utils.ToCurrencyStringUSD(p.Net, 2))
+	}
+	w.Flush()
+}
+
+func runBackfill(cfg config.AppConfig) error {
+	db, cleanup := storage.OpenDatabase(false)
+	defer cleanup()
+
+	if err := market.BackfillPriceHistory(db, cfg.Benchmarks.Symbols()...); err != nil {
+		return err
+	}
+	return market.BackfillExchangeRates(db, cfg.Currencies.Codes()...)
 }
 
 func runBackup() error {
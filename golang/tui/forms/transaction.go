@@ -2,7 +2,6 @@ package forms
 
 import (
 	"fmt"
-	"strconv"
 	"time"
 
 	"tracker/types"
@@ -13,15 +12,24 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+const (
+	actionSave    = "Save"
+	actionPreview = "Preview"
+	actionCancel  = "Cancel"
+)
+
 type TransactionForm struct {
 	form      *huh.Form
 	accountId string
-	width     int
-	height    int
-	completed bool
-	cancelled bool
-	result    types.Transaction
-	styles    TransactionFormStyles
+	// transferTo is the receiving account of a transfer
+	transferTo string
+	width      int
+	height     int
+	completed  bool
+	cancelled  bool
+	preview    bool
+	result     types.Transaction
+	styles     TransactionFormStyles
 }
 
 type TransactionFormStyles struct {
@@ -49,14 +57,42 @@ type TransactionFormResult struct {
 	Cancelled   bool
 }
 
-func NewTransactionForm(accountId string) TransactionForm {
+// NewTransactionForm returns the form adding a transaction to accountId, accounts are the transfer destinations
+func NewTransactionForm(accountId string, accounts []types.Account) TransactionForm {
 	var (
-		dateStr     = time.Now().Format("2006-01-02")
-		txType      = "Buy"
-		symbol      = ""
-		quantityStr = ""
-		priceStr    = ""
+		dateStr       = time.Now().Format("2006-01-02")
+		txType        = string(types.TransactionTypeBuy)
+		symbol        = ""
+		quantityStr   = ""
+		priceStr      = ""
+		feeStr        = "0"
+		amountStr     = ""
+		cashSymbolStr = ""
+		transferTo    = ""
+		currency      = types.CurrencyUSD
+		isCash        = func() bool { return types.TransactionType(txType).IsCash() }
+		isTransfer    = func() bool { return types.TransactionType(txType) == types.TransactionTypeTransfer }
+		typeOptions   = make([]huh.Option[string], 0, len(types.UserTransactionTypes))
+		validatePrice = func(s string) error {
+			if _, err := types.ParseMoney(s); err != nil {
+				return fmt.Errorf("must be a finite number that fits")
+			}
+			return nil
+		}
 	)
+	for _, t := range types.UserTransactionTypes {
+		typeOptions = append(typeOptions, huh.NewOption(string(t), string(t)))
+	}
+
+	accountOptions := make([]huh.Option[string], 0, len(accounts))
+	for _, ac := range accounts {
+		if ac.Id != "" && ac.Id != accountId {
+			accountOptions = append(accountOptions, huh.NewOption(ac.Name, ac.Id))
+		}
+		if ac.Id == accountId {
+			currency = types.NormalizeCurrency(ac.BaseCurrency)
+		}
+	}
 
 	form := huh.NewForm(
 		huh.NewGroup(
@@ -75,12 +111,23 @@ func NewTransactionForm(accountId string) TransactionForm {
 			huh.NewSelect[string]().
 				Key("type").
 				Title("Transaction Type").
-				Options(
-					huh.NewOption("Buy", "Buy"),
-					huh.NewOption("Sell", "Sell"),
-				).
+				Options(typeOptions...).
 				Value(&txType),
 
+			huh.NewInput().
+				Key("currency").
+				Title("Currency").
+				Value(&currency).
+				Validate(func(s string) error {
+					if len(s) != 3 {
+						return fmt.Errorf("must be a 3 letter currency code")
+					}
+					return nil
+				}),
+		),
+
+		// buys and sells
+		huh.NewGroup(
 			huh.NewInput().
 				Key("symbol").
 				Title("Symbol").
@@ -97,8 +144,12 @@ func NewTransactionForm(accountId string) TransactionForm {
 				Title("Quantity").
 				Value(&quantityStr).
 				Validate(func(s string) error {
-					if _, err := strconv.ParseInt(s, 10, 32); err != nil {
-						return fmt.Errorf("must be a number")
+					q, err := types.ParseQuantity(s)
+					if err != nil {
+						return fmt.Errorf("must be a number with up to %d decimals", types.QuantityDecimals)
+					}
+					if q <= 0 {
+						return fmt.Errorf("must be positive")
 					}
 					return nil
 				}),
@@ -107,18 +158,97 @@ func NewTransactionForm(accountId string) TransactionForm {
 				Key("price").
 				Title("Price per Share").
 				Value(&priceStr).
+				Validate(validatePrice),
+
+			huh.NewInput().
+				Key("fee").
+				Title("Fee / Commission").
+				Value(&feeStr).
+				Validate(func(s string) error {
+					if err := validatePrice(s); err != nil {
+						return err
+					}
+					if parseMoney(s) < 0 {
+						return fmt.Errorf("can't be negative")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return isCash() || isTransfer() }),
+
+		// shares moved to another account at their cost basis
+		huh.NewGroup(
+			huh.NewInput().
+				Key("transfer_symbol").
+				Title("Symbol").
+				Value(&symbol).
+				Validate(func(s string) error {
+					if len(s) == 0 {
+						return fmt.Errorf("symbol is required")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("transfer_quantity").
+				Title("Quantity").
+				Value(&quantityStr).
+				Validate(func(s string) error {
+					q, err := types.ParseQuantity(s)
+					if err != nil {
+						return fmt.Errorf("must be a number with up to %d decimals", types.QuantityDecimals)
+					}
+					if q <= 0 {
+						return fmt.Errorf("must be positive")
+					}
+					return nil
+				}),
+
+			huh.NewSelect[string]().
+				Key("transfer_to").
+				Title("To Account").
+				Options(accountOptions...).
+				Value(&transferTo).
+				Validate(func(s string) error {
+					if s == "" {
+						return fmt.Errorf("no account to transfer to")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return !isTransfer() }),
+
+		// deposits, withdrawals, fees, interest and tax
+		huh.NewGroup(
+			huh.NewInput().
+				Key("amount").
+				Title("Amount").
+				Value(&amountStr).
 				Validate(func(s string) error {
-					if _, err := strconv.ParseFloat(s, 64); err != nil {
-						return fmt.Errorf("must be a number")
+					if err := validatePrice(s); err != nil {
+						return err
+					}
+					if parseMoney(s) <= 0 {
+						return fmt.Errorf("must be positive")
 					}
 					return nil
 				}),
 
-			huh.NewConfirm().
-				Key("confirm").
+			huh.NewInput().
+				Key("cash_symbol").
+				Title("Symbol (optional)").
+				Value(&cashSymbolStr),
+		).WithHideFunc(func() bool { return !isCash() }),
+
+		// a buy or sell can be previewed before it's saved
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Key("action").
 				Title("Save transaction?").
-				Affirmative("Save").
-				Negative("Cancel"),
+				OptionsFunc(func() []huh.Option[string] {
+					if isCash() || isTransfer() {
+						return huh.NewOptions(actionSave, actionCancel)
+					}
+					return huh.NewOptions(actionSave, actionPreview, actionCancel)
+				}, &txType),
 		),
 	).WithTheme(getFormTheme()).WithShowHelp(true).WithShowErrors(true).WithKeyMap(getFormKeyMap())
 
@@ -144,10 +274,20 @@ func (f *TransactionForm) Cancelled() bool {
 	return f.cancelled
 }
 
+// Preview reports whether the result should be simulated instead of saved
+func (f *TransactionForm) Preview() bool {
+	return f.preview
+}
+
 func (f *TransactionForm) Result() types.Transaction {
 	return f.result
 }
 
+// TransferTo returns the receiving account when the result is a transfer
+func (f *TransactionForm) TransferTo() string {
+	return f.transferTo
+}
+
 func (f *TransactionForm) Init() tea.Cmd {
 	return f.form.Init()
 }
@@ -160,11 +300,14 @@ func (f *TransactionForm) Update(msg tea.Msg) (TransactionForm, tea.Cmd) {
 
 	if f.form.State == huh.StateCompleted {
 		f.completed = true
-		confirm := f.form.GetBool("confirm")
-		if !confirm {
-			f.cancelled = true
-		} else {
+		switch f.form.GetString("action") {
+		case actionSave:
+			f.result = f.buildTransaction()
+		case actionPreview:
+			f.preview = true
 			f.result = f.buildTransaction()
+		default:
+			f.cancelled = true
 		}
 	}
 
@@ -173,33 +316,57 @@ func (f *TransactionForm) Update(msg tea.Msg) (TransactionForm, tea.Cmd) {
 
 func (f *TransactionForm) buildTransaction() types.Transaction {
 	dateStr := f.form.GetString("date")
-	txType := f.form.GetString("type")
-	symbol := f.form.GetString("symbol")
-	quantityStr := f.form.GetString("quantity")
-	priceStr := f.form.GetString("price")
-
+	transactionType := types.TransactionType(f.form.GetString("type"))
 	date, _ := time.Parse("2006-01-02", dateStr)
-	quantity, _ := strconv.ParseInt(quantityStr, 10, 32)
-	price, _ := strconv.ParseFloat(priceStr, 64)
-	priceInCents := int32(price * 100)
-
-	var transactionType types.TransactionType
-	if txType == "Buy" {
-		transactionType = types.TransactionTypeBuy
-	} else {
-		transactionType = types.TransactionTypeSell
+	currency := types.NormalizeCurrency(f.form.GetString("currency"))
+
+	if transactionType == types.TransactionTypeTransfer {
+		// the rows of the transfer are built by the caller, they need the history of the account
+		f.transferTo = f.form.GetString("transfer_to")
+		quantity, _ := types.ParseQuantity(f.form.GetString("transfer_quantity"))
+		return types.Transaction{
+			AccountId: f.accountId,
+			Symbol:    f.form.GetString("transfer_symbol"),
+			Date:      date,
+			Type:      transactionType,
+			Quantity:  quantity,
+		}
+	}
+
+	if transactionType.IsCash() {
+		return types.Transaction{
+			AccountId: f.accountId,
+			Symbol:    f.form.GetString("cash_symbol"),
+			Date:      date,
+			Type:      transactionType,
+			Pps:       parseMoney(f.form.GetString("amount")),
+			Currency:  currency,
+		}
 	}
 
+	symbol := f.form.GetString("symbol")
+	quantity, _ := types.ParseQuantity(f.form.GetString("quantity"))
+	priceInCents := parseMoney(f.form.GetString("price"))
+	fee := parseMoney(f.form.GetString("fee"))
+
 	return types.Transaction{
 		AccountId: f.accountId,
 		Symbol:    symbol,
 		Date:      date,
 		Type:      transactionType,
-		Quantity:  int32(quantity),
+		Quantity:  quantity,
 		Pps:       priceInCents,
+		Fee:       fee,
+		Currency:  currency,
 	}
 }
 
+// parseMoney parses an amount input that already passed validation
+func parseMoney(s string) types.Money {
+	m, _ := types.ParseMoney(s)
+	return m
+}
+
 func (f TransactionForm) View() string {
 	title := f.styles.Title.Render("Add Transaction")
 	formView := f.form.View()
@@ -3,22 +3,26 @@ package tui
 import "github.com/charmbracelet/bubbles/key"
 
 type KeyMap struct {
-	Up          key.Binding
-	Down        key.Binding
-	Enter       key.Binding
-	Back        key.Binding
-	Quit        key.Binding
-	Help        key.Binding
-	Tab         key.Binding
-	NewTx       key.Binding
-	DeleteTx    key.Binding
-	ToggleDivs  key.Binding
-	CurrencyUSD key.Binding
-	CurrencyNIS key.Binding
-	CycleTag    key.Binding
-	Confirm     key.Binding
-	Cancel      key.Binding
-	Summarize   key.Binding
+	Up             key.Binding
+	Down           key.Binding
+	Enter          key.Binding
+	Back           key.Binding
+	Quit           key.Binding
+	Help           key.Binding
+	Tab            key.Binding
+	NewTx          key.Binding
+	DeleteTx       key.Binding
+	ToggleDivs     key.Binding
+	ToggleHoldings key.Binding
+	CycleCurrency  key.Binding
+	CycleTag       key.Binding
+	Confirm        key.Binding
+	Cancel         key.Binding
+	Summarize      key.Binding
+	Allocation     key.Binding
+	Rebalance      key.Binding
+	CashOnly       key.Binding
+	Dividends      key.Binding
 }
 
 var Keys = KeyMap{
@@ -62,13 +66,13 @@ var Keys = KeyMap{
 		key.WithKeys("h"),
 		key.WithHelp("h", "toggle dividends"),
 	),
-	CurrencyUSD: key.NewBinding(
-		key.WithKeys("1"),
-		key.WithHelp("1", "USD"),
+	ToggleHoldings: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "toggle holdings"),
 	),
-	CurrencyNIS: key.NewBinding(
-		key.WithKeys("2"),
-		key.WithHelp("2", "NIS"),
+	CycleCurrency: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "cycle currency"),
 	),
 	CycleTag: key.NewBinding(
 		key.WithKeys("t"),
@@ -86,6 +90,22 @@ var Keys = KeyMap{
 		key.WithKeys("ctrl+s"),
 		key.WithHelp("Ctrl+S", "AI insights"),
 	),
+	Allocation: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "allocation"),
+	),
+	Rebalance: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "rebalance"),
+	),
+	CashOnly: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "cash only"),
+	),
+	Dividends: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "dividends"),
+	),
 }
 
 func (k KeyMap) ShortHelp() []key.Binding {
@@ -95,8 +115,8 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Enter, k.Back, k.Summarize},
-		{k.CurrencyUSD, k.CurrencyNIS, k.CycleTag},
-		{k.NewTx, k.DeleteTx, k.ToggleDivs},
+		{k.CycleCurrency, k.CycleTag, k.Allocation, k.Rebalance, k.Dividends},
+		{k.NewTx, k.DeleteTx, k.ToggleDivs, k.ToggleHoldings},
 		{k.Tab, k.Help, k.Quit},
 	}
 }
@@ -112,7 +132,7 @@ func (k AccountsKeyMap) ShortHelp() []key.Binding {
 func (k AccountsKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Enter},
-		{k.CurrencyUSD, k.CurrencyNIS, k.CycleTag},
+		{k.CycleCurrency, k.CycleTag, k.Allocation, k.Rebalance, k.Dividends},
 		{k.Summarize, k.Help, k.Quit},
 	}
 }
@@ -128,8 +148,8 @@ func (k AccountDetailKeyMap) ShortHelp() []key.Binding {
 func (k AccountDetailKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Enter},
-		{k.NewTx, k.DeleteTx, k.ToggleDivs},
-		{k.Summarize, k.Back, k.Help, k.Quit},
+		{k.NewTx, k.DeleteTx, k.ToggleDivs, k.ToggleHoldings},
+		{k.Allocation, k.Dividends, k.Summarize, k.Back, k.Help, k.Quit},
 	}
 }
 
@@ -1,11 +1,20 @@
 package tui
 
-import "tracker/types"
+import (
+	"tracker/config"
+	"tracker/types"
+)
 
 type DataLoadedMsg struct {
 	Accounts     *[]types.Account
 	AccountsData map[string]types.AnalyzedPortfolio
 	AllPortfolio types.AnalyzedPortfolio
+	Prices       map[string]types.SymbolPrice
+	Symbols      map[string]types.SymbolInfo
+
+	AccountBenchmarks map[string]types.BenchmarkComparison
+	AllBenchmark      types.BenchmarkComparison
+	AccountRisk       map[string]types.RiskMetrics
 }
 
 type AccountSelectedMsg struct {
@@ -15,7 +24,7 @@ type AccountSelectedMsg struct {
 type BackToAccountsMsg struct{}
 
 type CurrencyChangedMsg struct {
-	Symbol       string
+	Currency     config.DisplayCurrency
 	ExchangeRate float64
 }
 
@@ -52,6 +61,10 @@ const (
 	ModalDeleteConfirm
 	ModalAbandonConfirm
 	ModalInsights
+	ModalAllocation
+	ModalRebalance
+	ModalSimulation
+	ModalDividends
 )
 
 type ErrorMsg struct {
@@ -77,3 +90,17 @@ type InsightsErrorMsg struct {
 	Title string
 	Error string
 }
+
+type RebalanceLoadedMsg struct {
+	Plan types.RebalancePlan
+}
+
+type DividendsLoadedMsg struct {
+	Title    string
+	Forecast types.DividendForecast
+	Report   types.DividendReport
+}
+
+type SimulationLoadedMsg struct {
+	Simulation types.TradeSimulation
+}
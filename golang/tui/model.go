@@ -3,11 +3,11 @@ package tui
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"tracker/config"
 	"tracker/llm"
 	"tracker/loaders"
-	"tracker/market"
 	"tracker/portfolio"
 	"tracker/tui/components"
 	"tracker/tui/forms"
@@ -45,21 +45,35 @@ type Model struct {
 	accountsView      views.AccountsView
 	accountDetailView views.AccountDetailView
 	insightsView      *views.InsightsView
+	allocationView    *views.AllocationView
+	rebalanceView     *views.RebalanceView
+	simulationView    *views.SimulationView
+	dividendsView     *views.DividendsView
 	transactionForm   forms.TransactionForm
 	confirmDialog     forms.ConfirmDialog
 	pendingDeleteTx   *types.Transaction
 	styles            Styles
 	accounts          *[]types.Account
 	accountsData      map[string]types.AnalyzedPortfolio
+	prices            map[string]types.SymbolPrice
+	symbols           map[string]types.SymbolInfo
 	allPortfolio      types.AnalyzedPortfolio
 	selectedAccount   types.Account
-	currencySymbol    string
+	currencies        config.CurrencyConfig
+	currency          config.DisplayCurrency
 	exchangeRate      float64
 	tagFilter         string
 	tags              []string
 	tagIndex          int
 	showDividends     bool
-	dividendTaxRate   float64
+	dividendTaxes     config.DividendTaxConfig
+	gainTaxRate       float64
+	benchmarks        config.BenchmarkConfig
+	accountBenchmarks map[string]types.BenchmarkComparison
+	allBenchmark      types.BenchmarkComparison
+	riskFreeRate      float64
+	rebalanceBand     float64
+	accountRisk       map[string]types.RiskMetrics
 	statusText        string
 	err               error
 }
@@ -84,21 +98,26 @@ func NewModel(db *sql.DB, cfg config.AppConfig) Model {
 	statusBar.SetLoading(true)
 
 	return Model{
-		db:              db,
-		view:            ViewLoading,
-		loading:         true,
-		spinner:         s,
-		help:            h,
-		header:          header,
-		statusBar:       statusBar,
-		styles:          AppStyles,
-		currencySymbol:  market.CurrencySymbolUSD,
-		exchangeRate:    1.0,
-		tagFilter:       "All",
-		tags:            []string{"All"},
-		showDividends:   true,
-		dividendTaxRate: cfg.DividendTaxRate,
-		modalType:       ModalNone,
+		db:            db,
+		view:          ViewLoading,
+		loading:       true,
+		spinner:       s,
+		help:          h,
+		header:        header,
+		statusBar:     statusBar,
+		styles:        AppStyles,
+		currencies:    cfg.Currencies,
+		currency:      cfg.Currencies.Base(),
+		exchangeRate:  1.0,
+		tagFilter:     "All",
+		tags:          []string{"All"},
+		showDividends: true,
+		dividendTaxes: cfg.DividendTaxes,
+		gainTaxRate:   cfg.CapitalGainTaxRate,
+		benchmarks:    cfg.Benchmarks,
+		riskFreeRate:  cfg.RiskFreeRate,
+		rebalanceBand: cfg.RebalanceTolerance,
+		modalType:     ModalNone,
 	}
 }
 
@@ -110,6 +129,7 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) loadData() tea.Cmd {
+	currency := m.currency.Code
 	return func() tea.Msg {
 		accounts, err := loaders.UserAccounts(m.db)
 		if err != nil {
@@ -118,7 +138,7 @@ func (m Model) loadData() tea.Cmd {
 
 		accountsData := make(map[string]types.AnalyzedPortfolio, len(*accounts))
 		for _, ac := range *accounts {
-			data, err := portfolio.LoadAndAnalyze(m.db, ac)
+			data, err := portfolio.LoadAndAnalyzeIn(m.db, ac, currency)
 			if err != nil {
 				return ErrorMsg{Err: err}
 			}
@@ -129,29 +149,75 @@ func (m Model) loadData() tea.Cmd {
 		for _, ac := range *accounts {
 			accountIds = append(accountIds, ac.Id)
 		}
-		allPortfolio, _ := portfolio.LoadAndAnalyzeAccounts(m.db, accountIds)
+		allPortfolio, _ := portfolio.LoadAndAnalyzeAccountsIn(m.db, accountIds, currency)
+		accountBenchmarks, allBenchmark := m.loadBenchmarks(accounts, accountIds, "All", currency)
 
 		return DataLoadedMsg{
-			Accounts:     accounts,
-			AccountsData: accountsData,
-			AllPortfolio: allPortfolio,
+			Accounts:          accounts,
+			AccountsData:      accountsData,
+			AllPortfolio:      allPortfolio,
+			Prices:            loaders.AllPrices(m.db),
+			Symbols:           loadSymbols(m.db),
+			AccountBenchmarks: accountBenchmarks,
+			AllBenchmark:      allBenchmark,
+			AccountRisk:       m.loadRisk(accounts),
 		}
 	}
 }
 
-func (m *Model) loadExchangeRate(currency string) tea.Cmd {
+// addTransfer stores the two rows moving the shares of tx to the account to
+func (m Model) addTransfer(tx types.Transaction, to string) error {
+	out, in, err := portfolio.LoadTransfer(m.db, tx.AccountId, to, tx.Symbol, tx.Quantity, tx.Date)
+	if err != nil {
+		return err
+	}
+	return loaders.AddTransfer(m.db, out, in)
+}
+
+// loadBenchmarks compares every account with its benchmark, and the given accounts together with the benchmark of tag,
+// in currency
+func (m Model) loadBenchmarks(accounts *[]types.Account, accountIds []string, tag, currency string) (map[string]types.BenchmarkComparison, types.BenchmarkComparison) {
+	accountBenchmarks := make(map[string]types.BenchmarkComparison, len(*accounts))
+	for _, ac := range *accounts {
+		accountBenchmarks[ac.Id], _ = portfolio.LoadBenchmarkComparison(m.db, []string{ac.Id}, m.benchmarks.ForAccount(ac), currency)
+	}
+
+	allBenchmark, _ := portfolio.LoadBenchmarkComparison(m.db, accountIds, m.benchmarks.ForTag(tag), currency)
+	return accountBenchmarks, allBenchmark
+}
+
+// loadSymbols returns the symbols metadata, holdings are shown without it when it can't be loaded
+func loadSymbols(db *sql.DB) map[string]types.SymbolInfo {
+	symbols, err := loaders.SymbolsInfo(db)
+	if err != nil {
+		return map[string]types.SymbolInfo{}
+	}
+	return symbols
+}
+
+// loadRisk computes the risk metrics of every account
+func (m Model) loadRisk(accounts *[]types.Account) map[string]types.RiskMetrics {
+	accountRisk := make(map[string]types.RiskMetrics, len(*accounts))
+	for _, ac := range *accounts {
+		accountRisk[ac.Id], _ = portfolio.LoadRiskMetrics(m.db, []string{ac.Id}, m.riskFreeRate)
+	}
+	return accountRisk
+}
+
+func (m *Model) loadExchangeRate(currency config.DisplayCurrency) tea.Cmd {
 	m.statusBar.SetLoading(true)
 	m.statusBar.SetStatus("Loading exchange rate...")
 	return func() tea.Msg {
-		if currency == "USD" {
-			return CurrencyChangedMsg{Symbol: market.CurrencySymbolUSD, ExchangeRate: 1.0}
+		if currency.Code == types.CurrencyUSD {
+			return CurrencyChangedMsg{Currency: currency, ExchangeRate: 1.0}
 		}
-		rate := loaders.CurrencyExchangeRate(m.db, "ILS")
-		return CurrencyChangedMsg{Symbol: market.CurrencySymbolILS, ExchangeRate: rate}
+		rate := loaders.CurrencyExchangeRate(m.db, currency.Code)
+		return CurrencyChangedMsg{Currency: currency, ExchangeRate: rate}
 	}
 }
 
 func (m *Model) reloadAccountData() tea.Cmd {
+	currency := m.currency.Code
 	return func() tea.Msg {
 		accounts, err := loaders.UserAccounts(m.db)
 		if err != nil {
@@ -160,7 +226,7 @@ func (m *Model) reloadAccountData() tea.Cmd {
 
 		accountsData := make(map[string]types.AnalyzedPortfolio, len(*accounts))
 		for _, ac := range *accounts {
-			data, err := portfolio.LoadAndAnalyze(m.db, ac)
+			data, err := portfolio.LoadAndAnalyzeIn(m.db, ac, currency)
 			if err != nil {
 				return ErrorMsg{Err: err}
 			}
@@ -171,12 +237,18 @@ func (m *Model) reloadAccountData() tea.Cmd {
 		for _, ac := range *accounts {
 			accountIds = append(accountIds, ac.Id)
 		}
-		allPortfolio, _ := portfolio.LoadAndAnalyzeAccounts(m.db, accountIds)
+		allPortfolio, _ := portfolio.LoadAndAnalyzeAccountsIn(m.db, accountIds, currency)
+		accountBenchmarks, allBenchmark := m.loadBenchmarks(accounts, accountIds, "All", currency)
 
 		return DataLoadedMsg{
-			Accounts:     accounts,
-			AccountsData: accountsData,
-			AllPortfolio: allPortfolio,
+			Accounts:          accounts,
+			AccountsData:      accountsData,
+			AllPortfolio:      allPortfolio,
+			Prices:            loaders.AllPrices(m.db),
+			Symbols:           loadSymbols(m.db),
+			AccountBenchmarks: accountBenchmarks,
+			AllBenchmark:      allBenchmark,
+			AccountRisk:       m.loadRisk(accounts),
 		}
 	}
 }
@@ -236,6 +308,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.accounts = msg.Accounts
 		m.accountsData = msg.AccountsData
 		m.allPortfolio = msg.AllPortfolio
+		m.prices = msg.Prices
+		m.symbols = msg.Symbols
+		m.accountBenchmarks = msg.AccountBenchmarks
+		m.allBenchmark = msg.AllBenchmark
+		m.accountRisk = msg.AccountRisk
 		m.tags = collectUniqueTags(msg.Accounts)
 
 		if m.view == ViewLoading {
@@ -243,13 +320,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.accountsView = views.NewAccountsView(m.accounts, m.accountsData, m.allPortfolio)
+		m.accountsView.SetBenchmark(m.allBenchmark)
 		m.accountsView.SetSize(m.width, m.height-4)
-		m.accountsView.SetCurrency(m.currencySymbol, m.exchangeRate)
+		m.accountsView.SetCurrency(m.currency, m.exchangeRate)
 
 		if m.selectedAccount.Id != "" {
-			m.accountDetailView = views.NewAccountDetailView(m.selectedAccount, m.accountsData[m.selectedAccount.Id], m.dividendTaxRate)
+			m.accountDetailView = views.NewAccountDetailView(m.selectedAccount, m.accountsData[m.selectedAccount.Id], m.prices, m.dividendTaxes)
+			m.accountDetailView.SetBenchmark(m.accountBenchmarks[m.selectedAccount.Id])
+			m.accountDetailView.SetRisk(m.accountRisk[m.selectedAccount.Id])
+			m.accountDetailView.SetSymbols(m.symbols)
 			m.accountDetailView.SetSize(m.width, m.height-4)
-			m.accountDetailView.SetCurrency(m.currencySymbol, m.exchangeRate)
+			m.accountDetailView.SetCurrency(m.currency, m.exchangeRate)
 		}
 
 		m.statusText = fmt.Sprintf("%d accounts loaded", len(*msg.Accounts))
@@ -261,12 +342,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case CurrencyChangedMsg:
-		m.currencySymbol = msg.Symbol
+		m.currency = msg.Currency
 		m.exchangeRate = msg.ExchangeRate
-		m.accountsView.SetCurrency(msg.Symbol, msg.ExchangeRate)
-		m.accountDetailView.SetCurrency(msg.Symbol, msg.ExchangeRate)
-		m.statusBar.SetLoading(false)
-		m.statusBar.SetStatus("Currency: " + msg.Symbol)
+		// the portfolios are analyzed again in the new currency, at the rates of the days of their flows
+		m.statusBar.SetStatus("Currency: " + msg.Currency.Code)
+		return m, m.reloadAccountData()
 
 	case InsightsLoadedMsg:
 		iv := views.NewInsightsView(msg.Title, msg.Content)
@@ -276,6 +356,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusBar.SetLoading(false)
 		m.statusBar.SetStatus("")
 
+	case RebalanceLoadedMsg:
+		rv := views.NewRebalanceView(msg.Plan, m.currency)
+		rv.SetSize(m.width, m.height)
+		m.rebalanceView = &rv
+		m.modalType = ModalRebalance
+		m.statusBar.SetLoading(false)
+		m.statusBar.SetStatus("")
+
+	case DividendsLoadedMsg:
+		dv := views.NewDividendsView(msg.Title, msg.Forecast, msg.Report, m.currency)
+		dv.SetSize(m.width, m.height)
+		m.dividendsView = &dv
+		m.modalType = ModalDividends
+		m.statusBar.SetLoading(false)
+		m.statusBar.SetStatus("")
+
+	case SimulationLoadedMsg:
+		sv := views.NewSimulationView(msg.Simulation, m.currency)
+		sv.SetSize(m.width, m.height)
+		m.simulationView = &sv
+		m.modalType = ModalSimulation
+		m.statusBar.SetLoading(false)
+		m.statusBar.SetStatus("")
+
 	case InsightsErrorMsg:
 		iv := views.NewInsightsView(msg.Title, "Error generating insights:\n\n"+msg.Error)
 		iv.SetSize(m.width, m.height)
@@ -319,6 +423,18 @@ func (m Model) updateModal(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.modalType == ModalInsights && m.insightsView != nil {
 			m.insightsView.SetSize(msg.Width, msg.Height)
 		}
+		if m.modalType == ModalAllocation && m.allocationView != nil {
+			m.allocationView.SetSize(msg.Width, msg.Height)
+		}
+		if m.modalType == ModalRebalance && m.rebalanceView != nil {
+			m.rebalanceView.SetSize(msg.Width, msg.Height)
+		}
+		if m.modalType == ModalSimulation && m.simulationView != nil {
+			m.simulationView.SetSize(msg.Width, msg.Height)
+		}
+		if m.modalType == ModalDividends && m.dividendsView != nil {
+			m.dividendsView.SetSize(msg.Width, msg.Height)
+		}
 		return m, nil
 
 	case tea.KeyMsg:
@@ -333,6 +449,92 @@ func (m Model) updateModal(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.insightsView = nil
 				return m, nil
 			}
+			if m.modalType == ModalAllocation {
+				m.modalType = ModalNone
+				m.allocationView = nil
+				return m, nil
+			}
+			if m.modalType == ModalRebalance {
+				m.modalType = ModalNone
+				m.rebalanceView = nil
+				return m, nil
+			}
+			if m.modalType == ModalDividends {
+				m.modalType = ModalNone
+				m.dividendsView = nil
+				return m, nil
+			}
+			if m.modalType == ModalSimulation {
+				m.modalType = ModalNone
+				m.simulationView = nil
+				m.statusBar.SetStatus("Preview discarded")
+				return m, nil
+			}
+		}
+
+		// a previewed trade is saved from the preview, nothing was written before
+		if m.modalType == ModalSimulation && m.simulationView != nil {
+			switch {
+			case key.Matches(msg, Keys.Enter):
+				trades := m.simulationView.Trades()
+				for _, tx := range trades {
+					if err := loaders.AddTransaction(m.db, tx); err != nil {
+						m.statusBar.SetStatus("Error: " + err.Error())
+						return m, nil
+					}
+				}
+				m.modalType = ModalNone
+				m.simulationView = nil
+				return m, func() tea.Msg { return TransactionAddedMsg{Transaction: trades[len(trades)-1]} }
+			case key.Matches(msg, Keys.Up):
+				m.simulationView.ScrollUp()
+			case key.Matches(msg, Keys.Down):
+				m.simulationView.ScrollDown()
+			}
+			return m, nil
+		}
+
+		if m.modalType == ModalRebalance && m.rebalanceView != nil {
+			switch {
+			case key.Matches(msg, Keys.Back), key.Matches(msg, Keys.Rebalance):
+				m.modalType = ModalNone
+				m.rebalanceView = nil
+			case key.Matches(msg, Keys.CashOnly):
+				return m, m.loadRebalance(!m.rebalanceView.CashOnly())
+			case key.Matches(msg, Keys.Up):
+				m.rebalanceView.ScrollUp()
+			case key.Matches(msg, Keys.Down):
+				m.rebalanceView.ScrollDown()
+			}
+			return m, nil
+		}
+
+		if m.modalType == ModalDividends && m.dividendsView != nil {
+			switch {
+			case key.Matches(msg, Keys.Back), key.Matches(msg, Keys.Dividends):
+				m.modalType = ModalNone
+				m.dividendsView = nil
+			case key.Matches(msg, Keys.Tab):
+				m.dividendsView.ToggleHistory()
+			case key.Matches(msg, Keys.Up):
+				m.dividendsView.ScrollUp()
+			case key.Matches(msg, Keys.Down):
+				m.dividendsView.ScrollDown()
+			}
+			return m, nil
+		}
+
+		if m.modalType == ModalAllocation && m.allocationView != nil {
+			switch {
+			case key.Matches(msg, Keys.Back), key.Matches(msg, Keys.Allocation):
+				m.modalType = ModalNone
+				m.allocationView = nil
+			case key.Matches(msg, Keys.Up):
+				m.allocationView.ScrollUp()
+			case key.Matches(msg, Keys.Down):
+				m.allocationView.ScrollDown()
+			}
+			return m, nil
 		}
 
 		if m.modalType == ModalInsights {
@@ -362,9 +564,19 @@ func (m Model) updateModal(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.transactionForm.Cancelled() {
 				m.modalType = ModalNone
 				m.statusBar.SetStatus("Cancelled")
+			} else if m.transactionForm.Preview() {
+				m.modalType = ModalNone
+				m.statusBar.SetLoading(true)
+				m.statusBar.SetStatus("Simulating trade...")
+				return m, m.loadSimulation(m.transactionForm.Result())
 			} else {
 				tx := m.transactionForm.Result()
-				err := loaders.AddTransaction(m.db, tx)
+				var err error
+				if tx.Type == types.TransactionTypeTransfer {
+					err = m.addTransfer(tx, m.transactionForm.TransferTo())
+				} else {
+					err = loaders.AddTransaction(m.db, tx)
+				}
 				if err != nil {
 					m.statusBar.SetStatus("Error: " + err.Error())
 				} else {
@@ -405,7 +617,21 @@ func (m Model) updateModal(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, cmd
 
-	case ModalInsights:
+	case ModalInsights, ModalAllocation, ModalSimulation, ModalDividends:
+		return m, nil
+
+	case ModalRebalance:
+		// the plan is loaded again when switching to cash only
+		switch msg := msg.(type) {
+		case RebalanceLoadedMsg:
+			rv := views.NewRebalanceView(msg.Plan, m.currency)
+			rv.SetSize(m.width, m.height)
+			m.rebalanceView = &rv
+		case ErrorMsg:
+			m.modalType = ModalNone
+			m.rebalanceView = nil
+			m.statusBar.SetStatus("Error: " + msg.Error())
+		}
 		return m, nil
 	}
 
@@ -420,21 +646,20 @@ func (m Model) updateAccountsView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if account := m.accountsView.SelectedAccount(); account != nil {
 			m.selectedAccount = *account
 			m.view = ViewAccountDetail
-			m.accountDetailView = views.NewAccountDetailView(*account, m.accountsData[account.Id], m.dividendTaxRate)
+			m.accountDetailView = views.NewAccountDetailView(*account, m.accountsData[account.Id], m.prices, m.dividendTaxes)
+			m.accountDetailView.SetBenchmark(m.accountBenchmarks[account.Id])
+			m.accountDetailView.SetRisk(m.accountRisk[account.Id])
+			m.accountDetailView.SetSymbols(m.symbols)
 			m.accountDetailView.SetSize(m.width, m.height-4)
-			m.accountDetailView.SetCurrency(m.currencySymbol, m.exchangeRate)
+			m.accountDetailView.SetCurrency(m.currency, m.exchangeRate)
 			m.header.SetSubtitle(account.Name)
 			m.statusBar.SetMode("DETAIL")
 			m.statusBar.SetStatus("")
 		}
 		return m, nil
 
-	case key.Matches(msg, Keys.CurrencyUSD):
-		cmd = m.loadExchangeRate("USD")
-		return m, cmd
-
-	case key.Matches(msg, Keys.CurrencyNIS):
-		cmd = m.loadExchangeRate("NIS")
+	case key.Matches(msg, Keys.CycleCurrency):
+		cmd = m.loadExchangeRate(m.currencies.Next(m.currency.Code))
 		return m, cmd
 
 	case key.Matches(msg, Keys.CycleTag):
@@ -443,9 +668,11 @@ func (m Model) updateAccountsView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.statusBar.SetStatus("Tag: " + newTag)
 
 		filteredIds := m.getFilteredAccountIds()
-		allPortfolio, _ := portfolio.LoadAndAnalyzeAccounts(m.db, filteredIds)
+		allPortfolio, _ := portfolio.LoadAndAnalyzeAccountsIn(m.db, filteredIds, m.currency.Code)
 		m.allPortfolio = allPortfolio
 		m.accountsView.SetAllPortfolio(allPortfolio)
+		m.allBenchmark, _ = portfolio.LoadBenchmarkComparison(m.db, filteredIds, m.benchmarks.ForTag(newTag), m.currency.Code)
+		m.accountsView.SetBenchmark(m.allBenchmark)
 		return m, nil
 
 	case key.Matches(msg, Keys.Summarize):
@@ -453,6 +680,28 @@ func (m Model) updateAccountsView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.statusBar.SetStatus("Generating portfolio insights...")
 		return m, m.getPortfolioInsights()
 
+	case key.Matches(msg, Keys.Allocation):
+		title := "Allocation"
+		if m.tagFilter != "All" {
+			title += " │ " + m.tagFilter
+		}
+		m.showAllocation(title, m.filteredAccounts())
+		return m, nil
+
+	case key.Matches(msg, Keys.Rebalance):
+		m.statusBar.SetLoading(true)
+		m.statusBar.SetStatus("Planning rebalance...")
+		return m, m.loadRebalance(false)
+
+	case key.Matches(msg, Keys.Dividends):
+		title := "Dividends"
+		if m.tagFilter != "All" {
+			title += " │ " + m.tagFilter
+		}
+		m.statusBar.SetLoading(true)
+		m.statusBar.SetStatus("Loading dividends...")
+		return m, m.loadDividends(title, m.filteredAccounts())
+
 	default:
 		m.accountsView, cmd = m.accountsView.Update(msg)
 		return m, cmd
@@ -474,27 +723,53 @@ func (m Model) updateAccountDetailView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case key.Matches(msg, Keys.NewTx):
 		m.modalType = ModalAddTransaction
-		m.transactionForm = forms.NewTransactionForm(m.selectedAccount.Id)
+		var accounts []types.Account
+		if m.accounts != nil {
+			accounts = *m.accounts
+		}
+		m.transactionForm = forms.NewTransactionForm(m.selectedAccount.Id, accounts)
 		m.transactionForm.SetSize(m.width, m.height)
 		return m, m.transactionForm.Init()
 
 	case key.Matches(msg, Keys.DeleteTx):
 		if tx := m.accountDetailView.SelectedTransaction(); tx != nil {
-			if tx.Type == types.TransactionTypeBuy || tx.Type == types.TransactionTypeSell {
+			if tx.Type == types.TransactionTypeBuy || tx.Type == types.TransactionTypeSell || tx.Type.IsCash() || tx.Type == types.TransactionTypeTransfer {
 				m.pendingDeleteTx = tx
 				m.modalType = ModalDeleteConfirm
-				message := fmt.Sprintf("%s %s - %d shares @ %s",
+				message := fmt.Sprintf("%s %s - %s shares @ %s",
 					tx.Date.Format("2006-01-02"),
 					tx.Symbol,
 					tx.Quantity,
-					utils.ToCurrencyString(int64(tx.Pps), 2, m.currencySymbol, m.exchangeRate),
+					utils.FormatCurrency(tx.Pps, 2, m.currency.CurrencyFormat, 1),
 				)
+				if tx.Type == types.TransactionTypeTransfer {
+					message = fmt.Sprintf("%s %s - transfer of %s shares, both accounts' rows are deleted",
+						tx.Date.Format("2006-01-02"),
+						tx.Symbol,
+						tx.Quantity,
+					)
+				}
+				if tx.Type.IsCash() {
+					message = fmt.Sprintf("%s %s - %s",
+						tx.Date.Format("2006-01-02"),
+						tx.Type,
+						utils.FormatCurrency(tx.Pps, 2, m.currency.CurrencyFormat, 1),
+					)
+				}
 				m.confirmDialog = forms.NewDeleteConfirmDialog(message)
 				m.confirmDialog.SetSize(m.width, m.height)
 			}
 		}
 		return m, nil
 
+	case key.Matches(msg, Keys.ToggleHoldings):
+		if m.accountDetailView.ToggleHoldings() {
+			m.statusBar.SetStatus("Showing holdings")
+		} else {
+			m.statusBar.SetStatus("Showing transactions")
+		}
+		return m, nil
+
 	case key.Matches(msg, Keys.ToggleDivs):
 		showing := m.accountDetailView.ToggleDividends()
 		if showing {
@@ -504,12 +779,8 @@ func (m Model) updateAccountDetailView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case key.Matches(msg, Keys.CurrencyUSD):
-		cmd = m.loadExchangeRate("USD")
-		return m, cmd
-
-	case key.Matches(msg, Keys.CurrencyNIS):
-		cmd = m.loadExchangeRate("NIS")
+	case key.Matches(msg, Keys.CycleCurrency):
+		cmd = m.loadExchangeRate(m.currencies.Next(m.currency.Code))
 		return m, cmd
 
 	case key.Matches(msg, Keys.Summarize):
@@ -517,12 +788,97 @@ func (m Model) updateAccountDetailView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.statusBar.SetStatus("Generating account insights...")
 		return m, m.getAccountInsights()
 
+	case key.Matches(msg, Keys.Allocation):
+		m.showAllocation("Allocation │ "+m.selectedAccount.Name, []types.Account{m.selectedAccount})
+		return m, nil
+
+	case key.Matches(msg, Keys.Dividends):
+		m.statusBar.SetLoading(true)
+		m.statusBar.SetStatus("Loading dividends...")
+		return m, m.loadDividends("Dividends │ "+m.selectedAccount.Name, []types.Account{m.selectedAccount})
+
 	default:
 		m.accountDetailView, cmd = m.accountDetailView.Update(msg)
 		return m, cmd
 	}
 }
 
+// showAllocation opens the allocation of the holdings of accounts, in the display currency they are analyzed in
+func (m *Model) showAllocation(title string, accounts []types.Account) {
+	allocation, err := portfolio.BuildAllocation(accounts, m.accountsData, m.prices, m.symbols)
+	if err != nil {
+		m.statusBar.SetStatus("Error: " + err.Error())
+		return
+	}
+	av := views.NewAllocationView(title, allocation, m.currency)
+	av.SetSize(m.width, m.height)
+	m.allocationView = &av
+	m.modalType = ModalAllocation
+}
+
+// loadDividends forecasts the dividends of the accounts over the next 12 months in the display currency, along with
+// the history of the dividends they received
+func (m Model) loadDividends(title string, accounts []types.Account) tea.Cmd {
+	currency := m.currency.Code
+	taxes := m.dividendTaxes
+	accountIds := make([]string, 0, len(accounts))
+	for _, ac := range accounts {
+		accountIds = append(accountIds, ac.Id)
+	}
+	// the accounts are already analyzed in the display currency
+	tax := portfolio.DividendTax{Rates: taxes, Symbols: m.symbols}
+	report, reportErr := portfolio.BuildDividendReport(accounts, m.accountsData, time.Now(), tax)
+	return func() tea.Msg {
+		if reportErr != nil {
+			return ErrorMsg{Err: reportErr}
+		}
+		forecast, err := portfolio.LoadDividendForecast(m.db, accountIds, currency, taxes)
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return DividendsLoadedMsg{Title: title, Forecast: forecast, Report: report}
+	}
+}
+
+// loadSimulation previews tx on the selected account in the display currency, without saving it
+func (m Model) loadSimulation(tx types.Transaction) tea.Cmd {
+	account := m.selectedAccount
+	currency := m.currency.Code
+	opts := portfolio.SimulationOptions{CapitalGainsTaxRate: m.gainTaxRate, DividendTaxes: m.dividendTaxes}
+	return func() tea.Msg {
+		sim, err := portfolio.LoadSimulation(m.db, account, []types.Transaction{tx}, currency, opts)
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return SimulationLoadedMsg{Simulation: sim}
+	}
+}
+
+// loadRebalance plans the rebalancing of the accounts of the tag filter toward the targets of the tag
+func (m Model) loadRebalance(cashOnly bool) tea.Cmd {
+	accountIds := m.getFilteredAccountIds()
+	tag := m.tagFilter
+	currency := m.currency.Code
+	opts := portfolio.RebalanceOptions{Tolerance: m.rebalanceBand, CashOnly: cashOnly}
+	return func() tea.Msg {
+		plan, err := portfolio.LoadRebalancePlan(m.db, accountIds, tag, currency, opts)
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return RebalanceLoadedMsg{Plan: plan}
+	}
+}
+
+func (m Model) filteredAccounts() []types.Account {
+	var accounts []types.Account
+	for _, ac := range *m.accounts {
+		if m.tagFilter == "All" || hasTag(ac.Tags, m.tagFilter) {
+			accounts = append(accounts, ac)
+		}
+	}
+	return accounts
+}
+
 func (m Model) getFilteredAccountIds() []string {
 	var ids []string
 	for _, ac := range *m.accounts {
@@ -577,10 +933,66 @@ func (m Model) viewModal() string {
 		return m.confirmDialog.View()
 	case ModalInsights:
 		return m.viewInsightsModal()
+	case ModalAllocation:
+		return m.viewAllocationModal()
+	case ModalRebalance:
+		return m.viewRebalanceModal()
+	case ModalSimulation:
+		return m.viewSimulationModal()
+	case ModalDividends:
+		return m.viewDividendsModal()
 	}
 	return ""
 }
 
+func (m Model) viewRebalanceModal() string {
+	if m.rebalanceView == nil {
+		return ""
+	}
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		m.rebalanceView.View(),
+		lipgloss.WithWhitespaceBackground(lipgloss.Color("#1a1a2e")),
+	)
+}
+
+func (m Model) viewDividendsModal() string {
+	if m.dividendsView == nil {
+		return ""
+	}
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		m.dividendsView.View(),
+		lipgloss.WithWhitespaceBackground(lipgloss.Color("#1a1a2e")),
+	)
+}
+
+func (m Model) viewSimulationModal() string {
+	if m.simulationView == nil {
+		return ""
+	}
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		m.simulationView.View(),
+		lipgloss.WithWhitespaceBackground(lipgloss.Color("#1a1a2e")),
+	)
+}
+
+func (m Model) viewAllocationModal() string {
+	if m.allocationView == nil {
+		return ""
+	}
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		m.allocationView.View(),
+		lipgloss.WithWhitespaceBackground(lipgloss.Color("#1a1a2e")),
+	)
+}
+
 func (m Model) viewInsightsModal() string {
 	if m.insightsView == nil {
 		return ""
@@ -664,7 +1076,7 @@ func (m Model) getPortfolioInsights() tea.Cmd {
 					Date:     tx.Date.Format("2006-01-02"),
 					Action:   string(tx.Type),
 					Symbol:   tx.Symbol,
-					Quantity: fmt.Sprintf("%.2f", float64(tx.Quantity)),
+					Quantity: tx.Quantity.String(),
 					Price:    fmt.Sprintf("%.2f", float64(tx.Pps)/100),
 				})
 			}
@@ -672,7 +1084,7 @@ func (m Model) getPortfolioInsights() tea.Cmd {
 
 		// Get metrics from the portfolio
 		portfolioData := m.allPortfolio
-		if m.tagFilter != "All" {
+		if m.tagFilter != "All" || m.currency.Code != types.CurrencyUSD {
 			portfolioData, _ = portfolio.LoadAndAnalyzeAccounts(m.db, filteredIds)
 		}
 
@@ -684,10 +1096,18 @@ func (m Model) getPortfolioInsights() tea.Cmd {
 			UnrealizedGainPercent: fmt.Sprintf("%.2f", float64(portfolioData.Gain)),
 			DividendsReceived:     fmt.Sprintf("%.2f", float64(portfolioData.TotalDividends)/100),
 			YieldOnCost:           fmt.Sprintf("%.2f", float64(portfolioData.AnnualizedYield)),
+			TimeWeightedReturn:    views.FormatTimeWeightedReturns(portfolioData.TimeWeightedReturns),
 		}
+		risk, _ := portfolio.LoadRiskMetrics(m.db, filteredIds, m.riskFreeRate)
+		setRiskMetrics(&metrics, risk)
 
-		// Build prompt with empty holdings (would need more data structure)
-		var holdings []llm.PortfolioData
+		holdings, err := m.holdingsData(portfolioData)
+		if err != nil {
+			return InsightsErrorMsg{
+				Title: "Portfolio Insights",
+				Error: err.Error(),
+			}
+		}
 		userInput := llm.ContextBuilder("Full Portfolio", holdings, transactions, metrics)
 
 		// Get response from Responses API
@@ -706,6 +1126,46 @@ func (m Model) getPortfolioInsights() tea.Cmd {
 	}
 }
 
+// holdingsData describes the current holdings of p together with their metadata, p is expected in USD
+func (m Model) holdingsData(p types.AnalyzedPortfolio) ([]llm.PortfolioData, error) {
+	rows, err := portfolio.BuildHoldingRows(p, m.prices, m.symbols)
+	if err != nil {
+		return nil, err
+	}
+	holdings := make([]llm.PortfolioData, 0, len(rows))
+	for _, h := range rows {
+		holdings = append(holdings, llm.PortfolioData{
+			Symbol:            h.Symbol,
+			Name:              h.Info.DisplayName(),
+			Quantity:          h.Quantity.String(),
+			CurrentPrice:      fmt.Sprintf("%.2f", float64(h.Price)/100),
+			CurrentValue:      fmt.Sprintf("%.2f", float64(h.MarketValue)/100),
+			Sector:            h.Info.Sector,
+			AssetClass:        string(h.Info.AssetClass),
+			Country:           h.Info.Country,
+			AllocationPercent: fmt.Sprintf("%.2f", h.Allocation*100),
+		})
+	}
+	return holdings, nil
+}
+
+// setRiskMetrics fills the risk fields of metrics, they are left empty when there is not enough history
+func setRiskMetrics(metrics *llm.MetricsData, risk types.RiskMetrics) {
+	if !risk.Available {
+		return
+	}
+
+	metrics.Volatility = fmt.Sprintf("%.2f", risk.Volatility*100)
+	metrics.MaxDrawdown = fmt.Sprintf("%.2f", risk.MaxDrawdown*100)
+	if !risk.PeakDate.IsZero() {
+		metrics.MaxDrawdownPeriod = risk.PeakDate.Format("2006-01-02") + " to " + risk.TroughDate.Format("2006-01-02")
+	}
+	metrics.CurrentDrawdown = fmt.Sprintf("%.2f", risk.CurrentDrawdown*100)
+	metrics.SharpeRatio = fmt.Sprintf("%.2f", risk.Sharpe)
+	metrics.SortinoRatio = fmt.Sprintf("%.2f", risk.Sortino)
+	metrics.RiskFreeRate = fmt.Sprintf("%.2f", risk.RiskFreeRate*100)
+}
+
 func (m Model) getAccountInsights() tea.Cmd {
 	return func() tea.Msg {
 		client, err := llm.NewClient()
@@ -717,6 +1177,9 @@ func (m Model) getAccountInsights() tea.Cmd {
 		}
 
 		accountData := m.accountsData[m.selectedAccount.Id]
+		if m.currency.Code != types.CurrencyUSD {
+			accountData, _ = portfolio.LoadAndAnalyze(m.db, m.selectedAccount)
+		}
 
 		// Get transactions data
 		var transactions []llm.TransactionData
@@ -725,7 +1188,7 @@ func (m Model) getAccountInsights() tea.Cmd {
 				Date:     tx.Date.Format("2006-01-02"),
 				Action:   string(tx.Type),
 				Symbol:   tx.Symbol,
-				Quantity: fmt.Sprintf("%.2f", float64(tx.Quantity)),
+				Quantity: tx.Quantity.String(),
 				Price:    fmt.Sprintf("%.2f", float64(tx.Pps)/100),
 			})
 		}
@@ -739,10 +1202,17 @@ func (m Model) getAccountInsights() tea.Cmd {
 			UnrealizedGainPercent: fmt.Sprintf("%.2f", float64(accountData.Gain)),
 			DividendsReceived:     fmt.Sprintf("%.2f", float64(accountData.TotalDividends)/100),
 			YieldOnCost:           fmt.Sprintf("%.2f", float64(accountData.AnnualizedYield)),
+			TimeWeightedReturn:    views.FormatTimeWeightedReturns(accountData.TimeWeightedReturns),
 		}
+		setRiskMetrics(&metrics, m.accountRisk[m.selectedAccount.Id])
 
-		// Build prompt with empty holdings (would need more data structure)
-		var holdings []llm.PortfolioData
+		holdings, err := m.holdingsData(accountData)
+		if err != nil {
+			return InsightsErrorMsg{
+				Title: fmt.Sprintf("Account: %s Insights", m.selectedAccount.Name),
+				Error: err.Error(),
+			}
+		}
 		userInput := llm.ContextBuilder(m.selectedAccount.Name, holdings, transactions, metrics)
 
 		// Get response from Responses API
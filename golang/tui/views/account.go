@@ -17,18 +17,23 @@ import (
 )
 
 type AccountDetailView struct {
-	table           table.Model
-	account         types.Account
-	portfolio       types.AnalyzedPortfolio
-	transactions    []types.Transaction
-	width           int
-	height          int
-	currencySymbol  string
-	exchangeRate    float64
-	dividendTaxRate float64
-	showDividends   bool
-	styles          AccountDetailStyles
-	focused         bool
+	table         table.Model
+	account       types.Account
+	portfolio     types.AnalyzedPortfolio
+	transactions  []types.Transaction
+	prices        map[string]types.SymbolPrice
+	symbols       map[string]types.SymbolInfo
+	benchmark     types.BenchmarkComparison
+	risk          types.RiskMetrics
+	width         int
+	height        int
+	currency      config.DisplayCurrency
+	exchangeRate  float64
+	dividendTaxes config.DividendTaxConfig
+	showDividends bool
+	showHoldings  bool
+	styles        AccountDetailStyles
+	focused       bool
 }
 
 type AccountDetailStyles struct {
@@ -78,17 +83,18 @@ func DefaultAccountDetailStyles() AccountDetailStyles {
 	}
 }
 
-func NewAccountDetailView(account types.Account, portfolio types.AnalyzedPortfolio, dividendTaxRate float64) AccountDetailView {
+func NewAccountDetailView(account types.Account, portfolio types.AnalyzedPortfolio, prices map[string]types.SymbolPrice, dividendTaxes config.DividendTaxConfig) AccountDetailView {
 	v := AccountDetailView{
-		account:         account,
-		portfolio:       portfolio,
-		transactions:    portfolio.Transactions,
-		currencySymbol:  "$",
-		exchangeRate:    1.0,
-		dividendTaxRate: dividendTaxRate,
-		showDividends:   true,
-		styles:          DefaultAccountDetailStyles(),
-		focused:         true,
+		account:       account,
+		portfolio:     portfolio,
+		transactions:  portfolio.ReportingTransactions,
+		prices:        prices,
+		currency:      config.BaseCurrency,
+		exchangeRate:  1.0,
+		dividendTaxes: dividendTaxes,
+		showDividends: true,
+		styles:        DefaultAccountDetailStyles(),
+		focused:       true,
 	}
 	return v
 }
@@ -99,19 +105,48 @@ func (v *AccountDetailView) SetSize(width, height int) {
 	v.rebuildTable()
 }
 
-func (v *AccountDetailView) SetCurrency(symbol string, rate float64) {
-	v.currencySymbol = symbol
+// SetCurrency sets the displayed currency, the portfolio, its transactions and the benchmark are expected to be
+// analyzed in it already and rate is only shown next to it
+func (v *AccountDetailView) SetCurrency(currency config.DisplayCurrency, rate float64) {
+	v.currency = currency
 	v.exchangeRate = rate
 	v.rebuildTable()
 }
 
+func (v *AccountDetailView) SetBenchmark(b types.BenchmarkComparison) {
+	v.benchmark = b
+}
+
+func (v *AccountDetailView) SetRisk(r types.RiskMetrics) {
+	v.risk = r
+}
+
+func (v *AccountDetailView) SetSymbols(symbols map[string]types.SymbolInfo) {
+	v.symbols = symbols
+	v.rebuildTable()
+}
+
 func (v *AccountDetailView) ToggleDividends() bool {
 	v.showDividends = !v.showDividends
 	v.rebuildTable()
 	return v.showDividends
 }
 
+func (v *AccountDetailView) ToggleHoldings() bool {
+	v.showHoldings = !v.showHoldings
+	v.rebuildTable()
+	return v.showHoldings
+}
+
+func (v *AccountDetailView) ShowingHoldings() bool {
+	return v.showHoldings
+}
+
 func (v *AccountDetailView) SelectedTransaction() *types.Transaction {
+	if v.showHoldings {
+		return nil
+	}
+
 	selected := v.table.SelectedRow()
 	if len(selected) == 0 {
 		return nil
@@ -142,7 +177,7 @@ func (v *AccountDetailView) Update(msg tea.Msg) (AccountDetailView, tea.Cmd) {
 }
 
 func (v *AccountDetailView) rebuildTable() {
-	infoHeight := 8
+	infoHeight := 11
 	tableHeight := v.height - infoHeight - 2
 	if tableHeight < 5 {
 		tableHeight = 5
@@ -150,6 +185,10 @@ func (v *AccountDetailView) rebuildTable() {
 
 	columns := v.buildColumns()
 	rows := v.buildRows()
+	if v.showHoldings {
+		columns = v.buildHoldingColumns()
+		rows = v.buildHoldingRows()
+	}
 
 	t := table.New(
 		table.WithColumns(columns),
@@ -180,12 +219,13 @@ func (v *AccountDetailView) rebuildTable() {
 func (v *AccountDetailView) buildColumns() []table.Column {
 	w := v.width - 8
 	return []table.Column{
-		{Title: "Date", Width: w * 15 / 100},
-		{Title: "Type", Width: w * 12 / 100},
-		{Title: "Symbol", Width: w * 15 / 100},
-		{Title: "Quantity", Width: w * 12 / 100},
-		{Title: "Price", Width: w * 18 / 100},
-		{Title: "Total", Width: w * 18 / 100},
+		{Title: "Date", Width: w * 13 / 100},
+		{Title: "Type", Width: w * 11 / 100},
+		{Title: "Symbol", Width: w * 12 / 100},
+		{Title: "Quantity", Width: w * 11 / 100},
+		{Title: "Price", Width: w * 15 / 100},
+		{Title: "Fee", Width: w * 11 / 100},
+		{Title: "Total", Width: w * 17 / 100},
 	}
 }
 
@@ -195,13 +235,75 @@ func (v *AccountDetailView) buildRows() []table.Row {
 
 	for _, row := range displayed {
 		tx := row.Transaction
+		quantity := row.Quantity.String()
+		price := utils.FormatCurrency(tx.Pps, 2, v.currency.CurrencyFormat, 1)
+		fee := ""
+		if tx.Fee != 0 {
+			fee = utils.FormatCurrency(tx.Fee, 2, v.currency.CurrencyFormat, 1)
+		}
+		if tx.Type.IsCash() {
+			quantity, price = "", ""
+		}
+		symbol := tx.Symbol
+		if tx.Action != nil {
+			symbol = tx.Symbol + " → " + tx.Action.NewSymbol
+			price = ""
+		}
 		rows = append(rows, table.Row{
 			tx.Date.Format("2006-01-02"),
 			string(tx.Type),
-			tx.Symbol,
-			fmt.Sprintf("%d", row.Quantity),
-			utils.ToCurrencyString(int64(tx.Pps), 2, v.currencySymbol, v.exchangeRate),
-			utils.ToCurrencyString(row.Total, 2, v.currencySymbol, v.exchangeRate),
+			symbol,
+			quantity,
+			price,
+			fee,
+			utils.FormatCurrency(row.Total, 2, v.currency.CurrencyFormat, 1),
+		})
+	}
+
+	return rows
+}
+
+func (v *AccountDetailView) buildHoldingColumns() []table.Column {
+	w := v.width - 8
+	return []table.Column{
+		{Title: "Symbol", Width: w * 8 / 100},
+		{Title: "Name", Width: w * 15 / 100},
+		{Title: "Shares", Width: w * 7 / 100},
+		{Title: "Last Price", Width: w * 9 / 100},
+		{Title: "Value", Width: w * 11 / 100},
+		{Title: "Cost Basis", Width: w * 11 / 100},
+		{Title: "Unrealized", Width: w * 11 / 100},
+		{Title: "Alloc", Width: w * 7 / 100},
+		{Title: "Dividends", Width: w * 10 / 100},
+		{Title: "Updated", Width: w * 9 / 100},
+	}
+}
+
+func (v *AccountDetailView) buildHoldingRows() []table.Row {
+	var rows []table.Row
+
+	// the analysis already checked the same sums, a holding that doesn't fit leaves the table empty
+	holdings, err := portfolio.BuildHoldingRows(v.portfolio, v.prices, v.symbols)
+	if err != nil {
+		return rows
+	}
+	for _, h := range holdings {
+		updated := "-"
+		if !h.PriceDate.IsZero() {
+			updated = h.PriceDate.Format("2006-01-02")
+		}
+
+		rows = append(rows, table.Row{
+			h.Symbol,
+			h.Info.Name,
+			h.Quantity.String(),
+			utils.FormatCurrency(h.Price, 2, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(h.MarketValue, 0, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(h.CostBasis, 0, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(h.Unrealized, 0, v.currency.CurrencyFormat, 1),
+			utils.ToYieldString(h.Allocation),
+			utils.FormatCurrency(h.Dividends, 0, v.currency.CurrencyFormat, 1),
+			updated,
 		})
 	}
 
@@ -215,18 +317,20 @@ func (v *AccountDetailView) getDisplayedTransactionRows() []portfolio.Transactio
 func (v AccountDetailView) View() string {
 	header := v.renderHeader()
 	info := v.renderInfo()
+	risk := v.renderRisk()
 	tableView := v.table.View()
 
 	return lipgloss.JoinVertical(lipgloss.Left,
 		header,
 		info,
+		risk,
 		tableView,
 	)
 }
 
 func (v AccountDetailView) renderHeader() string {
 	title := v.styles.Title.Render(v.account.Name)
-	subtitle := v.styles.Subtitle.Render(" │ " + v.account.Institution)
+	subtitle := v.styles.Subtitle.Render(" │ " + v.account.Institution + " │ " + types.NormalizeCurrency(v.account.BaseCurrency))
 
 	return v.styles.Header.Width(v.width).Render(
 		lipgloss.JoinHorizontal(lipgloss.Left, title, subtitle),
@@ -234,14 +338,16 @@ func (v AccountDetailView) renderHeader() string {
 }
 
 func (v AccountDetailView) renderInfo() string {
-	multiplier := v.exchangeRate
-	dividendsAfterTax := config.DividendsAfterTax(v.portfolio.TotalDividends, v.dividendTaxRate)
-	taxPercent := math.Round(v.dividendTaxRate*1000) / 10
-
-	currencyDisplay := v.currencySymbol
-	if v.currencySymbol == "₪" && v.exchangeRate != 1.0 {
-		currencyDisplay = fmt.Sprintf("%s (%.2f)", v.currencySymbol, v.exchangeRate)
+	// each dividend is taxed at the rate of the account and the country of the symbol paying it
+	tax := portfolio.DividendTax{Rates: v.dividendTaxes, Symbols: v.symbols}
+	dividendsAfterTax, err := tax.AfterTax(v.account.Id, v.portfolio.DividendFlows)
+	if err != nil {
+		// a sum overflowed, fall back to the default rate on the total
+		dividendsAfterTax = config.DividendsAfterTax(v.portfolio.TotalDividends, tax.Rates.Default)
 	}
+	taxPercent := math.Round(tax.EffectiveRate(v.portfolio.TotalDividends, dividendsAfterTax)*1000) / 10
+
+	currencyDisplay := CurrencyDisplay(v.currency, v.exchangeRate)
 
 	col1 := lipgloss.JoinVertical(lipgloss.Left,
 		lipgloss.JoinHorizontal(lipgloss.Left,
@@ -250,19 +356,23 @@ func (v AccountDetailView) renderInfo() string {
 		),
 		lipgloss.JoinHorizontal(lipgloss.Left,
 			v.styles.InfoLabel.Render("Value: "),
-			v.styles.Positive.Render(utils.ToCurrencyString(v.portfolio.Value, 0, v.currencySymbol, multiplier)),
+			v.styles.Positive.Render(utils.FormatCurrency(v.portfolio.Value, 0, v.currency.CurrencyFormat, 1)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Cash: "),
+			v.valueStyle(v.portfolio.CashBalance).Render(utils.FormatCurrency(v.portfolio.CashBalance, 0, v.currency.CurrencyFormat, 1)),
 		),
 		lipgloss.JoinHorizontal(lipgloss.Left,
 			v.styles.InfoLabel.Render("Invested: "),
-			v.styles.InfoValue.Render(utils.ToCurrencyString(v.portfolio.TotalInvested, 0, v.currencySymbol, multiplier)),
+			v.styles.InfoValue.Render(utils.FormatCurrency(v.portfolio.TotalInvested, 0, v.currency.CurrencyFormat, 1)),
 		),
 		lipgloss.JoinHorizontal(lipgloss.Left,
 			v.styles.InfoLabel.Render("Dividends: "),
-			v.styles.InfoValue.Render(utils.ToCurrencyString(v.portfolio.TotalDividends, 0, v.currencySymbol, multiplier)),
+			v.styles.InfoValue.Render(utils.FormatCurrency(v.portfolio.TotalDividends, 0, v.currency.CurrencyFormat, 1)),
 		),
 		lipgloss.JoinHorizontal(lipgloss.Left,
 			v.styles.InfoLabel.Render(fmt.Sprintf("Dividends (After %.1f%% Tax): ", taxPercent)),
-			v.styles.InfoValue.Render(utils.ToCurrencyString(dividendsAfterTax, 0, v.currencySymbol, multiplier)),
+			v.styles.InfoValue.Render(utils.FormatCurrency(dividendsAfterTax, 0, v.currency.CurrencyFormat, 1)),
 		),
 	)
 
@@ -284,6 +394,26 @@ func (v AccountDetailView) renderInfo() string {
 			v.styles.InfoLabel.Render("Dietz: "),
 			v.styles.InfoValue.Render(utils.ToYieldString(v.portfolio.ModifiedDietzYield)),
 		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("XIRR: "),
+			v.styles.InfoValue.Render(utils.ToYieldString(v.portfolio.XIRR)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Local Gain: "),
+			v.styles.InfoValue.Render(utils.ToYieldString(v.portfolio.LocalGain)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("FX Gain: "),
+			v.valueStyle(v.portfolio.FxGainValue).Render(utils.FormatCurrency(v.portfolio.FxGainValue, 0, v.currency.CurrencyFormat, 1)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Realized: "),
+			v.valueStyle(v.portfolio.RealizedGain).Render(utils.FormatCurrency(v.portfolio.RealizedGain, 0, v.currency.CurrencyFormat, 1)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Unrealized: "),
+			v.valueStyle(v.portfolio.UnrealizedGain).Render(utils.FormatCurrency(v.portfolio.UnrealizedGain, 0, v.currency.CurrencyFormat, 1)),
+		),
 	)
 
 	var holdingsText string
@@ -295,7 +425,7 @@ func (v AccountDetailView) renderInfo() string {
 	for _, symbol := range symbols {
 		count := v.portfolio.SymbolsCount[symbol]
 		if count > 0 {
-			holdingsText += fmt.Sprintf("%s(%d) ", symbol, count)
+			holdingsText += fmt.Sprintf("%s(%s) ", symbol, count)
 		}
 	}
 	if holdingsText == "" {
@@ -315,6 +445,18 @@ func (v AccountDetailView) renderInfo() string {
 			v.styles.InfoLabel.Render("Tags: "),
 			v.styles.Tags.Render(v.formatTags()),
 		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("TWR: "),
+			v.styles.InfoValue.Render(FormatTimeWeightedReturns(v.portfolio.TimeWeightedReturns)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Benchmark: "),
+			v.styles.InfoValue.Render(FormatBenchmark(v.benchmark, v.currency)),
+		),
+		lipgloss.JoinHorizontal(lipgloss.Left,
+			v.styles.InfoLabel.Render("Fees: "),
+			v.styles.InfoValue.Render(utils.FormatCurrency(v.portfolio.TotalFees, 0, v.currency.CurrencyFormat, 1)),
+		),
 	)
 
 	colWidth := (v.width - 8) / 3
@@ -327,6 +469,76 @@ func (v AccountDetailView) renderInfo() string {
 	)
 }
 
+func (v AccountDetailView) renderRisk() string {
+	title := v.styles.Subtitle.Render("Risk  ")
+	if !v.risk.Available {
+		return v.styles.InfoSection.Render(title + v.styles.InfoLabel.Render("not enough price history"))
+	}
+
+	drawdown := utils.ToYieldString(v.risk.MaxDrawdown)
+	if !v.risk.PeakDate.IsZero() {
+		drawdown += fmt.Sprintf(" (%s → %s)", v.risk.PeakDate.Format("2006-01-02"), v.risk.TroughDate.Format("2006-01-02"))
+	}
+
+	return v.styles.InfoSection.Render(lipgloss.JoinHorizontal(lipgloss.Left,
+		title,
+		v.styles.InfoLabel.Render("Volatility: "),
+		v.styles.InfoValue.Render(utils.ToYieldString(v.risk.Volatility)),
+		v.styles.InfoLabel.Render("  │  Max Drawdown: "),
+		v.styles.Negative.Render(drawdown),
+		v.styles.InfoLabel.Render("  │  Current Drawdown: "),
+		v.styles.InfoValue.Render(utils.ToYieldString(v.risk.CurrentDrawdown)),
+		v.styles.InfoLabel.Render(fmt.Sprintf("  │  Sharpe (rf %.1f%%): ", v.risk.RiskFreeRate*100)),
+		v.styles.InfoValue.Render(fmt.Sprintf("%.2f", v.risk.Sharpe)),
+		v.styles.InfoLabel.Render("  │  Sortino: "),
+		v.styles.InfoValue.Render(fmt.Sprintf("%.2f", v.risk.Sortino)),
+	))
+}
+
+func (v AccountDetailView) valueStyle(val types.Money) lipgloss.Style {
+	if val < 0 {
+		return v.styles.Negative
+	}
+	return v.styles.Positive
+}
+
+// FormatTimeWeightedReturns renders every TWR window on a single line, windows longer than a year are annualized
+func FormatTimeWeightedReturns(twr types.TimeWeightedReturns) string {
+	parts := make([]string, 0, 5)
+	for _, w := range twr.Windows() {
+		value := "-"
+		if w.Available {
+			value = utils.ToYieldString(w.Annualized)
+		}
+		parts = append(parts, w.Label+" "+value)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// CurrencyDisplay renders the currency with its rate to USD when it is not USD
+func CurrencyDisplay(currency config.DisplayCurrency, rate float64) string {
+	if currency.Code == types.CurrencyUSD {
+		return currency.Symbol
+	}
+	return fmt.Sprintf("%s %s (%.2f)", currency.Code, currency.Symbol, rate)
+}
+
+// FormatBenchmark renders the benchmark value and how far the portfolio is ahead of it, by gain and by TWR. The
+// comparison is expected to be built in currency already.
+func FormatBenchmark(b types.BenchmarkComparison, currency config.DisplayCurrency) string {
+	if b.Symbol == "" {
+		return "-"
+	}
+	if !b.Available {
+		return b.Symbol + " (no price history)"
+	}
+
+	return fmt.Sprintf("%s %s  diff %s  tracking %s", b.Symbol,
+		utils.FormatCurrency(b.Value, 0, currency.CurrencyFormat, 1),
+		utils.ToYieldString(b.ReturnDifference),
+		utils.ToYieldString(b.TrackingDifference))
+}
+
 func (v AccountDetailView) formatTags() string {
 	if len(v.account.Tags) == 0 {
 		return "-"
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"slices"
 
+	"tracker/config"
 	"tracker/types"
 	"tracker/utils"
 
@@ -17,9 +18,10 @@ type AccountsView struct {
 	accounts       *[]types.Account
 	accountsData   map[string]types.AnalyzedPortfolio
 	allPortfolio   types.AnalyzedPortfolio
+	benchmark      types.BenchmarkComparison
 	width          int
 	height         int
-	currencySymbol string
+	currency       config.DisplayCurrency
 	exchangeRate   float64
 	tagFilter      string
 	tags           []string
@@ -65,7 +67,7 @@ func NewAccountsView(accounts *[]types.Account, accountsData map[string]types.An
 		accounts:       accounts,
 		accountsData:   accountsData,
 		allPortfolio:   allPortfolio,
-		currencySymbol: "$",
+		currency:       config.BaseCurrency,
 		exchangeRate:   1.0,
 		tagFilter:      "All",
 		tags:           collectTags(accounts),
@@ -81,8 +83,10 @@ func (v *AccountsView) SetSize(width, height int) {
 	v.rebuildTable()
 }
 
-func (v *AccountsView) SetCurrency(symbol string, rate float64) {
-	v.currencySymbol = symbol
+// SetCurrency sets the displayed currency, the portfolios and the benchmark are expected to be analyzed in it
+// already and rate is only shown next to it
+func (v *AccountsView) SetCurrency(currency config.DisplayCurrency, rate float64) {
+	v.currency = currency
 	v.exchangeRate = rate
 	v.rebuildTable()
 }
@@ -104,6 +108,10 @@ func (v *AccountsView) SetAllPortfolio(p types.AnalyzedPortfolio) {
 	v.rebuildTable()
 }
 
+func (v *AccountsView) SetBenchmark(b types.BenchmarkComparison) {
+	v.benchmark = b
+}
+
 func (v *AccountsView) SelectedAccount() *types.Account {
 	selected := v.table.SelectedRow()
 	if len(selected) == 0 {
@@ -175,15 +183,17 @@ func (v *AccountsView) rebuildTable() {
 func (v *AccountsView) buildColumns() []table.Column {
 	w := v.width - 6
 	return []table.Column{
-		{Title: "ID", Width: w * 8 / 100},
-		{Title: "Account Name", Width: w * 16 / 100},
-		{Title: "Value", Width: w * 12 / 100},
-		{Title: "Invested", Width: w * 11 / 100},
-		{Title: "Withdrawn", Width: w * 11 / 100},
-		{Title: "Dividends", Width: w * 10 / 100},
-		{Title: "Gain", Width: w * 10 / 100},
-		{Title: "Annual", Width: w * 10 / 100},
-		{Title: "Dietz", Width: w * 10 / 100},
+		{Title: "ID", Width: w * 6 / 100},
+		{Title: "Account Name", Width: w * 13 / 100},
+		{Title: "Value", Width: w * 11 / 100},
+		{Title: "Invested", Width: w * 10 / 100},
+		{Title: "Withdrawn", Width: w * 9 / 100},
+		{Title: "Dividends", Width: w * 9 / 100},
+		{Title: "Gain", Width: w * 8 / 100},
+		{Title: "Annual", Width: w * 8 / 100},
+		{Title: "Dietz", Width: w * 8 / 100},
+		{Title: "TWR", Width: w * 8 / 100},
+		{Title: "XIRR", Width: w * 8 / 100},
 	}
 }
 
@@ -199,36 +209,37 @@ func (v *AccountsView) buildRows() []table.Row {
 		rows = append(rows, table.Row{
 			ac.Id,
 			ac.Name,
-			utils.ToCurrencyString(data.Value, 0, v.currencySymbol, v.exchangeRate),
-			utils.ToCurrencyString(data.TotalInvested, 0, v.currencySymbol, v.exchangeRate),
-			utils.ToCurrencyString(data.TotalWithdrawn, 0, v.currencySymbol, v.exchangeRate),
-			utils.ToCurrencyString(data.TotalDividends, 0, v.currencySymbol, v.exchangeRate),
+			utils.FormatCurrency(data.Value, 0, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(data.TotalInvested, 0, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(data.TotalWithdrawn, 0, v.currency.CurrencyFormat, 1),
+			utils.FormatCurrency(data.TotalDividends, 0, v.currency.CurrencyFormat, 1),
 			utils.ToYieldString(data.Gain),
 			utils.ToYieldString(data.AnnualizedYield),
 			utils.ToYieldString(data.ModifiedDietzYield),
+			utils.ToYieldString(data.TimeWeightedReturns.Inception.Annualized),
+			utils.ToYieldString(data.XIRR),
 		})
 	}
 
 	rows = append(rows, table.Row{
 		"",
 		"── All Portfolio ──",
-		utils.ToCurrencyString(v.allPortfolio.Value, 0, v.currencySymbol, v.exchangeRate),
-		utils.ToCurrencyString(v.allPortfolio.TotalInvested, 0, v.currencySymbol, v.exchangeRate),
-		utils.ToCurrencyString(v.allPortfolio.TotalWithdrawn, 0, v.currencySymbol, v.exchangeRate),
-		utils.ToCurrencyString(v.allPortfolio.TotalDividends, 0, v.currencySymbol, v.exchangeRate),
+		utils.FormatCurrency(v.allPortfolio.Value, 0, v.currency.CurrencyFormat, 1),
+		utils.FormatCurrency(v.allPortfolio.TotalInvested, 0, v.currency.CurrencyFormat, 1),
+		utils.FormatCurrency(v.allPortfolio.TotalWithdrawn, 0, v.currency.CurrencyFormat, 1),
+		utils.FormatCurrency(v.allPortfolio.TotalDividends, 0, v.currency.CurrencyFormat, 1),
 		utils.ToYieldString(v.allPortfolio.Gain),
 		utils.ToYieldString(v.allPortfolio.AnnualizedYield),
 		utils.ToYieldString(v.allPortfolio.ModifiedDietzYield),
+		utils.ToYieldString(v.allPortfolio.TimeWeightedReturns.Inception.Annualized),
+		utils.ToYieldString(v.allPortfolio.XIRR),
 	})
 
 	return rows
 }
 
 func (v AccountsView) View() string {
-	currencyDisplay := v.currencySymbol
-	if v.currencySymbol == "₪" && v.exchangeRate != 1.0 {
-		currencyDisplay = fmt.Sprintf("%s (%.2f)", v.currencySymbol, v.exchangeRate)
-	}
+	currencyDisplay := CurrencyDisplay(v.currency, v.exchangeRate)
 
 	infoBar := lipgloss.JoinHorizontal(lipgloss.Left,
 		v.styles.InfoLabel.Render("Currency: "),
@@ -236,6 +247,8 @@ func (v AccountsView) View() string {
 		v.styles.InfoLabel.Render("  │  Tag: "),
 		v.styles.InfoValue.Render(v.tagFilter),
 		v.styles.InfoLabel.Render(fmt.Sprintf("  │  Accounts: %d", v.filteredCount())),
+		v.styles.InfoLabel.Render("  │  Benchmark: "),
+		v.styles.InfoValue.Render(FormatBenchmark(v.benchmark, v.currency)),
 	)
 
 	tableView := v.table.View()
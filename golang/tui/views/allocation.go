@@ -0,0 +1,173 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// AllocationView is a modal with a horizontal bar chart per allocation group
+type AllocationView struct {
+	viewport   viewport.Model
+	title      string
+	allocation types.Allocation
+	currency   config.DisplayCurrency
+	styles     AllocationStyles
+	ready      bool
+}
+
+type AllocationStyles struct {
+	Container lipgloss.Style
+	Header    lipgloss.Style
+	Group     lipgloss.Style
+	Label     lipgloss.Style
+	Bar       lipgloss.Style
+	Track     lipgloss.Style
+	Value     lipgloss.Style
+	Footer    lipgloss.Style
+}
+
+func DefaultAllocationStyles() AllocationStyles {
+	return AllocationStyles{
+		Container: lipgloss.NewStyle().
+			Background(lipgloss.Color("#24283b")).
+			Foreground(lipgloss.Color("#e0e6f0")).
+			Padding(1, 2).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#7aa2f7")),
+		Header: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7dcfff")).
+			Bold(true).
+			MarginBottom(1),
+		Group: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ff9e64")).
+			Bold(true),
+		Label: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#e0e6f0")),
+		Bar: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#bb9af7")),
+		Track: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#414868")),
+		Value: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#737aa2")),
+		Footer: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#737aa2")).
+			Italic(true).
+			MarginTop(1),
+	}
+}
+
+// NewAllocationView shows allocation, its values are expected in currency already
+func NewAllocationView(title string, allocation types.Allocation, currency config.DisplayCurrency) AllocationView {
+	return AllocationView{
+		viewport:   viewport.New(0, 0),
+		title:      title,
+		allocation: allocation,
+		currency:   currency,
+		styles:     DefaultAllocationStyles(),
+	}
+}
+
+func (v *AllocationView) SetSize(width, height int) {
+	// same modal dimensions as the insights, 80% width and 70% height
+	contentWidth := max(width*80/100, 40) - 4
+	contentHeight := max(height*70/100, 10) - 4
+
+	v.viewport.Width = max(contentWidth, 20)
+	v.viewport.Height = max(contentHeight, 5)
+	v.viewport.SetContent(v.renderGroups(v.viewport.Width))
+	v.ready = true
+}
+
+func (v *AllocationView) ScrollUp() {
+	v.viewport.ScrollUp(1)
+}
+
+func (v *AllocationView) ScrollDown() {
+	v.viewport.ScrollDown(1)
+}
+
+func (v AllocationView) View() string {
+	if !v.ready {
+		return "Loading..."
+	}
+
+	header := v.styles.Header.Render("📊 " + v.title + " │ " +
+		utils.FormatCurrency(v.allocation.Total, 0, v.currency.CurrencyFormat, 1))
+	footer := v.styles.Footer.Render("Scroll: ↑/k/↓/j | Close: Esc")
+
+	return v.styles.Container.Render(lipgloss.JoinVertical(lipgloss.Top,
+		header,
+		v.viewport.View(),
+		footer,
+	))
+}
+
+func (v AllocationView) renderGroups(width int) string {
+	if v.allocation.Total == 0 {
+		return v.styles.Value.Render("No holdings to allocate")
+	}
+
+	labelWidth := 0
+	for _, g := range v.allocation.Groups {
+		for _, s := range g.Slices {
+			labelWidth = max(labelWidth, lipgloss.Width(s.Label))
+		}
+	}
+	labelWidth = min(labelWidth, 20)
+
+	values := make(map[string]string)
+	valueWidth := 0
+	for _, g := range v.allocation.Groups {
+		for _, s := range g.Slices {
+			value := fmt.Sprintf("%7s  %s", utils.ToYieldString(s.Percent), utils.FormatCurrency(s.Value, 0, v.currency.CurrencyFormat, 1))
+			values[g.Name+"\x00"+s.Label] = value
+			valueWidth = max(valueWidth, lipgloss.Width(value))
+		}
+	}
+	barWidth := max(width-labelWidth-valueWidth-4, 10)
+
+	var b strings.Builder
+	for i, g := range v.allocation.Groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(v.styles.Group.Render(g.Name) + "\n")
+		for _, s := range g.Slices {
+			b.WriteString(v.renderBar(s, labelWidth, barWidth, values[g.Name+"\x00"+s.Label]) + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// renderBar draws one slice, the bar is as wide as its percent of barWidth and capped at barWidth for tags whose
+// percentages add up to more than 100%
+func (v AllocationView) renderBar(s types.AllocationSlice, labelWidth, barWidth int, value string) string {
+	label := truncate(s.Label, labelWidth)
+
+	filled := min(int(float64(s.Percent)*float64(barWidth)+0.5), barWidth)
+	if filled == 0 && s.Value > 0 {
+		filled = 1
+	}
+
+	return v.styles.Label.Width(labelWidth).Render(label) + "  " +
+		v.styles.Bar.Render(strings.Repeat("█", filled)) +
+		v.styles.Track.Render(strings.Repeat("░", barWidth-filled)) + "  " +
+		v.styles.Value.Render(value)
+}
+
+// truncate shortens s to width runes, ending it with … when it's cut
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width || width < 1 {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,187 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DividendsView is a modal with the dividends expected over the next 12 months, by month, holding and payment, and
+// the history of the dividends received by year, month, symbol and account
+type DividendsView struct {
+	viewport viewport.Model
+	title    string
+	forecast types.DividendForecast
+	report   types.DividendReport
+	history  bool
+	currency config.DisplayCurrency
+	styles   RebalanceStyles
+	ready    bool
+}
+
+// NewDividendsView shows forecast first, its values and the ones of report are expected in currency already
+func NewDividendsView(title string, forecast types.DividendForecast, report types.DividendReport, currency config.DisplayCurrency) DividendsView {
+	return DividendsView{
+		viewport: viewport.New(0, 0),
+		title:    title,
+		forecast: forecast,
+		report:   report,
+		currency: currency,
+		styles:   DefaultRebalanceStyles(),
+	}
+}
+
+func (v *DividendsView) SetSize(width, height int) {
+	// same modal dimensions as the insights, 80% width and 70% height
+	v.viewport.Width = max(max(width*80/100, 40)-4, 20)
+	v.viewport.Height = max(max(height*70/100, 10)-4, 5)
+	v.setContent()
+	v.ready = true
+}
+
+// ToggleHistory switches between the forecast and the history of the dividends received
+func (v *DividendsView) ToggleHistory() {
+	v.history = !v.history
+	v.setContent()
+	v.viewport.GotoTop()
+}
+
+func (v *DividendsView) setContent() {
+	if v.history {
+		v.viewport.SetContent(v.renderHistory())
+	} else {
+		v.viewport.SetContent(v.renderForecast())
+	}
+}
+
+func (v *DividendsView) ScrollUp() {
+	v.viewport.ScrollUp(1)
+}
+
+func (v *DividendsView) ScrollDown() {
+	v.viewport.ScrollDown(1)
+}
+
+func (v DividendsView) View() string {
+	if !v.ready {
+		return "Loading..."
+	}
+
+	header := v.styles.Header.Render(fmt.Sprintf("💰 %s │ next 12 months %s, %s after tax", v.title,
+		v.money(v.forecast.Total), v.money(v.forecast.Net)))
+	footer := v.styles.Footer.Render("History: Tab | Scroll: ↑/k/↓/j | Close: Esc")
+	if v.history {
+		header = v.styles.Header.Render(fmt.Sprintf("💰 %s │ received %s, trailing 12 months %s, %s after tax", v.title,
+			v.money(v.report.Gross), v.money(v.report.TTM), v.money(v.report.TTMNet)))
+		footer = v.styles.Footer.Render("Forecast: Tab | Scroll: ↑/k/↓/j | Close: Esc")
+	}
+
+	return v.styles.Container.Render(lipgloss.JoinVertical(lipgloss.Top,
+		header,
+		v.viewport.View(),
+		footer,
+	))
+}
+
+func (v DividendsView) money(val types.Money) string {
+	return utils.FormatCurrency(val, 2, v.currency.CurrencyFormat, 1)
+}
+
+func (v DividendsView) renderForecast() string {
+	if len(v.forecast.Holdings) == 0 {
+		return v.styles.Muted.Width(v.viewport.Width).Render("No dividends expected, none of the holdings paid one in the last 18 months")
+	}
+
+	var b strings.Builder
+	b.WriteString(v.styles.Section.Render("Calendar") + "\n")
+	var top types.Money
+	for _, m := range v.forecast.Months {
+		top = max(top, m.Amount)
+	}
+	barWidth := max(v.viewport.Width-34, 10)
+	for _, m := range v.forecast.Months {
+		filled := 0
+		if top > 0 {
+			filled = int(float64(m.Amount)/float64(top)*float64(barWidth) + 0.5)
+		}
+		if filled == 0 && m.Amount > 0 {
+			filled = 1
+		}
+		b.WriteString(v.styles.Text.Render(m.Month.Format("Jan 06")+"  ") +
+			v.styles.Positive.Render(strings.Repeat("█", filled)) +
+			v.styles.Muted.Render(strings.Repeat("░", barWidth-filled)) +
+			v.styles.Text.Render(fmt.Sprintf(" %12s %12s", v.money(m.Amount), v.money(m.Net))) + "\n")
+	}
+
+	row := "%-10s %-11s %10s %12s %12s %8s %8s"
+	b.WriteString("\n" + v.styles.Section.Render("Holdings") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(row, "Symbol", "Frequency", "Next", "Annual", "Net", "Yield", "On cost")) + "\n")
+	for _, h := range v.forecast.Holdings {
+		b.WriteString(v.styles.Text.Render(fmt.Sprintf(row, truncate(h.Symbol, 10), h.Frequency, h.NextDate.Format("2006-01-02"),
+			v.money(h.Annual), v.money(h.Net), utils.ToYieldString(h.Yield), utils.ToYieldString(h.YieldOnCost))) + "\n")
+	}
+
+	payment := "%-10s %-10s %10s %12s %12s"
+	b.WriteString("\n" + v.styles.Section.Render("Payments") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(payment, "Date", "Symbol", "Shares", "Amount", "Net")) + "\n")
+	for _, p := range v.forecast.Payments {
+		b.WriteString(v.styles.Text.Render(fmt.Sprintf(payment, p.Date.Format("2006-01-02"), truncate(p.Symbol, 10), p.Quantity,
+			v.money(p.Amount), v.money(p.Net))) + "\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (v DividendsView) renderHistory() string {
+	if len(v.report.Years) == 0 {
+		return v.styles.Muted.Width(v.viewport.Width).Render("No dividends received yet")
+	}
+
+	var b strings.Builder
+	period := "%-10s %12s %12s %8s"
+	b.WriteString(v.styles.Section.Render("Years") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(period, "Year", "Gross", "Net", "YoY")) + "\n")
+	for _, y := range v.report.Years {
+		b.WriteString(v.renderPeriod(period, y) + "\n")
+	}
+
+	b.WriteString("\n" + v.styles.Section.Render("Months") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(period, "Month", "Gross", "Net", "YoY")) + "\n")
+	// newest first, the older months are in the years above
+	for i := len(v.report.Months) - 1; i >= max(len(v.report.Months)-24, 0); i-- {
+		b.WriteString(v.renderPeriod(period, v.report.Months[i]) + "\n")
+	}
+
+	group := "%-16s %12s %12s %12s %8s"
+	for _, section := range []struct {
+		name   string
+		groups []types.DividendGroup
+	}{{"Symbol", v.report.Symbols}, {"Account", v.report.Accounts}} {
+		b.WriteString("\n" + v.styles.Section.Render(section.name+"s") + "\n")
+		b.WriteString(v.styles.Column.Render(fmt.Sprintf(group, section.name, "Gross", "Net", "TTM", "Share")) + "\n")
+		for _, g := range section.groups {
+			b.WriteString(v.styles.Text.Render(fmt.Sprintf(group, truncate(g.Label, 16), v.money(g.Gross), v.money(g.Net),
+				v.money(g.TTM), utils.ToYieldString(g.Percent))) + "\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (v DividendsView) renderPeriod(format string, p types.DividendPeriod) string {
+	row := v.styles.Text.Render(fmt.Sprintf(strings.TrimSuffix(format, " %8s"), p.Label, v.money(p.Gross), v.money(p.Net)))
+	switch {
+	case !p.HasGrowth:
+		return row + v.styles.Muted.Render(fmt.Sprintf(" %8s", "-"))
+	case p.Growth < 0:
+		return row + v.styles.Negative.Render(fmt.Sprintf(" %8s", utils.ToYieldString(p.Growth)))
+	default:
+		return row + v.styles.Positive.Render(fmt.Sprintf(" %8s", utils.ToYieldString(p.Growth)))
+	}
+}
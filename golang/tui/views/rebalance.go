@@ -0,0 +1,174 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RebalanceView is a modal with the drift of every target and the suggested trades
+type RebalanceView struct {
+	viewport viewport.Model
+	plan     types.RebalancePlan
+	currency config.DisplayCurrency
+	styles   RebalanceStyles
+	ready    bool
+}
+
+type RebalanceStyles struct {
+	Container lipgloss.Style
+	Header    lipgloss.Style
+	Section   lipgloss.Style
+	Column    lipgloss.Style
+	Text      lipgloss.Style
+	Muted     lipgloss.Style
+	Positive  lipgloss.Style
+	Negative  lipgloss.Style
+	Footer    lipgloss.Style
+}
+
+func DefaultRebalanceStyles() RebalanceStyles {
+	return RebalanceStyles{
+		Container: lipgloss.NewStyle().
+			Background(lipgloss.Color("#24283b")).
+			Foreground(lipgloss.Color("#e0e6f0")).
+			Padding(1, 2).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#7aa2f7")),
+		Header: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7dcfff")).
+			Bold(true).
+			MarginBottom(1),
+		Section: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ff9e64")).
+			Bold(true),
+		Column: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#737aa2")),
+		Text: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#e0e6f0")),
+		Muted: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#737aa2")),
+		Positive: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#9ece6a")),
+		Negative: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#f7768e")),
+		Footer: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#737aa2")).
+			Italic(true).
+			MarginTop(1),
+	}
+}
+
+// NewRebalanceView shows plan, its values are expected in currency already
+func NewRebalanceView(plan types.RebalancePlan, currency config.DisplayCurrency) RebalanceView {
+	return RebalanceView{
+		viewport: viewport.New(0, 0),
+		plan:     plan,
+		currency: currency,
+		styles:   DefaultRebalanceStyles(),
+	}
+}
+
+func (v *RebalanceView) SetSize(width, height int) {
+	// same modal dimensions as the insights, 80% width and 70% height
+	v.viewport.Width = max(max(width*80/100, 40)-4, 20)
+	v.viewport.Height = max(max(height*70/100, 10)-4, 5)
+	v.viewport.SetContent(v.renderPlan())
+	v.ready = true
+}
+
+func (v *RebalanceView) ScrollUp() {
+	v.viewport.ScrollUp(1)
+}
+
+func (v *RebalanceView) ScrollDown() {
+	v.viewport.ScrollDown(1)
+}
+
+func (v RebalanceView) CashOnly() bool {
+	return v.plan.CashOnly
+}
+
+func (v RebalanceView) View() string {
+	if !v.ready {
+		return "Loading..."
+	}
+
+	mode := "sell and buy"
+	if v.plan.CashOnly {
+		mode = "cash only"
+	}
+	header := v.styles.Header.Render(fmt.Sprintf("⚖ Rebalance %s │ %s │ %s, cash %s", v.plan.Tag, mode,
+		v.money(v.plan.Total), v.money(v.plan.Cash)))
+	footer := v.styles.Footer.Render("Scroll: ↑/k/↓/j | Cash only: o | Close: Esc")
+
+	return v.styles.Container.Render(lipgloss.JoinVertical(lipgloss.Top,
+		header,
+		v.viewport.View(),
+		footer,
+	))
+}
+
+func (v RebalanceView) money(val types.Money) string {
+	return utils.FormatCurrency(val, 0, v.currency.CurrencyFormat, 1)
+}
+
+func (v RebalanceView) renderPlan() string {
+	if len(v.plan.Lines) == 0 {
+		return v.styles.Muted.Width(v.viewport.Width).Render("No targets for " + v.plan.Tag + ", set them with tracker rebalance --tag " +
+			v.plan.Tag + " --target <symbol>=<weight> or on the web rebalance page")
+	}
+
+	var b strings.Builder
+	if v.plan.TargetTotal < 0.999 || v.plan.TargetTotal > 1.001 {
+		b.WriteString(v.styles.Negative.Render("Targets add up to "+utils.ToYieldString(v.plan.TargetTotal)) + "\n\n")
+	}
+
+	row := "%-14s %12s %8s %8s %9s  %s"
+	b.WriteString(v.styles.Section.Render("Targets") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(row, "Target", "Value", "Weight", "Target", "Drift", "Band")) + "\n")
+	for _, line := range v.plan.Lines {
+		if !line.Targeted {
+			b.WriteString(v.styles.Muted.Render(fmt.Sprintf(row, truncate(line.Key, 14), v.money(line.Value),
+				utils.ToYieldString(line.Weight), "-", "-", "no target")) + "\n")
+			continue
+		}
+
+		band := v.styles.Positive.Render("ok ±" + utils.ToYieldString(line.Tolerance))
+		if line.OutOfBand {
+			band = v.styles.Negative.Render("out ±" + utils.ToYieldString(line.Tolerance))
+		}
+		b.WriteString(v.styles.Text.Render(fmt.Sprintf("%-14s %12s %8s %8s %9s  ", truncate(line.Key, 14), v.money(line.Value),
+			utils.ToYieldString(line.Weight), utils.ToYieldString(line.TargetWeight), utils.ToYieldString(line.Drift))) + band + "\n")
+	}
+
+	b.WriteString("\n" + v.styles.Section.Render("Trades") + "\n")
+	if len(v.plan.Trades) == 0 {
+		b.WriteString(v.styles.Muted.Render("No trades needed"))
+		return b.String()
+	}
+
+	trade := "%-5s %-14s %10s %12s %12s"
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(trade, "Trade", "Symbol", "Shares", "Price", "Amount")) + "\n")
+	for _, t := range v.plan.Trades {
+		symbol := t.Symbol
+		shares, price := t.Quantity.String(), utils.FormatCurrency(t.Price, 2, v.currency.CurrencyFormat, 1)
+		if symbol == "" {
+			symbol, shares, price = "any "+t.Group, "-", "-"
+		}
+		style := v.styles.Positive
+		if t.Type == types.TransactionTypeSell {
+			style = v.styles.Negative
+		}
+		b.WriteString(style.Render(fmt.Sprintf(trade, t.Type, truncate(symbol, 14), shares, price, v.money(t.Amount))) + "\n")
+	}
+	b.WriteString("\n" + v.styles.Text.Render("Cash left: "+v.money(v.plan.CashLeft)))
+
+	return b.String()
+}
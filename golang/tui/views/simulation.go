@@ -0,0 +1,144 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"tracker/config"
+	"tracker/types"
+	"tracker/utils"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SimulationView is a modal previewing the effect of a trade before it's saved
+type SimulationView struct {
+	viewport viewport.Model
+	sim      types.TradeSimulation
+	currency config.DisplayCurrency
+	styles   RebalanceStyles
+	ready    bool
+}
+
+// NewSimulationView shows sim, its values are expected in currency already
+func NewSimulationView(sim types.TradeSimulation, currency config.DisplayCurrency) SimulationView {
+	return SimulationView{
+		viewport: viewport.New(0, 0),
+		sim:      sim,
+		currency: currency,
+		styles:   DefaultRebalanceStyles(),
+	}
+}
+
+func (v *SimulationView) SetSize(width, height int) {
+	// same modal dimensions as the insights, 80% width and 70% height
+	v.viewport.Width = max(max(width*80/100, 40)-4, 20)
+	v.viewport.Height = max(max(height*70/100, 10)-4, 5)
+	v.viewport.SetContent(v.renderSimulation())
+	v.ready = true
+}
+
+func (v *SimulationView) ScrollUp() {
+	v.viewport.ScrollUp(1)
+}
+
+func (v *SimulationView) ScrollDown() {
+	v.viewport.ScrollDown(1)
+}
+
+// Trades returns the simulated trades, to save them once previewed
+func (v SimulationView) Trades() []types.Transaction {
+	return v.sim.Trades
+}
+
+func (v SimulationView) View() string {
+	if !v.ready {
+		return "Loading..."
+	}
+
+	trades := make([]string, 0, len(v.sim.Trades))
+	for _, t := range v.sim.Trades {
+		trades = append(trades, fmt.Sprintf("%s %s %s @ %.2f %s", t.Type, t.Quantity, strings.ToUpper(t.Symbol), t.Pps.Float64(), t.Currency))
+	}
+	header := v.styles.Header.Render("🔍 Preview │ " + strings.Join(trades, ", "))
+	footer := v.styles.Footer.Render("Scroll: ↑/k/↓/j | Save: Enter | Discard: Esc")
+
+	return v.styles.Container.Render(lipgloss.JoinVertical(lipgloss.Top,
+		header,
+		v.viewport.View(),
+		footer,
+	))
+}
+
+func (v SimulationView) money(val int64) string {
+	return utils.FormatCurrency(val, 0, v.currency.CurrencyFormat, 1)
+}
+
+// change renders the difference between two values in a column, colored by its sign
+func (v SimulationView) change(val int64) string {
+	switch {
+	case val > 0:
+		return v.styles.Positive.Render(fmt.Sprintf("%14s", "+"+v.money(val)))
+	case val < 0:
+		return v.styles.Negative.Render(fmt.Sprintf("%14s", v.money(val)))
+	}
+	return v.styles.Muted.Render(fmt.Sprintf("%14s", "-"))
+}
+
+func (v SimulationView) renderSimulation() string {
+	before, after := v.sim.Before, v.sim.After
+
+	var b strings.Builder
+	row := "%-22s %14s %14s "
+	b.WriteString(v.styles.Section.Render("Portfolio") + "\n")
+	b.WriteString(v.styles.Column.Render(fmt.Sprintf(row+"%14s", "", "Before", "After", "Change")) + "\n")
+	values := []struct {
+		label         string
+		before, after types.Money
+	}{
+		{"Value", before.Value, after.Value},
+		{"Cash", before.CashBalance, after.CashBalance},
+		{"Realized gain", before.RealizedGain, after.RealizedGain},
+		{"Unrealized gain", before.UnrealizedGain, after.UnrealizedGain},
+		{"Dividends (next 12m)", before.DividendIncome, after.DividendIncome},
+	}
+	for _, val := range values {
+		b.WriteString(v.styles.Text.Render(fmt.Sprintf(row, val.label, v.money(int64(val.before)), v.money(int64(val.after)))) +
+			v.change(int64(val.after-val.before)) + "\n")
+	}
+
+	b.WriteString("\n" + v.styles.Section.Render("Tax") + "\n")
+	b.WriteString(v.styles.Text.Render(fmt.Sprintf("%-22s ", "Realized by the trade")) + v.change(int64(v.sim.RealizedGain())) + "\n")
+	b.WriteString(v.styles.Text.Render(fmt.Sprintf("%-22s ", "Capital gains tax")) + v.change(-int64(v.sim.Tax)) + "\n")
+	b.WriteString(v.styles.Text.Render(fmt.Sprintf("%-22s ", "Dividends after tax")) + v.change(int64(v.sim.NetDividendIncome)) + "\n")
+
+	b.WriteString("\n" + v.styles.Section.Render("Holdings") + "\n")
+	v.renderChanges(&b, v.sim.HoldingChanges())
+
+	b.WriteString("\n" + v.styles.Section.Render(types.AllocationAssetClass) + "\n")
+	v.renderChanges(&b, v.sim.AssetClassChanges())
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (v SimulationView) renderChanges(b *strings.Builder, changes []types.AllocationChange) {
+	for _, c := range changes {
+		style := v.styles.Text
+		if c.After == 0 {
+			style = v.styles.Muted
+		}
+		b.WriteString(style.Render(fmt.Sprintf("%-22s %14s %14s ", truncate(c.Label, 22), utils.ToYieldString(c.Before),
+			utils.ToYieldString(c.After))) + v.drift(c.Change()) + "\n")
+	}
+}
+
+func (v SimulationView) drift(val float32) string {
+	switch {
+	case val > 0.0005:
+		return v.styles.Positive.Render(fmt.Sprintf("%14s", "+"+utils.ToYieldString(val)))
+	case val < -0.0005:
+		return v.styles.Negative.Render(fmt.Sprintf("%14s", utils.ToYieldString(val)))
+	}
+	return v.styles.Muted.Render(fmt.Sprintf("%14s", "-"))
+}
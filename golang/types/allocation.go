@@ -0,0 +1,41 @@
+package types
+
+// Allocation groups, each one splits the same market value along a different dimension
+const (
+	AllocationAssetClass  = "Asset Class"
+	AllocationSector      = "Sector"
+	AllocationCountry     = "Country"
+	AllocationCurrency    = "Currency"
+	AllocationInstitution = "Institution"
+	AllocationTag         = "Tag"
+)
+
+// AllocationSlice is the market value of the holdings sharing Label, Percent is a fraction of the allocation total
+type AllocationSlice struct {
+	Label   string
+	Value   Money
+	Percent float32
+}
+
+// AllocationGroup is the allocation along one dimension, its slices are sorted by value
+type AllocationGroup struct {
+	Name   string
+	Slices []AllocationSlice
+}
+
+// Allocation is the current market value of the holdings, Total, split by asset class, sector, country, currency,
+// institution and tag
+type Allocation struct {
+	Total  Money
+	Groups []AllocationGroup
+}
+
+// Group returns the group with the given name, or an empty group if there is none
+func (a Allocation) Group(name string) AllocationGroup {
+	for _, g := range a.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return AllocationGroup{Name: name}
+}
@@ -0,0 +1,19 @@
+package types
+
+// BenchmarkComparison is the outcome of investing the same dated cash flows in Symbol instead of the portfolio.
+// Gains are simple gains like AnalyzedPortfolio.Gain, time weighted returns are annualized since inception.
+type BenchmarkComparison struct {
+	Symbol    string
+	Available bool
+
+	Value     Money
+	Dividends Money
+	Gain      float32
+	TWR       float32
+
+	PortfolioGain float32
+	PortfolioTWR  float32
+
+	ReturnDifference   float32
+	TrackingDifference float32
+}
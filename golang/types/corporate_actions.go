@@ -0,0 +1,81 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+type CorporateActionType string
+
+const (
+	// CorporateActionRename changes the ticker of a symbol, holdings and lots carry over unchanged
+	CorporateActionRename CorporateActionType = "Rename"
+	// CorporateActionMerger replaces every share of Symbol with SharesPerShare shares of NewSymbol and
+	// CashPerShare in cash
+	CorporateActionMerger CorporateActionType = "Merger"
+	// CorporateActionSpinOff adds SharesPerShare shares of NewSymbol for every share of Symbol, which is still held
+	CorporateActionSpinOff CorporateActionType = "SpinOff"
+)
+
+// CorporateAction changes the symbol or shares held of Symbol from Date on.
+// SharesPerShare is fixed point like a Quantity, 0.5 is half a new share for every old share, a rename ignores it.
+// BasisAllocation is the fraction of the cost basis of Symbol that moves, to the spun off shares of a spin-off or
+// to the cash of a merger, the rest stays with the continuing shares.
+type CorporateAction struct {
+	Id              string              `json:"id"`
+	Date            time.Time           `json:"date"`
+	Type            CorporateActionType `json:"action_type"`
+	Symbol          string              `json:"symbol"`
+	NewSymbol       string              `json:"new_symbol"`
+	SharesPerShare  Quantity            `json:"shares_per_share"`
+	CashPerShare    Money               `json:"cash_per_share"`
+	BasisAllocation float64             `json:"basis_allocation"`
+}
+
+// Replaces reports whether the action ends Symbol, after a rename or a merger its shares are gone
+func (a CorporateAction) Replaces() bool {
+	return a.Type == CorporateActionRename || a.Type == CorporateActionMerger
+}
+
+// NewShares returns the shares of NewSymbol received for quantity shares of Symbol
+func (a CorporateAction) NewShares(quantity Quantity) Quantity {
+	if a.Type == CorporateActionRename {
+		return quantity
+	}
+	return quantity.MulRatio(int64(a.SharesPerShare), int64(QuantityScale))
+}
+
+// SymbolLineage follows symbols through actions and returns the symbols that are still traded, the symbols
+// replaced by a rename or a merger are dropped and the symbols they became or spun off are added
+func SymbolLineage(symbols []string, actions []CorporateAction) []string {
+	current := make(map[string]string, len(symbols))
+	for _, s := range symbols {
+		current[strings.ToLower(s)] = s
+	}
+
+	sorted := slices.Clone(actions)
+	slices.SortStableFunc(sorted, func(a, b CorporateAction) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	for _, a := range sorted {
+		key := strings.ToLower(a.Symbol)
+		if _, ok := current[key]; !ok {
+			continue
+		}
+		if a.NewSymbol != "" {
+			current[strings.ToLower(a.NewSymbol)] = a.NewSymbol
+		}
+		if a.Replaces() && strings.ToLower(a.NewSymbol) != key {
+			delete(current, key)
+		}
+	}
+
+	ret := make([]string, 0, len(current))
+	for _, s := range current {
+		ret = append(ret, s)
+	}
+	slices.Sort(ret)
+	return ret
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSymbolLineage(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	actions := []CorporateAction{
+		// out of order on purpose, the merger of META only applies after FB became META
+		{Date: day(3), Type: CorporateActionMerger, Symbol: "META", NewSymbol: "BIG"},
+		{Date: day(1), Type: CorporateActionRename, Symbol: "FB", NewSymbol: "META"},
+		{Date: day(2), Type: CorporateActionSpinOff, Symbol: "IBM", NewSymbol: "KD"},
+		{Date: day(2), Type: CorporateActionRename, Symbol: "GOOG", NewSymbol: "GOOGL"},
+	}
+
+	lineage := SymbolLineage([]string{"FB", "IBM", "AAPL"}, actions)
+	expected := []string{"AAPL", "BIG", "IBM", "KD"}
+	if fmt.Sprint(lineage) != fmt.Sprint(expected) {
+		t.Fatalf("Expected lineage to be %v but got %v\n", expected, lineage)
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// CurrencyUSD is the currency amounts without one are in, and the base of the stored exchange rates
+const CurrencyUSD = "USD"
+
+// NormalizeCurrency returns the uppercase currency code, an empty currency is USD
+func NormalizeCurrency(currency string) string {
+	if currency == "" {
+		return CurrencyUSD
+	}
+	return strings.ToUpper(currency)
+}
+
+// FxRate is how many units of Currency one US dollar bought on Date
+type FxRate struct {
+	Currency string
+	Date     time.Time
+	Value    float64
+}
+
+// FxRates holds the exchange rates of every currency by uppercase code, sorted by date
+type FxRates map[string][]FxRate
+
+// Rate returns the rate of currency on date, the last known rate before it or the first known rate when the
+// history starts later. USD and currencies without any rate are 1.
+func (r FxRates) Rate(currency string, date time.Time) float64 {
+	currency = NormalizeCurrency(currency)
+	rates := r[currency]
+	if currency == CurrencyUSD || len(rates) == 0 {
+		return 1
+	}
+
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].Date.After(date)
+	})
+	if i == 0 {
+		return rates[0].Value
+	}
+	return rates[i-1].Value
+}
+
+// Convert converts amount from one currency to another at the rates of date, rounded to the nearest minor unit. It
+// returns ErrMoneyOverflow when the converted amount doesn't fit.
+func (r FxRates) Convert(amount Money, from, to string, date time.Time) (Money, error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+
+	fromRate := r.Rate(from, date)
+	if fromRate == 0 {
+		return amount, nil
+	}
+	return RoundMoney(float64(amount) * r.Rate(to, date) / fromRate)
+}
+
+// CurrencyReturn is the part of a portfolio traded or held in one currency. Invested, Withdrawn, Dividends, Value
+// and GainValue are in the currency itself, the Reporting amounts convert every flow at the rate of its day and
+// the value at today's rate. FxGain is the part of the reporting gain that comes from the exchange rate moving
+// since the flows, ReportingGain is GainValue at today's rate plus FxGain.
+type CurrencyReturn struct {
+	Currency  string
+	Invested  Money
+	Withdrawn Money
+	Dividends Money
+	Value     Money
+	GainValue Money
+	Gain      float32
+
+	ReportingInvested  Money
+	ReportingWithdrawn Money
+	ReportingDividends Money
+	ReportingValue     Money
+	ReportingGain      Money
+	FxGain             Money
+}
@@ -0,0 +1,45 @@
+package types
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFxRatesConvert(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	rates := FxRates{
+		"ILS": {{Currency: "ILS", Date: day(10), Value: 4}, {Currency: "ILS", Date: day(20), Value: 3.5}},
+		"EUR": {{Currency: "EUR", Date: day(10), Value: 0.5}},
+	}
+
+	if r := rates.Rate("ils", day(15)); r != 4 {
+		t.Fatalf("Expected the rate of the last known day %v but got %v\n", 4, r)
+	}
+
+	if r := rates.Rate("ILS", day(1)); r != 4 {
+		t.Fatalf("Expected the first rate before the history starts but got %v\n", r)
+	}
+
+	if r := rates.Rate("", day(1)); r != 1 {
+		t.Fatalf("Expected USD to be 1 but got %v\n", r)
+	}
+
+	if m, err := rates.Convert(400, "ILS", "", day(20)); err != nil || m != 114 {
+		t.Fatalf("Expected 400 shekels to be %d cents but got %d, %v\n", 114, m, err)
+	}
+
+	if m, err := rates.Convert(100, "EUR", "ILS", day(10)); err != nil || m != 800 {
+		t.Fatalf("Expected 100 euro cents to be %d agorot but got %d, %v\n", 800, m, err)
+	}
+
+	if _, err := rates.Convert(math.MaxInt64/2, "", "ILS", day(10)); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected converting to shekels to overflow but got %v\n", err)
+	}
+
+	nan := FxRates{"ILS": {{Currency: "ILS", Date: day(10), Value: math.NaN()}}}
+	if _, err := nan.Convert(100, "", "ILS", day(10)); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected a NaN rate to fail but got %v\n", err)
+	}
+}
@@ -0,0 +1,99 @@
+package types
+
+import "time"
+
+// DividendFrequency is how often a symbol pays, guessed from the interval between its recent dividends
+type DividendFrequency string
+
+const (
+	DividendMonthly    DividendFrequency = "Monthly"
+	DividendQuarterly  DividendFrequency = "Quarterly"
+	DividendSemiAnnual DividendFrequency = "Semi-annual"
+	DividendAnnual     DividendFrequency = "Annual"
+)
+
+// DividendPayment is a dividend expected on Date. PerShare is in the currency the symbol pays in, Amount and Net are
+// in the currency of the forecast.
+type DividendPayment struct {
+	Date     time.Time
+	Symbol   string
+	Quantity Quantity
+	PerShare Money
+	Amount   Money
+	Net      Money
+}
+
+// DividendHolding is the income a holding is expected to pay over the forecast. YieldOnCost is Annual over the cost
+// basis and Yield over the market value.
+type DividendHolding struct {
+	Symbol      string
+	Quantity    Quantity
+	Frequency   DividendFrequency
+	PerShare    Money
+	NextDate    time.Time
+	Annual      Money
+	Net         Money
+	YieldOnCost float32
+	Yield       float32
+}
+
+// DividendMonth is the income expected in the month starting at Month
+type DividendMonth struct {
+	Month  time.Time
+	Amount Money
+	Net    Money
+}
+
+// DividendForecast projects the dividends of the current holdings over the 12 months starting at From, each holding
+// keeps paying its latest dividend at its recent frequency. Months has an entry for each of the 12 months, Payments
+// are sorted by date.
+type DividendForecast struct {
+	From     time.Time
+	Total    Money
+	Net      Money
+	Holdings []DividendHolding
+	Months   []DividendMonth
+	Payments []DividendPayment
+}
+
+// DividendFlow is a dividend received on Date, Amount is what the shares held that day were paid
+type DividendFlow struct {
+	Date   time.Time
+	Symbol string
+	Amount Money
+}
+
+// DividendPeriod is the dividends of a year or a month. Growth compares Gross with the same period a year before, it
+// is only set when HasGrowth, when the period before paid anything.
+type DividendPeriod struct {
+	Label     string
+	Start     time.Time
+	Gross     Money
+	Net       Money
+	Growth    float32
+	HasGrowth bool
+}
+
+// DividendGroup is the dividends of a symbol or an account, Percent is its part of the lifetime dividends
+type DividendGroup struct {
+	Label   string
+	Gross   Money
+	Net     Money
+	TTM     Money
+	Percent float32
+}
+
+// DividendReport breaks down the dividends received up to AsOf. TTM is the trailing twelve months. Years and Months
+// are oldest first and include the periods that paid nothing, the current year is compared with the same part of the
+// year before. Symbols and Accounts are sorted by Gross.
+type DividendReport struct {
+	AsOf     time.Time
+	Gross    Money
+	Net      Money
+	TTM      Money
+	TTMNet   Money
+	Years    []DividendPeriod
+	Months   []DividendPeriod
+	Symbols  []DividendGroup
+	Accounts []DividendGroup
+}
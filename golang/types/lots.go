@@ -0,0 +1,78 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+type LotMethod string
+
+const (
+	LotMethodFIFO        LotMethod = "FIFO"
+	LotMethodLIFO        LotMethod = "LIFO"
+	LotMethodHighestCost LotMethod = "HighestCost"
+)
+
+// ParseLotMethod returns the lot method named s, ignoring case
+func ParseLotMethod(s string) (LotMethod, bool) {
+	for _, method := range []LotMethod{LotMethodFIFO, LotMethodLIFO, LotMethodHighestCost} {
+		if strings.EqualFold(strings.TrimSpace(s), string(method)) {
+			return method, true
+		}
+	}
+	return "", false
+}
+
+// LotSelection explicitly picks Quantity shares from the lot opened by LotId when matching a sell
+type LotSelection struct {
+	LotId    string
+	Quantity Quantity
+}
+
+// Lot is an open tax lot, the Id is the id of the Buy transaction that opened it
+type Lot struct {
+	Id        string
+	AccountId string
+	Symbol    string
+	OpenDate  time.Time
+	Quantity  Quantity
+	CostBasis Money
+}
+
+func (l Lot) AverageCost() float64 {
+	if l.Quantity == 0 {
+		return 0
+	}
+	return float64(l.CostBasis) / l.Quantity.Float64()
+}
+
+func (l Lot) HoldingDays(asOf time.Time) int {
+	return int(asOf.Sub(l.OpenDate).Hours() / 24)
+}
+
+// ClosedLot is the part of a lot that was matched against a sell
+type ClosedLot struct {
+	LotId     string
+	SellId    string
+	AccountId string
+	Symbol    string
+	OpenDate  time.Time
+	CloseDate time.Time
+	Quantity  Quantity
+	CostBasis Money
+	Proceeds  Money
+}
+
+// Gain is Proceeds less CostBasis
+func (l ClosedLot) Gain() (Money, error) {
+	return l.Proceeds.Sub(l.CostBasis)
+}
+
+func (l ClosedLot) HoldingDays() int {
+	return int(l.CloseDate.Sub(l.OpenDate).Hours() / 24)
+}
+
+// LongTerm reports whether the lot was held for more than a year
+func (l ClosedLot) LongTerm() bool {
+	return l.CloseDate.After(l.OpenDate.AddDate(1, 0, 0))
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// ErrMoneyOverflow is returned when an amount doesn't fit in a Money
+var ErrMoneyOverflow = errors.New("money: arithmetic overflow")
+
+// Money is an amount in minor units (cents). Add, Sub and the multiplications are checked and return
+// ErrMoneyOverflow instead of wrapping around.
+type Money int64
+
+// MoneyFromFloat rounds an amount in major units (dollars) to minor units
+func MoneyFromFloat(major float64) Money {
+	return Money(math.Round(major * 100))
+}
+
+// RoundMoney rounds an amount in minor units, it returns ErrMoneyOverflow when the amount isn't finite or doesn't fit
+func RoundMoney(minor float64) (Money, error) {
+	r := math.Round(minor)
+	if math.IsNaN(r) || r >= 0x1p63 || r < -0x1p63 {
+		return 0, ErrMoneyOverflow
+	}
+	return Money(r), nil
+}
+
+// ParseMoney parses an amount in major units, it rejects NaN, infinities and amounts that don't fit
+func ParseMoney(s string) (Money, error) {
+	major, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsInf(major, 0) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	m, err := RoundMoney(major * 100)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is too large", s)
+	}
+	return m, nil
+}
+
+// Float64 returns the amount in major units
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	sum := m + o
+	if (o > 0 && sum < m) || (o < 0 && sum > m) {
+		return 0, ErrMoneyOverflow
+	}
+	return sum, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	diff := m - o
+	if (o > 0 && diff > m) || (o < 0 && diff < m) {
+		return 0, ErrMoneyOverflow
+	}
+	return diff, nil
+}
+
+// Mul returns m * n
+func (m Money) Mul(n int64) (Money, error) {
+	v, ok := mulDiv(int64(m), n, 1)
+	if !ok {
+		return 0, ErrMoneyOverflow
+	}
+	return Money(v), nil
+}
+
+// MulRatio returns m * num / den rounded to the nearest minor unit, the intermediate product can't overflow.
+// A zero den is reported as an overflow.
+func (m Money) MulRatio(num, den int64) (Money, error) {
+	v, ok := mulDiv(int64(m), num, den)
+	if !ok {
+		return 0, ErrMoneyOverflow
+	}
+	return Money(v), nil
+}
+
+// MulQuantity returns the value of q units at a price of m per unit, rounded to the nearest minor unit
+func (m Money) MulQuantity(q Quantity) (Money, error) {
+	return m.MulRatio(int64(q), int64(QuantityScale))
+}
+
+// mulDiv returns a*b/c rounded half away from zero, using a 128 bit intermediate so large products don't overflow.
+// ok is false when c is zero or the result doesn't fit in an int64.
+func mulDiv(a, b, c int64) (int64, bool) {
+	if c == 0 {
+		return 0, false
+	}
+
+	negative := (a < 0) != (b < 0) != (c < 0)
+	ua, ub, uc := absUint(a), absUint(b), absUint(c)
+
+	hi, lo := bits.Mul64(ua, ub)
+	if hi >= uc {
+		// bits.Div64 panics when the quotient doesn't fit in 64 bits
+		return 0, false
+	}
+
+	quo, rem := bits.Div64(hi, lo, uc)
+	if rem >= uc-rem {
+		quo++
+	}
+
+	if negative {
+		if quo > 1<<63 {
+			return 0, false
+		}
+		return int64(-quo), true
+	}
+	if quo > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(quo), true
+}
+
+func absUint(v int64) uint64 {
+	if v < 0 {
+		return -uint64(v)
+	}
+	return uint64(v)
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMoneyCheckedArithmetic(t *testing.T) {
+	if _, err := Money(math.MaxInt64).Add(1); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected adding to the largest amount to overflow but got %v\n", err)
+	}
+
+	if _, err := Money(math.MinInt64).Sub(1); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected subtracting from the smallest amount to overflow but got %v\n", err)
+	}
+
+	if _, err := Money(math.MaxInt64 / 2).Mul(3); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected multiplying past the largest amount to overflow but got %v\n", err)
+	}
+
+	if v, err := Money(-150).Add(50); err != nil || v != -100 {
+		t.Fatalf("Expected -100 but got %d, %v\n", v, err)
+	}
+
+	// 50,000 shares at $500 doesn't fit in an int32
+	v, err := Money(50000).MulQuantity(NewQuantity(50000))
+	if err != nil || v != 2_500_000_000 {
+		t.Fatalf("Expected 2500000000 but got %d, %v\n", v, err)
+	}
+
+	if _, err := Money(math.MaxInt32).MulQuantity(NewQuantity(10_000_000_000)); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected a position larger than an int64 to overflow but got %v\n", err)
+	}
+
+	if v, err := Money(-101).MulRatio(1, 2); err != nil || v != -51 {
+		t.Fatalf("Expected -101 / 2 to round to -51 but got %d, %v\n", v, err)
+	}
+
+	if _, err := Money(100).MulRatio(1, 0); !errors.Is(err, ErrMoneyOverflow) {
+		t.Fatalf("Expected a zero denominator to fail but got %v\n", err)
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	// 12.34 * 100 is 1233.9999999999998 in floating point
+	if m := MoneyFromFloat(12.34); m != 1234 {
+		t.Fatalf("Expected 1234 cents but got %d\n", m)
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	if m, err := ParseMoney(" 12.34 "); err != nil || m != 1234 {
+		t.Fatalf("Expected 1234 cents but got %d, %v\n", m, err)
+	}
+
+	for _, s := range []string{"", "abc", "NaN", "Inf", "-Inf", "1e400", "1e17"} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Fatalf("Expected %q to be rejected\n", s)
+		}
+	}
+}
@@ -1,22 +1,110 @@
 package types
 
+import (
+	"strings"
+	"time"
+)
+
 type AnalyzedPortfolio struct {
-	Value              int64
-	TotalInvested      int64
-	TotalWithdrawn     int64
-	TotalDividends     int64
-	GainValue          int64
+	Value              Money
+	TotalInvested      Money
+	TotalWithdrawn     Money
+	TotalDividends     Money
+	GainValue          Money
 	Gain               float32
 	AnnualizedYield    float32
 	ModifiedDietzYield float32
+	XIRR               float32
+	RealizedGain       Money
+	UnrealizedGain     Money
+
+	// CashBalance is uninvested cash, it is part of Value. TotalInvested and TotalWithdrawn are the deposits
+	// and withdrawals of accounts that record them, and the buys and sells of accounts that don't.
+	// TotalFees is trade commissions plus fee transactions.
+	CashBalance   Money
+	TotalInterest Money
+	TotalFees     Money
+	TotalTaxes    Money
+
+	// ReportingCurrency is the currency of every amount above, flows are converted at the rate of their day.
+	// LocalGainValue is the gain in the currencies the portfolio trades in converted at today's rate and
+	// FxGainValue the rest of GainValue, coming from the exchange rates moving. Currencies breaks both down by
+	// currency, keyed by uppercase code.
+	ReportingCurrency string
+	LocalGainValue    Money
+	LocalGain         float32
+	FxGainValue       Money
+	Currencies        map[string]CurrencyReturn
+	// Prices are the latest prices in ReportingCurrency, keyed by lowercase symbol
+	Prices map[string]SymbolPrice
+
+	TimeWeightedReturns TimeWeightedReturns
 
 	FirstTransaction Transaction
 	LastTransaction  Transaction
 
-	SymbolsCount map[string]int32
+	SymbolsCount map[string]Quantity
 	Transactions []Transaction
+	// ReportingTransactions are Transactions with their amounts in ReportingCurrency at the rate of their day
+	ReportingTransactions []Transaction
+
+	OpenLots    []Lot
+	ClosedLots  []ClosedLot
+	SymbolGains map[string]SymbolGain
+
+	// DividendFlows are the dividends received in ReportingCurrency, in the order they were paid
+	DividendFlows []DividendFlow
+}
+
+// SymbolGain breaks down the gain of a single symbol, Total is the sum of the gains. Keys in
+// AnalyzedPortfolio.SymbolGains are lowercase
+type SymbolGain struct {
+	Symbol      string
+	CostBasis   Money
+	MarketValue Money
+	Realized    Money
+	Unrealized  Money
+	Dividends   Money
+	Total       Money
+}
+
+// PortfolioSnapshot is the state of a portfolio at the end of a single day, Holdings keys are lowercase.
+// Dividends counts dividends paid out of the portfolio, dividends paid into a cash ledger are part of Value.
+type PortfolioSnapshot struct {
+	Date             time.Time
+	Value            Money
+	NetContributions Money
+	Dividends        Money
+	Holdings         map[string]Quantity
 }
 
 func NewAnalyzedPortfolio() AnalyzedPortfolio {
 	return AnalyzedPortfolio{}
 }
+
+// CostBasis returns the cost basis of the open lots held for symbol
+func (p AnalyzedPortfolio) CostBasis(symbol string) Money {
+	var basis Money
+	for _, l := range p.OpenLots {
+		if strings.EqualFold(l.Symbol, symbol) {
+			basis += l.CostBasis
+		}
+	}
+	return basis
+}
+
+// AverageCost returns the average cost per share of the open lots held for symbol
+func (p AnalyzedPortfolio) AverageCost(symbol string) float64 {
+	var basis Money
+	var quantity Quantity
+	for _, l := range p.OpenLots {
+		if strings.EqualFold(l.Symbol, symbol) {
+			basis += l.CostBasis
+			quantity += l.Quantity
+		}
+	}
+	if quantity == 0 {
+		return 0
+	}
+	return float64(basis) / quantity.Float64()
+}
@@ -2,8 +2,13 @@ package types
 
 import "time"
 
+// SymbolPrice is a price of Symbol in Currency, an empty Currency is the currency the symbol is traded in. Close is
+// the unadjusted close of Date when the source has one, it is what the price history keeps.
 type SymbolPrice struct {
 	Symbol    string
-	AdjPrice  int32
+	AdjPrice  Money
+	Close     Money
+	Currency  string
+	Date      time.Time
 	CreatedAt time.Time
 }
@@ -0,0 +1,192 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QuantityDecimals is the number of decimal places a Quantity keeps, enough for fractional shares and crypto
+const QuantityDecimals = 8
+
+// QuantityScale is the fixed point value of a single unit
+const QuantityScale Quantity = 100_000_000
+
+// Quantity is a fixed point number of shares or units, 1 share is QuantityScale.
+// It is stored as a decimal string, whole numbers written by older versions are read as whole units.
+type Quantity int64
+
+// NewQuantity returns a quantity of whole units
+func NewQuantity(units int64) Quantity {
+	return Quantity(units) * QuantityScale
+}
+
+// QuantityFromFloat rounds f to the nearest representable quantity
+func QuantityFromFloat(f float64) Quantity {
+	return Quantity(math.Round(f * float64(QuantityScale)))
+}
+
+// ParseQuantity parses a decimal number like "12", "0.5" or "-1.25" without going through a float
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	unsigned := s
+	if negative || strings.HasPrefix(s, "+") {
+		unsigned = s[1:]
+	}
+	whole, fraction, _ := strings.Cut(unsigned, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	if len(fraction) > QuantityDecimals {
+		return 0, fmt.Errorf("quantity %q has more than %d decimal places", s, QuantityDecimals)
+	}
+	// the sign only goes first, strconv would take one at the start of either part
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+
+	var units, frac int64
+	var err error
+	if whole != "" {
+		if units, err = strconv.ParseInt(whole, 10, 64); err != nil {
+			return 0, fmt.Errorf("quantity %q is too large", s)
+		}
+	}
+	if fraction != "" {
+		frac, _ = strconv.ParseInt(fraction, 10, 64)
+		for range QuantityDecimals - len(fraction) {
+			frac *= 10
+		}
+	}
+
+	if units > (math.MaxInt64-frac)/int64(QuantityScale) {
+		return 0, fmt.Errorf("quantity %q is too large", s)
+	}
+
+	q := NewQuantity(units) + Quantity(frac)
+	if negative {
+		q = -q
+	}
+	return q, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 returns the quantity in units
+func (q Quantity) Float64() float64 {
+	return float64(q) / float64(QuantityScale)
+}
+
+// IsWhole reports whether the quantity has no fractional part
+func (q Quantity) IsWhole() bool {
+	return q%QuantityScale == 0
+}
+
+// MulPrice returns the value in cents of the quantity at price cents per unit, rounded to the nearest cent.
+// A result that doesn't fit saturates, use Money.MulQuantity where an overflow must be reported.
+func (q Quantity) MulPrice(price int64) int64 {
+	return saturatingMulDiv(int64(q), price, int64(QuantityScale))
+}
+
+// MulRatio returns the quantity multiplied by num/den, rounded to the nearest representable quantity
+func (q Quantity) MulRatio(num, den int64) Quantity {
+	return Quantity(saturatingMulDiv(int64(q), num, den))
+}
+
+func saturatingMulDiv(a, b, c int64) int64 {
+	if v, ok := mulDiv(a, b, c); ok {
+		return v
+	}
+	if c == 0 {
+		return 0
+	}
+	if (a < 0) != (b < 0) != (c < 0) {
+		return math.MinInt64
+	}
+	return math.MaxInt64
+}
+
+// String formats the quantity without trailing zeros, 12.50000000 is "12.5" and 3.00000000 is "3"
+func (q Quantity) String() string {
+	sign := ""
+	abs := uint64(q)
+	if q < 0 {
+		sign = "-"
+		abs = uint64(-q)
+	}
+
+	units := abs / uint64(QuantityScale)
+	frac := abs % uint64(QuantityScale)
+	if frac == 0 {
+		return sign + strconv.FormatUint(units, 10)
+	}
+
+	fraction := strings.TrimRight(fmt.Sprintf("%0*d", QuantityDecimals, frac), "0")
+	return sign + strconv.FormatUint(units, 10) + "." + fraction
+}
+
+// Value implements driver.Valuer, quantities are written as decimal strings so no precision is lost
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
+
+// Scan implements sql.Scanner. Integers are whole units as written before quantities were fractional.
+func (q *Quantity) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*q = 0
+	case int64:
+		*q = NewQuantity(v)
+	case float64:
+		*q = QuantityFromFloat(v)
+	case string:
+		parsed, err := ParseQuantity(v)
+		if err != nil {
+			return err
+		}
+		*q = parsed
+	case []byte:
+		parsed, err := ParseQuantity(string(v))
+		if err != nil {
+			return err
+		}
+		*q = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into Quantity", src)
+	}
+	return nil
+}
+
+// MarshalJSON writes the quantity as a plain number of units
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON reads a number of units, quoted or not
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
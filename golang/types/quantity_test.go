@@ -0,0 +1,95 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Quantity
+		str      string
+	}{
+		{input: "10", expected: NewQuantity(10), str: "10"},
+		{input: "0.5", expected: QuantityScale / 2, str: "0.5"},
+		{input: ".25", expected: QuantityScale / 4, str: "0.25"},
+		{input: "1.00000001", expected: QuantityScale + 1, str: "1.00000001"},
+		{input: "-2.5", expected: -NewQuantity(5) / 2, str: "-2.5"},
+		{input: " 3.10 ", expected: NewQuantity(3) + QuantityScale/10, str: "3.1"},
+		{input: "+4", expected: NewQuantity(4), str: "4"},
+		{input: "92233720368.54775807", expected: math.MaxInt64, str: "92233720368.54775807"},
+	}
+
+	for _, tc := range testCases {
+		q, err := ParseQuantity(tc.input)
+		if err != nil {
+			t.Fatalf("Expected %q to parse but got %v\n", tc.input, err)
+		}
+		if q != tc.expected || q.String() != tc.str {
+			t.Fatalf("Expected %q to be %d (%s) but got %d (%s)\n", tc.input, tc.expected, tc.str, q, q)
+		}
+	}
+
+	invalid := []string{
+		"", "abc", "1.2.3", "1.123456789", "-", "1e3",
+		// signs after the decimal point or twice
+		"1.+5", "1.-5", "-+5", "+-5", "--5",
+		// the fraction pushes the integer part at the limit over it
+		"92233720368.54775808", "92233720368.99999999", "92233720369", "99999999999999999999",
+	}
+	for _, input := range invalid {
+		if _, err := ParseQuantity(input); err == nil {
+			t.Fatalf("Expected %q to be rejected\n", input)
+		}
+	}
+}
+
+func TestQuantityMulPrice(t *testing.T) {
+	half := QuantityScale / 2
+	if v := half.MulPrice(101); v != 51 {
+		t.Fatalf("Expected half of 101 cents to round to 51 but got %d\n", v)
+	}
+
+	// 10 million shares at the largest int32 price overflows a plain int64 product of the fixed point values
+	big := NewQuantity(10_000_000)
+	if v := big.MulPrice(math.MaxInt32); v != 10_000_000*math.MaxInt32 {
+		t.Fatalf("Expected %d but got %d\n", int64(10_000_000)*math.MaxInt32, v)
+	}
+
+	if q := NewQuantity(3).MulRatio(150, 100); q != NewQuantity(9)/2 {
+		t.Fatalf("Expected a 3:2 split of 3 shares to be 4.5 but got %s\n", q)
+	}
+}
+
+func TestQuantityScanLegacyIntegers(t *testing.T) {
+	var q Quantity
+	for _, src := range []any{int64(7), "7", []byte("7"), float64(7)} {
+		if err := q.Scan(src); err != nil || q != NewQuantity(7) {
+			t.Fatalf("Expected %v (%T) to scan as 7 shares but got %s, %v\n", src, src, q, err)
+		}
+	}
+
+	var v driver.Value
+	v, _ = (QuantityScale / 8).Value()
+	if v != "0.125" {
+		t.Fatalf("Expected 0.125 to be stored as a decimal string but got %v\n", v)
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	var tx Transaction
+	if err := json.Unmarshal([]byte(`{"quantity": 1.5}`), &tx); err != nil {
+		t.Fatalf("Error wasn't nil: %v\n", err)
+	}
+	if tx.Quantity != NewQuantity(3)/2 {
+		t.Fatalf("Expected quantity 1.5 but got %s\n", tx.Quantity)
+	}
+
+	data, _ := json.Marshal(tx.Quantity)
+	if string(data) != "1.5" {
+		t.Fatalf("Expected 1.5 but got %s\n", data)
+	}
+}
@@ -0,0 +1,57 @@
+package types
+
+// TargetKind is what a target allocation is set on
+type TargetKind string
+
+const (
+	TargetSymbol     TargetKind = "symbol"
+	TargetAssetClass TargetKind = "asset_class"
+)
+
+// TargetAllocation is the Weight, a fraction of the portfolio, that the symbol or asset class Key should have in the
+// accounts of Tag. Drift within Tolerance is left alone, a Tolerance of 0 uses the configured one.
+type TargetAllocation struct {
+	Tag       string
+	Kind      TargetKind
+	Key       string
+	Weight    float32
+	Tolerance float32
+}
+
+// RebalanceLine compares the current value of a target with its weight. Holdings without a target get a line that
+// is not Targeted and is never traded.
+type RebalanceLine struct {
+	Kind         TargetKind
+	Key          string
+	Targeted     bool
+	Value        Money
+	Weight       float32
+	TargetWeight float32
+	Drift        float32
+	Tolerance    float32
+	OutOfBand    bool
+}
+
+// RebalanceTrade is a suggested buy or sell of whole shares of Symbol toward the target Group. A trade for an asset
+// class that isn't held has no Symbol, only the Amount to invest in it.
+type RebalanceTrade struct {
+	Symbol   string
+	Group    string
+	Type     TransactionType
+	Quantity Quantity
+	Price    Money
+	Amount   Money
+}
+
+// RebalancePlan is the drift of the accounts of Tag from their targets and the trades that bring them back. Total is
+// the market value of the holdings together with the Cash available to invest, CashLeft is what the trades don't use.
+type RebalancePlan struct {
+	Tag         string
+	CashOnly    bool
+	Total       Money
+	Cash        Money
+	CashLeft    Money
+	TargetTotal float32
+	Lines       []RebalanceLine
+	Trades      []RebalanceTrade
+}
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// ReturnWindow is the return over the period starting at Start, Available is false when the portfolio history
+// is shorter than the window
+type ReturnWindow struct {
+	Label      string
+	Start      time.Time
+	Return     float32
+	Annualized float32
+	Available  bool
+}
+
+type TimeWeightedReturns struct {
+	YTD        ReturnWindow
+	OneYear    ReturnWindow
+	ThreeYears ReturnWindow
+	FiveYears  ReturnWindow
+	Inception  ReturnWindow
+}
+
+func (r TimeWeightedReturns) Windows() []ReturnWindow {
+	return []ReturnWindow{r.YTD, r.OneYear, r.ThreeYears, r.FiveYears, r.Inception}
+}
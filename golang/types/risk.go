@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+// RiskMetrics are computed from the daily returns of the portfolio. Volatility, Sharpe and Sortino are annualized,
+// drawdowns are negative fractions of the peak. Available is false when there is not enough history.
+type RiskMetrics struct {
+	Available bool
+
+	Volatility      float32
+	MaxDrawdown     float32
+	PeakDate        time.Time
+	TroughDate      time.Time
+	CurrentDrawdown float32
+	Sharpe          float32
+	Sortino         float32
+	RiskFreeRate    float32
+}
@@ -0,0 +1,79 @@
+package types
+
+// SimulationState is an account before or after the trades of a simulation. Holdings splits the market value by
+// symbol and Allocation by asset class, sector and the other allocation groups. DividendIncome is the dividends
+// expected over the next 12 months, the dividend yield of the last 12 months on the holdings, before tax.
+type SimulationState struct {
+	Value          Money
+	CashBalance    Money
+	RealizedGain   Money
+	UnrealizedGain Money
+	DividendIncome Money
+	Holdings       []AllocationSlice
+	Allocation     Allocation
+}
+
+// TradeSimulation is the effect of hypothetical Trades on an account. Tax is the capital gains tax on the gain the
+// trades realize, zero when they realize a loss. NetDividendIncome is the change in the expected dividends after the
+// dividend tax.
+type TradeSimulation struct {
+	Trades            []Transaction
+	Before            SimulationState
+	After             SimulationState
+	Tax               Money
+	NetDividendIncome Money
+}
+
+// RealizedGain is the gain realized by the trades, the simulation checks that it fits
+func (s TradeSimulation) RealizedGain() Money {
+	return s.After.RealizedGain - s.Before.RealizedGain
+}
+
+// DividendIncome is the change in the dividends expected over the next 12 months, before tax. The simulation checks
+// that it fits.
+func (s TradeSimulation) DividendIncome() Money {
+	return s.After.DividendIncome - s.Before.DividendIncome
+}
+
+// AllocationChange is the weight of a label before and after a simulation
+type AllocationChange struct {
+	Label  string
+	Before float32
+	After  float32
+}
+
+func (c AllocationChange) Change() float32 {
+	return c.After - c.Before
+}
+
+// HoldingChanges compares the weight of every symbol before and after the trades
+func (s TradeSimulation) HoldingChanges() []AllocationChange {
+	return compareSlices(s.Before.Holdings, s.After.Holdings)
+}
+
+// AssetClassChanges compares the weight of every asset class before and after the trades
+func (s TradeSimulation) AssetClassChanges() []AllocationChange {
+	return compareSlices(s.Before.Allocation.Group(AllocationAssetClass).Slices, s.After.Allocation.Group(AllocationAssetClass).Slices)
+}
+
+// compareSlices pairs the labels of before and after, in the order of after with the labels that are gone last
+func compareSlices(before, after []AllocationSlice) []AllocationChange {
+	weights := make(map[string]float32, len(before))
+	for _, s := range before {
+		weights[s.Label] = s.Percent
+	}
+
+	changes := make([]AllocationChange, 0, len(after))
+	seen := make(map[string]bool, len(after))
+	for _, s := range after {
+		seen[s.Label] = true
+		changes = append(changes, AllocationChange{Label: s.Label, Before: weights[s.Label], After: s.Percent})
+	}
+	for _, s := range before {
+		if !seen[s.Label] {
+			changes = append(changes, AllocationChange{Label: s.Label, Before: s.Percent})
+		}
+	}
+
+	return changes
+}
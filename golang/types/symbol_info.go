@@ -0,0 +1,54 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// AssetClass is the kind of security a symbol is
+type AssetClass string
+
+const (
+	AssetClassStock  AssetClass = "Stock"
+	AssetClassETF    AssetClass = "ETF"
+	AssetClassFund   AssetClass = "Fund"
+	AssetClassBond   AssetClass = "Bond"
+	AssetClassCrypto AssetClass = "Crypto"
+	AssetClassOther  AssetClass = "Other"
+)
+
+// AssetClasses are the asset classes a symbol can be edited to
+var AssetClasses = []AssetClass{AssetClassStock, AssetClassETF, AssetClassFund, AssetClassBond, AssetClassCrypto, AssetClassOther}
+
+// SymbolInfo describes a symbol. Metadata edited by hand is Manual and is kept when metadata is fetched again.
+type SymbolInfo struct {
+	Symbol     string
+	Name       string
+	Exchange   string
+	Currency   string
+	AssetClass AssetClass
+	Sector     string
+	Country    string
+	ISIN       string
+	Manual     bool
+	UpdatedAt  time.Time
+}
+
+// DisplayName returns the name of the symbol, or the symbol itself when the name is unknown
+func (s SymbolInfo) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strings.ToUpper(s.Symbol)
+}
+
+// Summary returns the known asset class, sector and country joined by " · "
+func (s SymbolInfo) Summary() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{string(s.AssetClass), s.Sector, s.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
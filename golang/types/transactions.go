@@ -1,6 +1,8 @@
 package types
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -13,16 +15,75 @@ const (
 	TransactionTypeSell     TransactionType = "Sell"
 	TransactionTypeDividend TransactionType = "Dividend"
 	TransactionTypeSplit    TransactionType = "Split"
+
+	TransactionTypeDeposit    TransactionType = "Deposit"
+	TransactionTypeWithdrawal TransactionType = "Withdrawal"
+	TransactionTypeFee        TransactionType = "Fee"
+	TransactionTypeInterest   TransactionType = "Interest"
+	TransactionTypeTax        TransactionType = "Tax"
+
+	// TransactionTypeTransfer moves shares between accounts, it is stored as a pair of rows sharing a TransferId.
+	// The outgoing row has a negative Quantity and Pps of both rows is the cost basis per share moved.
+	TransactionTypeTransfer TransactionType = "Transfer"
+
+	// TransactionTypeCorporateAction applies the corporate action in Action, like dividends and splits these rows
+	// come from the market data and have no account
+	TransactionTypeCorporateAction TransactionType = "CorporateAction"
 )
 
+// UserTransactionTypes are the types that can be entered by hand, dividends and splits come from the market data
+var UserTransactionTypes = []TransactionType{
+	TransactionTypeBuy,
+	TransactionTypeSell,
+	TransactionTypeDeposit,
+	TransactionTypeWithdrawal,
+	TransactionTypeFee,
+	TransactionTypeInterest,
+	TransactionTypeTax,
+	TransactionTypeTransfer,
+}
+
+// IsCash reports whether the transaction only moves cash. The amount is kept in Pps, Quantity is unused and
+// Symbol is optional.
+func (t TransactionType) IsCash() bool {
+	switch t {
+	case TransactionTypeDeposit, TransactionTypeWithdrawal, TransactionTypeFee, TransactionTypeInterest, TransactionTypeTax:
+		return true
+	}
+	return false
+}
+
+// IsExternalFlow reports whether the transaction moves money in or out of the account
+func (t TransactionType) IsExternalFlow() bool {
+	return t == TransactionTypeDeposit || t == TransactionTypeWithdrawal
+}
+
+// Transaction amounts, Pps and Fee, are in Currency, an empty Currency is USD
 type Transaction struct {
-	Id        string          `json:"id"`
-	AccountId string          `json:"account_id"`
-	Symbol    string          `json:"symbol"`
-	Date      time.Time       `json:"date"`
-	Type      TransactionType `json:"transaction_type"`
-	Quantity  int32           `json:"quantity"`
-	Pps       int32           `json:"pps"`
+	Id         string           `json:"id"`
+	AccountId  string           `json:"account_id"`
+	Symbol     string           `json:"symbol"`
+	Date       time.Time        `json:"date"`
+	Type       TransactionType  `json:"transaction_type"`
+	Quantity   Quantity         `json:"quantity"`
+	Pps        Money            `json:"pps"`
+	Fee        Money            `json:"fee,omitempty"`
+	TransferId string           `json:"transfer_id,omitempty"`
+	Action     *CorporateAction `json:"action,omitempty"`
+	Currency   string           `json:"currency,omitempty"`
+}
+
+// Cost returns what a buy paid including its fee, or what a sell received after its fee. Fee is the commission
+// charged on a trade.
+func (t Transaction) Cost() (Money, error) {
+	value, err := t.Pps.MulQuantity(t.Quantity)
+	if err != nil {
+		return 0, err
+	}
+	if t.Type == TransactionTypeSell {
+		return value.Sub(t.Fee)
+	}
+	return value.Add(t.Fee)
 }
 
 func (t Transaction) AsDate() time.Time {
@@ -34,6 +95,50 @@ func (t Transaction) AsDate() time.Time {
 	// return ret
 }
 
+// Validate checks a hand entered transaction, trades need a symbol, a positive quantity and a price,
+// cash transactions need a positive amount
+func (t Transaction) Validate() error {
+	switch {
+	case t.Type == TransactionTypeBuy || t.Type == TransactionTypeSell:
+		if t.Symbol == "" {
+			return errors.New("symbol is required")
+		}
+		if t.Quantity <= 0 {
+			return errors.New("quantity must be positive")
+		}
+		if t.Pps < 0 {
+			return errors.New("price can't be negative")
+		}
+		if t.Fee < 0 {
+			return errors.New("fee can't be negative")
+		}
+	case t.Type == TransactionTypeTransfer:
+		if t.Symbol == "" {
+			return errors.New("symbol is required")
+		}
+		if t.Quantity == 0 {
+			return errors.New("quantity is required")
+		}
+		if t.TransferId == "" {
+			return errors.New("transfer is missing its other side")
+		}
+		if t.Pps < 0 {
+			return errors.New("cost basis can't be negative")
+		}
+	case t.Type.IsCash():
+		if t.Pps <= 0 {
+			return errors.New("amount must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported transaction type %q", t.Type)
+	}
+
+	if t.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	return nil
+}
+
 type Account struct {
 	Id            string   `json:"id"`
 	Name          string   `json:"name"`
@@ -42,6 +147,7 @@ type Account struct {
 	InstitutionId string   `json:"institution_id"`
 	Description   *string  `json:"description"`
 	Tags          []string `json:"tags"`
+	BaseCurrency  string   `json:"base_currency"`
 	CreatedAt     *string  `json:"created_at"`
 	UpdatedAt     *string  `json:"updated_at"`
 }
@@ -13,7 +13,12 @@ func addCommas(s string) string {
 	return addCommas(s[:n-3]) + "," + s[n-3:]
 }
 
-func ToCurrencyString(val int64, precision int, currency string, rate float64) string {
+// Cents is any integer amount in minor units, like int64 totals or types.Money
+type Cents interface {
+	~int32 | ~int64
+}
+
+func ToCurrencyString[T Cents](val T, precision int, currency string, rate float64) string {
 	amount := float64(val) * rate / 100.0
 	formatStr := fmt.Sprintf("%%.%df", precision)
 	formatted := fmt.Sprintf(formatStr, amount)
@@ -41,10 +46,27 @@ func ToCurrencyString(val int64, precision int, currency string, rate float64) s
 	return fmt.Sprintf("%s%s.%s", currency, intPart, decPart)
 }
 
-func ToCurrencyStringUSD(val int64, precision int) string {
+func ToCurrencyStringUSD[T Cents](val T, precision int) string {
 	return ToCurrencyString(val, precision, "$", 1.0)
 }
 
 func ToYieldString(val float32) string {
 	return fmt.Sprintf("%.2f%%", val*100)
 }
+
+// CurrencyFormat is how amounts of a currency are written. Decimals caps the precision, e.g. 0 for currencies
+// without minor units, and SymbolAfter writes the symbol after the amount.
+type CurrencyFormat struct {
+	Symbol      string
+	Decimals    int
+	SymbolAfter bool
+}
+
+// FormatCurrency formats val like ToCurrencyString following the rules of format
+func FormatCurrency[T Cents](val T, precision int, format CurrencyFormat, rate float64) string {
+	precision = min(precision, format.Decimals)
+	if format.SymbolAfter {
+		return ToCurrencyString(val, precision, "", rate) + " " + format.Symbol
+	}
+	return ToCurrencyString(val, precision, format.Symbol, rate)
+}
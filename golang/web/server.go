@@ -1,12 +1,18 @@
 package web
 
 import (
+	"database/sql"
 	"embed"
+	"fmt"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"tracker/config"
 	"tracker/loaders"
@@ -23,8 +29,38 @@ import (
 //go:embed templates/* static/*
 var f embed.FS
 
-func toCurrencyWithRate(val int64, precision int, symbol string, rate float64) string {
-	return utils.ToCurrencyString(val, precision, symbol, rate)
+func toCurrencyWithRate(val any, precision int, currency config.DisplayCurrency, rate float64) string {
+	return utils.FormatCurrency(toCents(val), precision, currency.CurrencyFormat, rate)
+}
+
+// toCurrencyIn formats an amount the portfolio was already analyzed in, converted at the rates of its days
+func toCurrencyIn(val any, precision int, currency config.DisplayCurrency) string {
+	return utils.FormatCurrency(toCents(val), precision, currency.CurrencyFormat, 1)
+}
+
+// displayCurrency returns the configured currency of the currency query parameter and its rate to USD, unknown
+// currencies are displayed in USD
+func displayCurrency(c *gin.Context, db *sql.DB, currencies config.CurrencyConfig) (config.DisplayCurrency, float64) {
+	currency := currencies.Get(c.DefaultQuery("currency", types.CurrencyUSD))
+	if currency.Code == types.CurrencyUSD {
+		return currency, 1.0
+	}
+	return currency, loaders.CurrencyExchangeRate(db, currency.Code)
+}
+
+// toCents accepts the amounts templates pass around, int64 totals and types.Money
+func toCents(val any) int64 {
+	switch v := val.(type) {
+	case types.Money:
+		return int64(v)
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	}
+	return 0
 }
 
 func collectUniqueTags(accounts *[]types.Account) []string {
@@ -47,15 +83,123 @@ func hasTag(tags []string, tag string) bool {
 
 func getFilteredAccountIds(accounts *[]types.Account, tagFilter string) []string {
 	var ids []string
+	for _, ac := range getFilteredAccounts(accounts, tagFilter) {
+		ids = append(ids, ac.Id)
+	}
+	return ids
+}
+
+func getFilteredAccounts(accounts *[]types.Account, tagFilter string) []types.Account {
+	var filtered []types.Account
 	for _, ac := range *accounts {
 		if ac.Id == "" {
 			continue
 		}
 		if tagFilter == "All" || hasTag(ac.Tags, tagFilter) {
-			ids = append(ids, ac.Id)
+			filtered = append(filtered, ac)
 		}
 	}
-	return ids
+	return filtered
+}
+
+// transactionFromForm reads a hand entered transaction from the add transaction form. Trades use the quantity,
+// price and optional fee fields, cash transactions the amount field and transfers the quantity and to_account
+// fields. The rows of a transfer are built by the caller, only its symbol and quantity are checked here.
+// transaction_currency is the currency of the amounts, the currency field is the display currency to return to.
+func transactionFromForm(c *gin.Context, accountId string) (types.Transaction, error) {
+	date, err := time.Parse("2006-01-02", c.PostForm("date"))
+	if err != nil {
+		return types.Transaction{}, fmt.Errorf("invalid date %q", c.PostForm("date"))
+	}
+
+	tx := types.Transaction{
+		AccountId: accountId,
+		Symbol:    strings.TrimSpace(c.PostForm("symbol")),
+		Date:      date,
+		Type:      types.TransactionType(c.PostForm("type")),
+		Currency:  types.NormalizeCurrency(strings.TrimSpace(c.PostForm("transaction_currency"))),
+	}
+	if len(tx.Currency) != 3 {
+		return types.Transaction{}, fmt.Errorf("invalid currency %q", tx.Currency)
+	}
+
+	if tx.Type == types.TransactionTypeTransfer {
+		if tx.Symbol == "" {
+			return types.Transaction{}, fmt.Errorf("symbol is required")
+		}
+		if tx.Quantity, err = types.ParseQuantity(c.PostForm("quantity")); err != nil {
+			return types.Transaction{}, err
+		}
+		return tx, nil
+	}
+
+	priceField := "price"
+	if tx.Type.IsCash() {
+		priceField = "amount"
+	} else if tx.Quantity, err = types.ParseQuantity(c.PostForm("quantity")); err != nil {
+		return types.Transaction{}, err
+	}
+
+	if tx.Pps, err = types.ParseMoney(c.PostForm(priceField)); err != nil {
+		return types.Transaction{}, fmt.Errorf("invalid %s %q", priceField, c.PostForm(priceField))
+	}
+
+	if feeStr := strings.TrimSpace(c.PostForm("fee")); feeStr != "" && !tx.Type.IsCash() {
+		if tx.Fee, err = types.ParseMoney(feeStr); err != nil {
+			return types.Transaction{}, fmt.Errorf("invalid fee %q", feeStr)
+		}
+	}
+
+	return tx, tx.Validate()
+}
+
+// targetFromForm reads a target allocation from the rebalance page, weight and tolerance are percentages and an empty
+// tolerance uses the configured one
+func targetFromForm(c *gin.Context) (types.TargetAllocation, error) {
+	t := types.TargetAllocation{
+		Tag:  c.DefaultPostForm("tag", "All"),
+		Kind: types.TargetKind(c.PostForm("kind")),
+		Key:  strings.TrimSpace(c.PostForm("key")),
+	}
+	switch t.Kind {
+	case types.TargetSymbol:
+	case types.TargetAssetClass:
+		if !slices.Contains(types.AssetClasses, types.AssetClass(t.Key)) {
+			return t, fmt.Errorf("unknown asset class %q", t.Key)
+		}
+	default:
+		return t, fmt.Errorf("unknown target kind %q", t.Kind)
+	}
+	if t.Key == "" {
+		return t, fmt.Errorf("symbol or asset class is required")
+	}
+
+	weight, err := strconv.ParseFloat(strings.TrimSpace(c.PostForm("weight")), 64)
+	if err != nil || math.IsNaN(weight) || weight < 0 || weight > 100 {
+		return t, fmt.Errorf("invalid weight %q", c.PostForm("weight"))
+	}
+	t.Weight = float32(weight / 100)
+
+	if raw := strings.TrimSpace(c.PostForm("tolerance")); raw != "" {
+		tolerance, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(tolerance) || tolerance < 0 || tolerance > 100 {
+			return t, fmt.Errorf("invalid tolerance %q", raw)
+		}
+		t.Tolerance = float32(tolerance / 100)
+	}
+
+	return t, nil
+}
+
+// transferAccounts returns the accounts shares can be transferred to from accountId
+func transferAccounts(accounts *[]types.Account, accountId string) []types.Account {
+	var ret []types.Account
+	for _, ac := range *accounts {
+		if ac.Id != "" && ac.Id != accountId {
+			ret = append(ret, ac)
+		}
+	}
+	return ret
 }
 
 func StartServer(cfg config.AppConfig) {
@@ -73,15 +217,15 @@ func StartServer(cfg config.AppConfig) {
 	}))
 
 	funcMap := template.FuncMap{
-		"toCurrency":         utils.ToCurrencyStringUSD,
+		"toCurrency": func(val any, precision int) string {
+			return utils.ToCurrencyStringUSD(toCents(val), precision)
+		},
 		"toCurrencyWithRate": toCurrencyWithRate,
+		"toCurrencyIn":       toCurrencyIn,
 		"toYield":            utils.ToYieldString,
 		"formatDate": func(t time.Time) string {
 			return t.Format("2006-01-02")
 		},
-		"int64": func(i int32) int64 {
-			return int64(i)
-		},
 	}
 	templ := template.Must(template.New("").Funcs(funcMap).ParseFS(f, "templates/*.html"))
 	r.SetHTMLTemplate(templ)
@@ -98,13 +242,8 @@ func StartServer(cfg config.AppConfig) {
 	r.GET("/", func(c *gin.Context) {
 		// db, cleanup := storage.OpenLocalDatabase(false)
 
-		currency := c.DefaultQuery("currency", "USD")
-		currencySymbol := market.CurrencySymbolUSD
-		exchangeRate := 1.0
-		if currency == "ILS" {
-			currencySymbol = market.CurrencySymbolILS
-			exchangeRate = loaders.CurrencyExchangeRate(db, "ILS")
-		}
+		// the portfolio is analyzed in the display currency, its flows at the rate of their day
+		currency, exchangeRate := displayCurrency(c, db, cfg.Currencies)
 
 		tagFilter := c.DefaultQuery("tag", "All")
 
@@ -114,7 +253,7 @@ func StartServer(cfg config.AppConfig) {
 
 		accountsData := make(map[string]types.AnalyzedPortfolio, len(*accounts))
 		for _, ac := range *accounts {
-			data, _ := portfolio.LoadAndAnalyze(db, ac)
+			data, _ := portfolio.LoadAndAnalyzeIn(db, ac, currency.Code)
 			accountsData[ac.Id] = data
 		}
 
@@ -126,14 +265,20 @@ func StartServer(cfg config.AppConfig) {
 		}
 
 		filteredIds := getFilteredAccountIds(accounts, tagFilter)
-		allPortfolioData, _ := portfolio.LoadAndAnalyzeAccounts(db, filteredIds)
+		allPortfolioData, _ := portfolio.LoadAndAnalyzeAccountsIn(db, filteredIds, currency.Code)
+		benchmark, _ := portfolio.LoadBenchmarkComparison(db, filteredIds, cfg.Benchmarks.ForTag(tagFilter), currency.Code)
+		symbols, _ := loaders.SymbolsInfo(db)
+		allocation, _ := portfolio.BuildAllocation(filteredAccounts, accountsData, loaders.AllPrices(db), symbols)
 
 		c.HTML(http.StatusOK, "index.html", gin.H{
 			"accounts":         &filteredAccounts,
 			"accountsData":     accountsData,
 			"allPortfolioData": allPortfolioData,
-			"currency":         currency,
-			"currencySymbol":   currencySymbol,
+			"allocation":       allocation,
+			"benchmark":        benchmark,
+			"currency":         currency.Code,
+			"currencies":       cfg.Currencies,
+			"displayCurrency":  currency,
 			"exchangeRate":     exchangeRate,
 			"tags":             tags,
 			"tagFilter":        tagFilter,
@@ -143,13 +288,8 @@ func StartServer(cfg config.AppConfig) {
 	r.GET("/account/:id", func(c *gin.Context) {
 		accountId := c.Param("id")
 
-		currency := c.DefaultQuery("currency", "USD")
-		currencySymbol := market.CurrencySymbolUSD
-		exchangeRate := 1.0
-		if currency == "ILS" {
-			currencySymbol = market.CurrencySymbolILS
-			exchangeRate = loaders.CurrencyExchangeRate(db, "ILS")
-		}
+		// the portfolio is analyzed in the display currency, its flows at the rate of their day
+		currency, exchangeRate := displayCurrency(c, db, cfg.Currencies)
 
 		showDividends := c.DefaultQuery("showDividends", "true") == "true"
 
@@ -167,26 +307,323 @@ func StartServer(cfg config.AppConfig) {
 			return
 		}
 
-		portfolioData, _ := portfolio.LoadAndAnalyze(db, account)
-		transactions := portfolio.BuildTransactionRows(portfolioData.Transactions, showDividends)
-		dividendsAfterTax := config.DividendsAfterTax(portfolioData.TotalDividends, cfg.DividendTaxRate)
+		portfolioData, _ := portfolio.LoadAndAnalyzeIn(db, account, currency.Code)
+		transactions := portfolio.BuildTransactionRows(portfolioData.ReportingTransactions, showDividends)
+		benchmark, _ := portfolio.LoadBenchmarkComparison(db, []string{account.Id}, cfg.Benchmarks.ForAccount(account), currency.Code)
+		risk, _ := portfolio.LoadRiskMetrics(db, []string{account.Id}, cfg.RiskFreeRate)
+		symbols, _ := loaders.SymbolsInfo(db)
+		dividendTax := portfolio.DividendTax{Rates: cfg.DividendTaxes, Symbols: symbols}
+		dividendsAfterTax, err := dividendTax.AfterTax(account.Id, portfolioData.DividendFlows)
+		if err != nil {
+			log.Printf("failed to tax dividends: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to tax dividends")
+			return
+		}
+		dividendTaxRate := dividendTax.EffectiveRate(portfolioData.TotalDividends, dividendsAfterTax)
 
 		c.HTML(http.StatusOK, "account.html", gin.H{
 			"account":            account,
 			"portfolio":          portfolioData,
 			"transactions":       transactions,
-			"currency":           currency,
-			"currencySymbol":     currencySymbol,
+			"currency":           currency.Code,
+			"currencies":         cfg.Currencies,
+			"displayCurrency":    currency,
 			"exchangeRate":       exchangeRate,
-			"dividendTaxRate":    cfg.DividendTaxRate,
-			"dividendTaxPercent": cfg.DividendTaxRate * 100,
+			"dividendTaxRate":    dividendTaxRate,
+			"dividendTaxPercent": dividendTaxRate * 100,
 			"dividendsAfterTax":  dividendsAfterTax,
 			"showDividends":      showDividends,
+			"benchmark":          benchmark,
+			"risk":               risk,
+			"symbols":            symbols,
+			"transactionTypes":   types.UserTransactionTypes,
+			"transferAccounts":   transferAccounts(accounts, account.Id),
+			"today":              time.Now().Format("2006-01-02"),
+		})
+	})
+
+	r.POST("/account/:id/transactions", func(c *gin.Context) {
+		accountId := c.Param("id")
+
+		accounts, _ := loaders.UserAccounts(db)
+		found := false
+		for _, ac := range *accounts {
+			if ac.Id == accountId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.String(http.StatusNotFound, "Account not found")
+			return
+		}
+
+		tx, err := transactionFromForm(c, accountId)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if tx.Type == types.TransactionTypeTransfer {
+			to := c.PostForm("to_account")
+			if !slices.ContainsFunc(transferAccounts(accounts, accountId), func(ac types.Account) bool { return ac.Id == to }) {
+				c.String(http.StatusBadRequest, "Unknown account to transfer to")
+				return
+			}
+
+			out, in, err := portfolio.LoadTransfer(db, accountId, to, tx.Symbol, tx.Quantity, tx.Date)
+			if err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			err = loaders.AddTransfer(db, out, in)
+		} else {
+			err = loaders.AddTransaction(db, tx)
+		}
+		if err != nil {
+			log.Printf("failed to add transaction: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to add transaction")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/account/"+accountId+"?currency="+url.QueryEscape(c.DefaultPostForm("currency", "USD")))
+	})
+
+	// previews a buy or sell from the add transaction form without saving it, the page posts it again to save it
+	r.POST("/account/:id/simulate", func(c *gin.Context) {
+		accountId := c.Param("id")
+		currency := cfg.Currencies.Get(c.DefaultPostForm("currency", types.CurrencyUSD))
+
+		accounts, _ := loaders.UserAccounts(db)
+		var account types.Account
+		for _, ac := range *accounts {
+			if ac.Id == accountId {
+				account = ac
+				break
+			}
+		}
+		if account.Id == "" {
+			c.String(http.StatusNotFound, "Account not found")
+			return
+		}
+
+		tx, err := transactionFromForm(c, accountId)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if tx.Type != types.TransactionTypeBuy && tx.Type != types.TransactionTypeSell {
+			c.String(http.StatusBadRequest, "Only buys and sells can be previewed")
+			return
+		}
+
+		sim, err := portfolio.LoadSimulation(db, account, []types.Transaction{tx}, currency.Code, portfolio.SimulationOptions{
+			CapitalGainsTaxRate: cfg.CapitalGainTaxRate,
+			DividendTaxes:       cfg.DividendTaxes,
+		})
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.HTML(http.StatusOK, "simulate.html", gin.H{
+			"account":         account,
+			"sim":             sim,
+			"trade":           tx,
+			"currency":        currency.Code,
+			"displayCurrency": currency,
+			"form": gin.H{
+				"date":                 c.PostForm("date"),
+				"type":                 c.PostForm("type"),
+				"symbol":               c.PostForm("symbol"),
+				"transaction_currency": c.PostForm("transaction_currency"),
+				"quantity":             c.PostForm("quantity"),
+				"price":                c.PostForm("price"),
+				"fee":                  c.PostForm("fee"),
+			},
 		})
 	})
 
+	r.GET("/symbol/:symbol", func(c *gin.Context) {
+		currency, _ := displayCurrency(c, db, cfg.Currencies)
+
+		symbol := strings.ToUpper(c.Param("symbol"))
+		symbols, _ := loaders.SymbolsInfo(db)
+		info, ok := symbols[strings.ToLower(symbol)]
+		if !ok {
+			info = types.SymbolInfo{Symbol: symbol}
+		}
+
+		c.HTML(http.StatusOK, "symbol.html", gin.H{
+			"info":         info,
+			"currency":     currency.Code,
+			"assetClasses": types.AssetClasses,
+		})
+	})
+
+	r.POST("/symbol/:symbol", func(c *gin.Context) {
+		symbol := strings.ToUpper(c.Param("symbol"))
+		assetClass := types.AssetClass(c.PostForm("asset_class"))
+		if assetClass != "" && !slices.Contains(types.AssetClasses, assetClass) {
+			c.String(http.StatusBadRequest, "Unknown asset class")
+			return
+		}
+
+		err := loaders.SaveSymbolInfo(db, types.SymbolInfo{
+			Symbol:     symbol,
+			Name:       strings.TrimSpace(c.PostForm("name")),
+			Exchange:   strings.TrimSpace(c.PostForm("exchange")),
+			Currency:   strings.ToUpper(strings.TrimSpace(c.PostForm("symbol_currency"))),
+			AssetClass: assetClass,
+			Sector:     strings.TrimSpace(c.PostForm("sector")),
+			Country:    strings.TrimSpace(c.PostForm("country")),
+			ISIN:       strings.ToUpper(strings.TrimSpace(c.PostForm("isin"))),
+		})
+		if err != nil {
+			log.Printf("failed to save symbol metadata: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to save symbol")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/symbol/"+url.PathEscape(symbol)+"?currency="+url.QueryEscape(c.DefaultPostForm("currency", "USD")))
+	})
+
+	r.GET("/rebalance", func(c *gin.Context) {
+		currency, _ := displayCurrency(c, db, cfg.Currencies)
+		tag := c.DefaultQuery("tag", "All")
+		cashOnly := c.Query("cashOnly") == "true"
+
+		var newCash types.Money
+		if raw := strings.TrimSpace(c.Query("cash")); raw != "" {
+			amount, err := types.ParseMoney(raw)
+			if err != nil || amount < 0 {
+				c.String(http.StatusBadRequest, "Invalid cash amount")
+				return
+			}
+			newCash = amount
+		}
+
+		accounts, _ := loaders.UserAccounts(db)
+		plan, err := portfolio.LoadRebalancePlan(db, getFilteredAccountIds(accounts, tag), tag, currency.Code, portfolio.RebalanceOptions{
+			Tolerance: cfg.RebalanceTolerance,
+			CashOnly:  cashOnly,
+			NewCash:   newCash,
+		})
+		if err != nil {
+			log.Printf("failed to plan rebalance: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to plan rebalance")
+			return
+		}
+
+		c.HTML(http.StatusOK, "rebalance.html", gin.H{
+			"plan":             plan,
+			"newCash":          c.Query("cash"),
+			"currency":         currency.Code,
+			"displayCurrency":  currency,
+			"tags":             collectUniqueTags(accounts),
+			"assetClasses":     types.AssetClasses,
+			"defaultTolerance": float32(cfg.RebalanceTolerance),
+		})
+	})
+
+	// the dividend forecast and history of the accounts of a tag, or of a single account
+	r.GET("/dividends", func(c *gin.Context) {
+		currency, _ := displayCurrency(c, db, cfg.Currencies)
+		tag := c.DefaultQuery("tag", "All")
+
+		accounts, _ := loaders.UserAccounts(db)
+		title, selected := tag, getFilteredAccounts(accounts, tag)
+		var account types.Account
+		if accountId := c.Query("account"); accountId != "" {
+			for _, ac := range *accounts {
+				if ac.Id == accountId {
+					account = ac
+					break
+				}
+			}
+			if account.Id == "" {
+				c.String(http.StatusNotFound, "Account not found")
+				return
+			}
+			title, selected = account.Name, []types.Account{account}
+		}
+		var accountIds []string
+		for _, ac := range selected {
+			accountIds = append(accountIds, ac.Id)
+		}
+
+		forecast, err := portfolio.LoadDividendForecast(db, accountIds, currency.Code, cfg.DividendTaxes)
+		if err != nil {
+			log.Printf("failed to forecast dividends: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to forecast dividends")
+			return
+		}
+
+		report, err := portfolio.LoadDividendReport(db, selected, currency.Code, cfg.DividendTaxes)
+		if err != nil {
+			log.Printf("failed to report dividends: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to report dividends")
+			return
+		}
+		// the last two years of months, newest first, the years cover the rest
+		var recentMonths []types.DividendPeriod
+		for i := len(report.Months) - 1; i >= max(len(report.Months)-24, 0); i-- {
+			recentMonths = append(recentMonths, report.Months[i])
+		}
+
+		// the calendar bars are relative to the biggest month
+		var topMonth types.Money
+		for _, m := range forecast.Months {
+			topMonth = max(topMonth, m.Amount)
+		}
+
+		// rates differ by account and country, the tax is shown as the part of the dividends it takes
+		tax := portfolio.DividendTax{Rates: cfg.DividendTaxes}
+
+		c.HTML(http.StatusOK, "dividends.html", gin.H{
+			"title":              title,
+			"topMonth":           topMonth,
+			"account":            account,
+			"tag":                tag,
+			"forecast":           forecast,
+			"report":             report,
+			"recentMonths":       recentMonths,
+			"currency":           currency.Code,
+			"displayCurrency":    currency,
+			"forecastTaxPercent": tax.EffectiveRate(forecast.Total, forecast.Net) * 100,
+			"reportTaxPercent":   tax.EffectiveRate(report.Gross, report.Net) * 100,
+		})
+	})
+
+	r.POST("/rebalance/targets", func(c *gin.Context) {
+		t, err := targetFromForm(c)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := loaders.SaveTargetAllocation(db, t); err != nil {
+			log.Printf("failed to save target allocation: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to save target")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/rebalance?tag="+url.QueryEscape(t.Tag)+"&currency="+url.QueryEscape(c.DefaultPostForm("currency", "USD")))
+	})
+
+	r.POST("/rebalance/targets/delete", func(c *gin.Context) {
+		tag := c.DefaultPostForm("tag", "All")
+		if err := loaders.DeleteTargetAllocation(db, tag, types.TargetKind(c.PostForm("kind")), c.PostForm("key")); err != nil {
+			log.Printf("failed to delete target allocation: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to remove target")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/rebalance?tag="+url.QueryEscape(tag)+"&currency="+url.QueryEscape(c.DefaultPostForm("currency", "USD")))
+	})
+
 	r.POST("/updateMarket", func(c *gin.Context) {
-		market.UpdateMarketData(db)
+		market.UpdateMarketData(db, cfg.Currencies.Codes(), cfg.Benchmarks.Symbols()...)
 
 		c.String(http.StatusOK, "Market data updated")
 	})
@@ -194,7 +631,7 @@ func StartServer(cfg config.AppConfig) {
 	c := cron.New()
 	c.AddFunc("0 */12 * * *", func() {
 		log.Println("Running scheduled market data update...")
-		market.UpdateMarketData(db)
+		market.UpdateMarketData(db, cfg.Currencies.Codes(), cfg.Benchmarks.Symbols()...)
 		log.Println("Market data update completed")
 	})
 	c.Start()